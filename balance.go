@@ -0,0 +1,179 @@
+package som
+
+import (
+	"fmt"
+)
+
+// Balance is the strategy for balancing classes of a categorical variable during training.
+type Balance uint8
+
+const (
+	NoBalance        Balance = iota // No class balancing
+	Balanced                        // Each epoch's samples are drawn with equal numbers per class
+	InverseFrequency                // Rows are weighted by the inverse frequency of their class
+)
+
+var balances = map[string]Balance{
+	"none":     NoBalance,
+	"balanced": Balanced,
+	"inverse":  InverseFrequency,
+}
+
+// GetBalance returns the class balancing strategy with the given name.
+// Options are none, balanced and inverse.
+func GetBalance(name string) (Balance, bool) {
+	b, ok := balances[name]
+	return b, ok
+}
+
+// String returns the name of the class balancing strategy.
+func (b Balance) String() string {
+	for name, bal := range balances {
+		if bal == b {
+			return name
+		}
+	}
+	return "none"
+}
+
+// BalanceConfig holds the parameters for class balancing during training.
+type BalanceConfig struct {
+	Column string  // Name of the categorical column with the classes. Used by the CLI to read classes, see [Trainer.SetClasses]
+	Mode   Balance // Balancing strategy
+}
+
+// classes holds the class of each data row, for class balancing.
+type classes struct {
+	names   []string
+	indices []int
+	rows    [][]int   // Row indices per class
+	weights []float64 // Inverse frequency weight per class
+}
+
+// SetClasses sets the class of each data row, for class balancing (see [BalanceConfig]).
+// Class indices refer to the given class names, and -1 indicates a missing class.
+// Rows with missing classes are not used by balanced sampling, and have a weight of 1
+// when weighting by inverse class frequency.
+//
+// Classes can be obtained from categorical tables using [conv.TableToClasses],
+// or from class labels using [conv.ClassesToIndices].
+//
+// An error is returned if the number of indices does not match the number of rows,
+// or if any index is out of range.
+func (t *Trainer) SetClasses(names []string, indices []int) error {
+	if len(indices) != t.tables[0].Rows() {
+		return fmt.Errorf("length of class indices (%d) does not match number of data rows (%d)", len(indices), t.tables[0].Rows())
+	}
+	rows := make([][]int, len(names))
+	for i, idx := range indices {
+		if idx < 0 {
+			continue
+		}
+		if idx >= len(names) {
+			return fmt.Errorf("class index %d in row %d out of range for %d classes", idx, i, len(names))
+		}
+		rows[idx] = append(rows[idx], i)
+	}
+	t.classes = &classes{
+		names:   names,
+		indices: indices,
+		rows:    rows,
+		weights: classWeights(rows),
+	}
+	return nil
+}
+
+// balanceMode returns the balancing strategy in use, which is NoBalance if no classes are set.
+func (t *Trainer) balanceMode() Balance {
+	if t.params.Balance == nil || t.classes == nil {
+		return NoBalance
+	}
+	return t.params.Balance.Mode
+}
+
+// classWeights returns a weight for each class from the row indices per class,
+// equal to the inverse class frequency, normalized to a mean of 1 over all rows with a class.
+func classWeights(classRows [][]int) []float64 {
+	known, nonEmpty := 0, 0
+	for _, rows := range classRows {
+		known += len(rows)
+		if len(rows) > 0 {
+			nonEmpty++
+		}
+	}
+	weights := make([]float64, len(classRows))
+	for i, rows := range classRows {
+		if len(rows) > 0 {
+			weights[i] = float64(known) / float64(nonEmpty*len(rows))
+		}
+	}
+	return weights
+}
+
+// balancedRows draws the given number of rows, with equal numbers per class, in random order.
+// Within each class, rows are drawn without replacement as long as possible.
+// Classes without rows are ignored.
+func (t *Trainer) balancedRows(samples int) []int {
+	nonEmpty := []int{}
+	for i, rows := range t.classes.rows {
+		if len(rows) > 0 {
+			nonEmpty = append(nonEmpty, i)
+		}
+	}
+	if len(nonEmpty) == 0 {
+		return nil
+	}
+
+	quota := make([]int, len(nonEmpty))
+	for i := range quota {
+		quota[i] = samples / len(nonEmpty)
+	}
+	for _, i := range t.rng.Perm(len(nonEmpty))[:samples%len(nonEmpty)] {
+		quota[i]++
+	}
+
+	indices := make([]int, 0, samples)
+	for i, cls := range nonEmpty {
+		rows := t.classes.rows[cls]
+		var perm []int
+		for j := 0; j < quota[i]; j++ {
+			if j%len(rows) == 0 {
+				perm = t.rng.Perm(len(rows))
+			}
+			indices = append(indices, rows[perm[j%len(rows)]])
+		}
+	}
+	t.rng.Shuffle(len(indices), func(i, j int) { indices[i], indices[j] = indices[j], indices[i] })
+	return indices
+}
+
+// classShares returns the fraction of the total weight of the presented rows per class.
+// Rows without a class are not counted.
+func (t *Trainer) classShares(rows []int) []float64 {
+	shares := make([]float64, len(t.classes.names))
+	total := 0.0
+	count := func(row int) {
+		cls := t.classes.indices[row]
+		if cls < 0 {
+			return
+		}
+		w := t.weight(row)
+		shares[cls] += w
+		total += w
+	}
+	if rows == nil {
+		for row := range t.classes.indices {
+			count(row)
+		}
+	} else {
+		for _, row := range rows {
+			count(row)
+		}
+	}
+	if total > 0 {
+		for i := range shares {
+			shares[i] /= total
+		}
+	}
+	return shares
+}
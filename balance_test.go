@@ -0,0 +1,100 @@
+package som
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/mlange-42/som/decay"
+	"github.com/mlange-42/som/layer"
+	"github.com/mlange-42/som/neighborhood"
+	"github.com/mlange-42/som/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalance(t *testing.T) {
+	for name, b := range balances {
+		b2, ok := GetBalance(name)
+		assert.True(t, ok)
+		assert.Equal(t, b, b2)
+		assert.Equal(t, name, b.String())
+	}
+	_, ok := GetBalance("unknown")
+	assert.False(t, ok)
+}
+
+func TestTrainerSetClasses(t *testing.T) {
+	s := createGrowingSom(t, layer.Size{Width: 3, Height: 1}, nil, neighborhood.Wrap{})
+	tab := table.New([]string{"x"}, 4)
+	trainer, err := NewTrainer(s, []*table.Table{tab}, &TrainingConfig{}, rand.New(rand.NewSource(1)))
+	assert.NoError(t, err)
+
+	assert.Error(t, trainer.SetClasses([]string{"a", "b"}, []int{0, 1}))
+	assert.Error(t, trainer.SetClasses([]string{"a", "b"}, []int{0, 1, 2, 0}))
+	assert.NoError(t, trainer.SetClasses([]string{"a", "b"}, []int{0, 1, -1, 0}))
+
+	assert.Equal(t, [][]int{{0, 3}, {1}}, trainer.classes.rows)
+	assert.Equal(t, []float64{0.75, 1.5}, trainer.classes.weights)
+}
+
+func TestTrainerBalancedRows(t *testing.T) {
+	s := createGrowingSom(t, layer.Size{Width: 3, Height: 1}, nil, neighborhood.Wrap{})
+	tab := table.New([]string{"x"}, 10)
+
+	params := TrainingConfig{
+		Balance: &BalanceConfig{Column: "cls", Mode: Balanced},
+	}
+	trainer, err := NewTrainer(s, []*table.Table{tab}, &params, rand.New(rand.NewSource(1)))
+	assert.NoError(t, err)
+
+	// Without classes, all rows are used in order.
+	assert.Nil(t, trainer.sampleRows(0))
+
+	assert.NoError(t, trainer.SetClasses([]string{"a", "b", "c"}, []int{0, 0, 0, 0, 0, 0, 0, 1, 1, -1}))
+
+	rows := trainer.sampleRows(0)
+	assert.Len(t, rows, 10)
+
+	counts := []int{0, 0}
+	for _, row := range rows {
+		assert.NotEqual(t, 9, row, "row without class should not be sampled")
+		counts[trainer.classes.indices[row]]++
+	}
+	assert.Equal(t, 10, counts[0]+counts[1])
+	assert.Equal(t, 5, counts[0])
+	assert.Equal(t, 5, counts[1])
+
+	shares := trainer.classShares(rows)
+	assert.Equal(t, []float64{0.5, 0.5, 0}, shares)
+}
+
+func TestTrainerInverseFrequency(t *testing.T) {
+	s := createGrowingSom(t, layer.Size{Width: 1, Height: 1}, nil, neighborhood.Wrap{})
+	tab, err := table.NewWithData([]string{"x"}, []float64{0, 0, 0, 4})
+	assert.NoError(t, err)
+
+	params := TrainingConfig{
+		Algorithm:          Batch,
+		Epochs:             1,
+		NeighborhoodRadius: &decay.Linear{Start: 1, End: 1},
+		Balance:            &BalanceConfig{Column: "cls", Mode: InverseFrequency},
+	}
+	trainer, err := NewTrainer(s, []*table.Table{tab}, &params, rand.New(rand.NewSource(1)))
+	assert.NoError(t, err)
+	assert.NoError(t, trainer.SetClasses([]string{"a", "b"}, []int{0, 0, 0, 1}))
+
+	progress := make(chan TrainingProgress)
+	go trainer.Train(progress)
+	var last TrainingProgress
+	for p := range progress {
+		last = p
+	}
+
+	// Both classes have the same total weight.
+	assert.InDelta(t, 2.0, s.layers[0].Weights()[0], 1e-12)
+	assert.Equal(t, []string{"a", "b"}, last.ClassNames)
+	assert.InDeltaSlice(t, []float64{0.5, 0.5}, last.ClassShares, 1e-12)
+
+	assert.Equal(t, "Epoch,Alpha,Radius,Decay,MeanDist,Error,Share:a,Share:b", last.CsvHeader(','))
+	assert.True(t, strings.HasSuffix(last.CsvRow(','), ",0.5,0.5"))
+}
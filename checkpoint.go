@@ -0,0 +1,82 @@
+package som
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Checkpoint holds the state of a training run, for resuming it later.
+// Together with the SOM's weights, it allows to continue training
+// with results identical to an uninterrupted run.
+type Checkpoint struct {
+	Epoch  int    // Number of completed training epochs
+	Epochs int    // Total number of training epochs
+	Seed   int64  // Seed of the random number generator
+	Draws  uint64 // Number of values drawn from the random number generator since seeding
+}
+
+// CountingSource is a [rand.Source64] that counts the values drawn from it.
+// This allows to store its state, and to restore it later using [NewCountingSourceAt].
+type CountingSource struct {
+	src   rand.Source64
+	seed  int64
+	draws uint64
+}
+
+// NewCountingSource creates a new CountingSource with the given seed.
+func NewCountingSource(seed int64) *CountingSource {
+	return &CountingSource{
+		src:  rand.NewSource(seed).(rand.Source64),
+		seed: seed,
+	}
+}
+
+// NewCountingSourceAt creates a new CountingSource with the given seed,
+// in the state after the given number of draws.
+func NewCountingSourceAt(seed int64, draws uint64) *CountingSource {
+	s := NewCountingSource(seed)
+	for ; s.draws < draws; s.draws++ {
+		s.src.Uint64()
+	}
+	return s
+}
+
+// Int63 returns a non-negative pseudo-random 63-bit integer as an int64.
+func (s *CountingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+// Uint64 returns a pseudo-random 64-bit value as a uint64.
+func (s *CountingSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+// Seed re-seeds the source and resets the number of draws.
+func (s *CountingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.seed = seed
+	s.draws = 0
+}
+
+// State returns the seed and the number of values drawn since seeding.
+func (s *CountingSource) State() (seed int64, draws uint64) {
+	return s.seed, s.draws
+}
+
+// checkpointer decides when to create checkpoints during training.
+type checkpointer struct {
+	epochs   int
+	interval time.Duration
+	last     time.Time
+	callback func(epoch int)
+}
+
+// check calls the callback if a checkpoint is due after the given number of completed epochs.
+func (c *checkpointer) check(epoch int) {
+	if c.epochs > 0 && epoch%c.epochs == 0 || c.interval > 0 && time.Since(c.last) >= c.interval {
+		c.callback(epoch)
+		c.last = time.Now()
+	}
+}
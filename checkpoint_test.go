@@ -0,0 +1,95 @@
+package som
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/mlange-42/som/decay"
+	"github.com/mlange-42/som/layer"
+	"github.com/mlange-42/som/neighborhood"
+	"github.com/mlange-42/som/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountingSource(t *testing.T) {
+	src := NewCountingSource(42)
+	rng := rand.New(src)
+	for i := 0; i < 100; i++ {
+		rng.Float64()
+		rng.Intn(10)
+	}
+
+	seed, draws := src.State()
+	assert.Equal(t, int64(42), seed)
+	assert.Greater(t, draws, uint64(0))
+
+	restored := rand.New(NewCountingSourceAt(seed, draws))
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, rng.Int63(), restored.Int63())
+	}
+
+	src.Seed(1)
+	seed, draws = src.State()
+	assert.Equal(t, int64(1), seed)
+	assert.Equal(t, uint64(0), draws)
+}
+
+func TestTrainerResume(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	tab := table.New([]string{"x"}, 50)
+	for i := 0; i < tab.Rows(); i++ {
+		tab.Set(i, 0, rng.Float64()*10)
+	}
+	tables := []*table.Table{tab}
+
+	params := TrainingConfig{
+		Epochs:             20,
+		LearningRate:       &decay.Linear{Start: 0.5, End: 0.01},
+		NeighborhoodRadius: &decay.Linear{Start: 2, End: 0.7},
+		WeightDecay:        &decay.Linear{Start: 0.1, End: 0.0},
+	}
+
+	train := func(s *Som, src *CountingSource, resume int, checkpoint func(epoch int)) []int {
+		trainer, err := NewTrainer(s, tables, &params, rand.New(src))
+		assert.NoError(t, err)
+		if resume > 0 {
+			trainer.Resume(resume)
+		}
+		if checkpoint != nil {
+			trainer.SetCheckpoints(7, 0, checkpoint)
+		}
+		progress := make(chan TrainingProgress)
+		go trainer.Train(progress)
+		epochs := []int{}
+		for p := range progress {
+			epochs = append(epochs, p.Epoch)
+		}
+		return epochs
+	}
+
+	full := createGrowingSom(t, layer.Size{Width: 4, Height: 1}, nil, neighborhood.Wrap{})
+	train(full, NewCountingSource(1), 0, nil)
+
+	s := createGrowingSom(t, layer.Size{Width: 4, Height: 1}, nil, neighborhood.Wrap{})
+	src := NewCountingSource(1)
+	var weights []float64
+	var draws uint64
+	checkpoints := []int{}
+	train(s, src, 0, func(epoch int) {
+		checkpoints = append(checkpoints, epoch)
+		if epoch == 14 {
+			weights = slices.Clone(s.layers[0].Weights())
+			_, draws = src.State()
+		}
+	})
+	assert.Equal(t, []int{7, 14}, checkpoints)
+	assert.Equal(t, full.layers[0].Weights(), s.layers[0].Weights())
+
+	resumed := createGrowingSom(t, layer.Size{Width: 4, Height: 1}, nil, neighborhood.Wrap{})
+	copy(resumed.layers[0].Weights(), weights)
+	epochs := train(resumed, NewCountingSourceAt(1, draws), 14, nil)
+
+	assert.Equal(t, []int{14, 15, 16, 17, 18, 19}, epochs)
+	assert.Equal(t, full.layers[0].Weights(), resumed.layers[0].Weights())
+}
@@ -0,0 +1,565 @@
+package som
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
+
+	"github.com/mlange-42/som/distance"
+	"github.com/mlange-42/som/layer"
+)
+
+// ClusterMethod is the algorithm for clustering the nodes of an SOM.
+type ClusterMethod uint8
+
+const (
+	KMeans         ClusterMethod = iota // k-means clustering of node vectors, with k-means++ initialization
+	WardLinkage                         // Agglomerative hierarchical clustering with Ward's criterion, based on squared node distances
+	AverageLinkage                      // Agglomerative hierarchical clustering with average linkage
+)
+
+var clusterMethods = map[string]ClusterMethod{
+	"kmeans":  KMeans,
+	"ward":    WardLinkage,
+	"average": AverageLinkage,
+}
+
+// GetClusterMethod returns the clustering method with the given name.
+// Options are kmeans, ward and average.
+func GetClusterMethod(name string) (ClusterMethod, bool) {
+	m, ok := clusterMethods[name]
+	return m, ok
+}
+
+// String returns the name of the clustering method.
+func (m ClusterMethod) String() string {
+	for name, me := range clusterMethods {
+		if me == m {
+			return name
+		}
+	}
+	return "kmeans"
+}
+
+// ClusterIndex is a cluster validity index, used to select the number of clusters automatically.
+type ClusterIndex uint8
+
+const (
+	DaviesBouldin ClusterIndex = iota // Davies-Bouldin index. Lower is better
+	Silhouette                        // Mean silhouette coefficient. Higher is better
+)
+
+var clusterIndices = map[string]ClusterIndex{
+	"davies-bouldin": DaviesBouldin,
+	"silhouette":     Silhouette,
+}
+
+// GetClusterIndex returns the cluster validity index with the given name.
+// Options are davies-bouldin and silhouette.
+func GetClusterIndex(name string) (ClusterIndex, bool) {
+	idx, ok := clusterIndices[name]
+	return idx, ok
+}
+
+// String returns the name of the cluster validity index.
+func (c ClusterIndex) String() string {
+	if c == Silhouette {
+		return "silhouette"
+	}
+	return "davies-bouldin"
+}
+
+// HigherIsBetter returns whether higher values of the index indicate a better clustering.
+func (c ClusterIndex) HigherIsBetter() bool {
+	return c == Silhouette
+}
+
+// ClusterConfig holds the parameters for clustering SOM nodes.
+type ClusterConfig struct {
+	Method      ClusterMethod // Clustering algorithm
+	Clusters    int           // Number of clusters. If zero, the number is selected automatically using Index
+	MinClusters int           // Minimum number of clusters for automatic selection
+	MaxClusters int           // Maximum number of clusters for automatic selection
+	Index       ClusterIndex  // Validity index for automatic selection
+	Connected   bool          // Only merge clusters that are adjacent on the map. Hierarchical methods only
+}
+
+// Clustering is the result of clustering SOM nodes.
+type Clustering struct {
+	Clusters int     // Number of clusters
+	Nodes    []int   // Cluster index per node
+	Score    float64 // Value of the validity index, see [ClusterConfig]
+}
+
+// Cluster clusters the nodes of the SOM, using the layer weights and metrics like for BMU search.
+// Layers with zero weight are not considered.
+// Clusters are numbered in the order of their first node.
+//
+// For automatic selection of the number of clusters, the clustering with the best
+// validity index for each number of clusters in [MinClusters, MaxClusters] is used.
+// The random number generator is only used for k-means initialization.
+func (s *Som) Cluster(config *ClusterConfig, rng *rand.Rand) (*Clustering, error) {
+	n := s.size.Nodes()
+	if config.Connected && config.Method == KMeans {
+		return nil, fmt.Errorf("map-adjacency constraint is not supported for k-means clustering")
+	}
+
+	minK, maxK := config.Clusters, config.Clusters
+	if config.Clusters <= 0 {
+		minK, maxK = max(config.MinClusters, 2), min(config.MaxClusters, n-1)
+		if minK > maxK {
+			return nil, fmt.Errorf("invalid range for the number of clusters: [%d, %d] for %d nodes", config.MinClusters, config.MaxClusters, n)
+		}
+	} else if config.Clusters > n {
+		return nil, fmt.Errorf("number of clusters (%d) is larger than the number of nodes (%d)", config.Clusters, n)
+	}
+
+	var merges [][2]int
+	if config.Method != KMeans {
+		merges = s.agglomerate(config.Method, config.Connected)
+		if n-len(merges) > minK {
+			return nil, fmt.Errorf("can't merge into less than %d clusters", n-len(merges))
+		}
+	}
+
+	var best *Clustering
+	for k := minK; k <= maxK; k++ {
+		var nodes []int
+		if config.Method == KMeans {
+			nodes = s.kMeans(k, rng)
+		} else {
+			nodes = cutMerges(n, merges, k)
+		}
+		c := &Clustering{Clusters: k, Nodes: nodes}
+		c.Score = s.clusterScore(c, config.Index)
+		if best == nil || config.Index.HigherIsBetter() && c.Score > best.Score ||
+			!config.Index.HigherIsBetter() && c.Score < best.Score {
+			best = c
+		}
+	}
+
+	return best, nil
+}
+
+// AddClusterLayer adds a categorical layer with the given name, for the clusters of the SOM's nodes.
+// The layer has a column per cluster, named c1, c2, ..., and has zero weight.
+func (s *Som) AddClusterLayer(name string, c *Clustering) error {
+	if slices.ContainsFunc(s.layers, func(l *layer.Layer) bool { return l.Name() == name }) {
+		return fmt.Errorf("layer %s already exists", name)
+	}
+	if len(c.Nodes) != s.size.Nodes() {
+		return fmt.Errorf("number of clustered nodes (%d) does not match number of nodes (%d)", len(c.Nodes), s.size.Nodes())
+	}
+
+	columns := make([]string, c.Clusters)
+	for i := range columns {
+		columns[i] = "c" + strconv.Itoa(i+1)
+	}
+	data := make([]float64, len(c.Nodes)*c.Clusters)
+	for i, cl := range c.Nodes {
+		data[i*c.Clusters+cl] = 1
+	}
+	lay, err := layer.NewWithData(name, columns, nil, *s.Size(), &distance.Hamming{}, 0.0, true, data)
+	if err != nil {
+		return err
+	}
+	s.layers = append(s.layers, lay)
+	return nil
+}
+
+// nodeVectors returns the vectors of all nodes, per layer.
+func (s *Som) nodeVectors() [][][]float64 {
+	vectors := make([][][]float64, s.size.Nodes())
+	for i := range vectors {
+		vectors[i] = make([][]float64, len(s.layers))
+		for l, lay := range s.layers {
+			vectors[i][l] = lay.GetNodeAt(i)
+		}
+	}
+	return vectors
+}
+
+// centroids returns the mean vectors of the clusters, per layer.
+func (s *Som) centroids(vectors [][][]float64, nodes []int, k int) [][][]float64 {
+	centers := make([][][]float64, k)
+	counts := make([]int, k)
+	for c := range centers {
+		centers[c] = make([][]float64, len(s.layers))
+		for l, lay := range s.layers {
+			centers[c][l] = make([]float64, lay.Columns())
+		}
+	}
+	for i, c := range nodes {
+		counts[c]++
+		for l := range s.layers {
+			for j, v := range vectors[i][l] {
+				centers[c][l][j] += v
+			}
+		}
+	}
+	for c := range centers {
+		if counts[c] == 0 {
+			continue
+		}
+		for l := range s.layers {
+			for j := range centers[c][l] {
+				centers[c][l][j] /= float64(counts[c])
+			}
+		}
+	}
+	return centers
+}
+
+// kMeansRestarts is the number of k-means runs with different initializations.
+// The run with the lowest sum of distances to the cluster centers is used.
+const kMeansRestarts = 10
+
+// kMeansIterations is the maximum number of iterations of a k-means run.
+const kMeansIterations = 100
+
+// kMeans clusters the nodes into k clusters using k-means with k-means++ initialization.
+func (s *Som) kMeans(k int, rng *rand.Rand) []int {
+	vectors := s.nodeVectors()
+	n := len(vectors)
+
+	var best []int
+	bestCost := math.Inf(1)
+	for run := 0; run < kMeansRestarts; run++ {
+		centers := s.kMeansPlusPlus(vectors, k, rng)
+		nodes := make([]int, n)
+		dist := make([]float64, n)
+		cost := 0.0
+		for iter := 0; iter < kMeansIterations; iter++ {
+			changed := false
+			cost = 0
+			for i, v := range vectors {
+				c, d := s.nearestCenter(v, centers)
+				cost += d
+				dist[i] = d
+				if iter == 0 || c != nodes[i] {
+					changed = true
+					nodes[i] = c
+				}
+			}
+			if !changed {
+				break
+			}
+			s.fillEmptyClusters(nodes, dist, k)
+			centers = s.centroids(vectors, nodes, k)
+		}
+		s.fillEmptyClusters(nodes, dist, k)
+		if cost < bestCost {
+			best, bestCost = nodes, cost
+		}
+	}
+	return renumberClusters(best, k)
+}
+
+// fillEmptyClusters assigns the node farthest from its cluster center to each empty cluster.
+func (s *Som) fillEmptyClusters(nodes []int, dist []float64, k int) {
+	counts := make([]int, k)
+	for _, c := range nodes {
+		counts[c]++
+	}
+	for c := range counts {
+		if counts[c] > 0 {
+			continue
+		}
+		far := -1
+		for i, d := range dist {
+			if counts[nodes[i]] > 1 && (far < 0 || d > dist[far]) {
+				far = i
+			}
+		}
+		counts[nodes[far]]--
+		counts[c]++
+		nodes[far] = c
+		dist[far] = 0
+	}
+}
+
+// kMeansPlusPlus selects k initial centers from the nodes, using k-means++ seeding.
+func (s *Som) kMeansPlusPlus(vectors [][][]float64, k int, rng *rand.Rand) [][][]float64 {
+	centers := make([][][]float64, 0, k)
+	centers = append(centers, vectors[rng.Intn(len(vectors))])
+
+	dist := make([]float64, len(vectors))
+	for len(centers) < k {
+		sum := 0.0
+		for i, v := range vectors {
+			_, d := s.nearestCenter(v, centers)
+			dist[i] = d * d
+			sum += dist[i]
+		}
+		if sum == 0 {
+			centers = append(centers, vectors[rng.Intn(len(vectors))])
+			continue
+		}
+		r := rng.Float64() * sum
+		idx := 0
+		for i, d := range dist {
+			r -= d
+			if r <= 0 && d > 0 {
+				idx = i
+				break
+			}
+			idx = i
+		}
+		centers = append(centers, vectors[idx])
+	}
+	return centers
+}
+
+// nearestCenter returns the index of the center nearest to the given vector, and the distance.
+func (s *Som) nearestCenter(v [][]float64, centers [][][]float64) (int, float64) {
+	best, bestDist := 0, math.Inf(1)
+	for c, center := range centers {
+		if d := s.dataDistance(v, center); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best, bestDist
+}
+
+// agglomerate performs agglomerative hierarchical clustering of the nodes.
+// It returns the sequence of merges. In each merge, the second cluster is merged into the first.
+// Clusters are identified by the index of their first node.
+//
+// If connected is true, only clusters that are adjacent on the map are merged.
+func (s *Som) agglomerate(method ClusterMethod, connected bool) [][2]int {
+	n := s.size.Nodes()
+
+	dist := make([][]float64, n)
+	for i := range dist {
+		dist[i] = make([]float64, n)
+		for j := 0; j < i; j++ {
+			d := s.nodeDistance(i, j)
+			if method == WardLinkage {
+				d *= d
+			}
+			dist[i][j] = d
+			dist[j][i] = d
+		}
+	}
+
+	var adjacent []map[int]bool
+	if connected {
+		adjacent = make([]map[int]bool, n)
+		for i := range adjacent {
+			adjacent[i] = map[int]bool{}
+		}
+		for i := 0; i < n; i++ {
+			x, y := s.size.Coords(i)
+			s.forEachNeighbor(x, y, func(x2, y2, row, col int) {
+				if j := s.size.Index(x2, y2); j != i {
+					adjacent[i][j] = true
+				}
+			})
+		}
+	}
+	candidate := func(i, j int) bool {
+		return i != j && (!connected || adjacent[i][j])
+	}
+
+	active := make([]bool, n)
+	sizes := make([]int, n)
+	for i := range active {
+		active[i] = true
+		sizes[i] = 1
+	}
+
+	nearest := make([]int, n)
+	nearestDist := make([]float64, n)
+	findNearest := func(i int) {
+		nearest[i], nearestDist[i] = -1, math.Inf(1)
+		for j := 0; j < n; j++ {
+			if active[j] && candidate(i, j) && dist[i][j] < nearestDist[i] {
+				nearest[i], nearestDist[i] = j, dist[i][j]
+			}
+		}
+	}
+	for i := 0; i < n; i++ {
+		findNearest(i)
+	}
+
+	merges := make([][2]int, 0, n-1)
+	for len(merges) < n-1 {
+		a := -1
+		for i := 0; i < n; i++ {
+			if active[i] && nearest[i] >= 0 && (a < 0 || nearestDist[i] < nearestDist[a]) {
+				a = i
+			}
+		}
+		if a < 0 {
+			break
+		}
+		b := nearest[a]
+		if b < a {
+			a, b = b, a
+		}
+		merges = append(merges, [2]int{a, b})
+
+		na, nb := float64(sizes[a]), float64(sizes[b])
+		for k := 0; k < n; k++ {
+			if !active[k] || k == a || k == b {
+				continue
+			}
+			var d float64
+			if method == WardLinkage {
+				nk := float64(sizes[k])
+				d = ((na+nk)*dist[a][k] + (nb+nk)*dist[b][k] - nk*dist[a][b]) / (na + nb + nk)
+			} else {
+				d = (na*dist[a][k] + nb*dist[b][k]) / (na + nb)
+			}
+			dist[a][k] = d
+			dist[k][a] = d
+		}
+		active[b] = false
+		sizes[a] += sizes[b]
+
+		if connected {
+			for k := range adjacent[b] {
+				delete(adjacent[k], b)
+				if k != a {
+					adjacent[k][a] = true
+					adjacent[a][k] = true
+				}
+			}
+			delete(adjacent[a], b)
+		}
+
+		for k := 0; k < n; k++ {
+			if !active[k] {
+				continue
+			}
+			if k == a || nearest[k] == a || nearest[k] == b {
+				findNearest(k)
+			} else if candidate(k, a) && dist[k][a] < nearestDist[k] {
+				nearest[k], nearestDist[k] = a, dist[k][a]
+			}
+		}
+	}
+	return merges
+}
+
+// cutMerges returns the cluster index of each of n nodes, after applying merges until k clusters remain.
+func cutMerges(n int, merges [][2]int, k int) []int {
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for _, m := range merges[:n-k] {
+		parent[find(m[1])] = find(m[0])
+	}
+
+	nodes := make([]int, n)
+	for i := range nodes {
+		nodes[i] = find(i)
+	}
+	return renumberClusters(nodes, n)
+}
+
+// renumberClusters renumbers cluster indices in the order of their first node.
+// Argument k is an upper bound for the cluster indices.
+func renumberClusters(nodes []int, k int) []int {
+	ids := make([]int, k)
+	for i := range ids {
+		ids[i] = -1
+	}
+	next := 0
+	result := make([]int, len(nodes))
+	for i, c := range nodes {
+		if ids[c] < 0 {
+			ids[c] = next
+			next++
+		}
+		result[i] = ids[c]
+	}
+	return result
+}
+
+// clusterScore calculates the given validity index of a clustering.
+func (s *Som) clusterScore(c *Clustering, index ClusterIndex) float64 {
+	if index == Silhouette {
+		return s.silhouette(c)
+	}
+	return s.daviesBouldin(c)
+}
+
+// daviesBouldin calculates the Davies-Bouldin index of a clustering.
+func (s *Som) daviesBouldin(c *Clustering) float64 {
+	vectors := s.nodeVectors()
+	centers := s.centroids(vectors, c.Nodes, c.Clusters)
+
+	scatter := make([]float64, c.Clusters)
+	counts := make([]int, c.Clusters)
+	for i, cl := range c.Nodes {
+		scatter[cl] += s.dataDistance(vectors[i], centers[cl])
+		counts[cl]++
+	}
+	for i := range scatter {
+		scatter[i] /= float64(counts[i])
+	}
+
+	index := 0.0
+	for i := 0; i < c.Clusters; i++ {
+		worst := 0.0
+		for j := 0; j < c.Clusters; j++ {
+			if i == j {
+				continue
+			}
+			sep := s.dataDistance(centers[i], centers[j])
+			if sep == 0 {
+				worst = math.Inf(1)
+				break
+			}
+			worst = max(worst, (scatter[i]+scatter[j])/sep)
+		}
+		index += worst
+	}
+	return index / float64(c.Clusters)
+}
+
+// silhouette calculates the mean silhouette coefficient of a clustering.
+// Nodes in singleton clusters have a coefficient of zero.
+func (s *Som) silhouette(c *Clustering) float64 {
+	n := len(c.Nodes)
+	counts := make([]int, c.Clusters)
+	for _, cl := range c.Nodes {
+		counts[cl]++
+	}
+
+	sum := 0.0
+	meanDist := make([]float64, c.Clusters)
+	for i, cl := range c.Nodes {
+		if counts[cl] < 2 {
+			continue
+		}
+		clear(meanDist)
+		for j, cl2 := range c.Nodes {
+			if i != j {
+				meanDist[cl2] += s.nodeDistance(i, j)
+			}
+		}
+		a := meanDist[cl] / float64(counts[cl]-1)
+		b := math.Inf(1)
+		for k, d := range meanDist {
+			if k != cl {
+				b = min(b, d/float64(counts[k]))
+			}
+		}
+		if m := max(a, b); m > 0 {
+			sum += (b - a) / m
+		}
+	}
+	return sum / float64(n)
+}
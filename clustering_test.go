@@ -0,0 +1,109 @@
+package som
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/mlange-42/som/conv"
+	"github.com/mlange-42/som/layer"
+	"github.com/mlange-42/som/neighborhood"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClusterMethod(t *testing.T) {
+	for name, m := range clusterMethods {
+		m2, ok := GetClusterMethod(name)
+		assert.True(t, ok)
+		assert.Equal(t, m, m2)
+		assert.Equal(t, name, m.String())
+	}
+	_, ok := GetClusterMethod("unknown")
+	assert.False(t, ok)
+
+	for name, idx := range clusterIndices {
+		idx2, ok := GetClusterIndex(name)
+		assert.True(t, ok)
+		assert.Equal(t, idx, idx2)
+		assert.Equal(t, name, idx.String())
+	}
+	_, ok = GetClusterIndex("unknown")
+	assert.False(t, ok)
+}
+
+func TestSomCluster(t *testing.T) {
+	s := createGrowingSom(t, layer.Size{Width: 6, Height: 1}, nil, neighborhood.Wrap{})
+	copy(s.layers[0].Weights(), []float64{0, 0.1, 0.2, 5, 5.1, 5.2})
+
+	for _, method := range []ClusterMethod{KMeans, WardLinkage, AverageLinkage} {
+		t.Run(method.String(), func(t *testing.T) {
+			c, err := s.Cluster(&ClusterConfig{Method: method, Clusters: 2}, rand.New(rand.NewSource(1)))
+			assert.NoError(t, err)
+			assert.Equal(t, 2, c.Clusters)
+			assert.Equal(t, []int{0, 0, 0, 1, 1, 1}, c.Nodes)
+
+			c, err = s.Cluster(&ClusterConfig{Method: method, Clusters: 3}, rand.New(rand.NewSource(1)))
+			assert.NoError(t, err)
+			assert.Equal(t, 3, c.Clusters)
+			assert.Len(t, uniqueInts(c.Nodes), 3)
+
+			for _, index := range []ClusterIndex{DaviesBouldin, Silhouette} {
+				c, err := s.Cluster(&ClusterConfig{Method: method, MinClusters: 2, MaxClusters: 5, Index: index}, rand.New(rand.NewSource(1)))
+				assert.NoError(t, err)
+				assert.Equal(t, 2, c.Clusters)
+				assert.Equal(t, []int{0, 0, 0, 1, 1, 1}, c.Nodes)
+			}
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := s.Cluster(&ClusterConfig{Method: KMeans, Clusters: 2, Connected: true}, rand.New(rand.NewSource(1)))
+		assert.Error(t, err)
+		_, err = s.Cluster(&ClusterConfig{Method: WardLinkage, Clusters: 7}, rand.New(rand.NewSource(1)))
+		assert.Error(t, err)
+		_, err = s.Cluster(&ClusterConfig{Method: WardLinkage, MinClusters: 4, MaxClusters: 3}, rand.New(rand.NewSource(1)))
+		assert.Error(t, err)
+	})
+}
+
+func TestSomClusterConnected(t *testing.T) {
+	s := createGrowingSom(t, layer.Size{Width: 4, Height: 1}, nil, neighborhood.Wrap{})
+	copy(s.layers[0].Weights(), []float64{0, 5, 0.1, 5.1})
+
+	for _, method := range []ClusterMethod{WardLinkage, AverageLinkage} {
+		t.Run(method.String(), func(t *testing.T) {
+			c, err := s.Cluster(&ClusterConfig{Method: method, Clusters: 2}, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, []int{0, 1, 0, 1}, c.Nodes)
+
+			c, err = s.Cluster(&ClusterConfig{Method: method, Clusters: 2, Connected: true}, nil)
+			assert.NoError(t, err)
+			assert.Contains(t, [][]int{{0, 0, 0, 1}, {0, 0, 1, 1}, {0, 1, 1, 1}}, c.Nodes)
+		})
+	}
+}
+
+func TestSomAddClusterLayer(t *testing.T) {
+	s := createGrowingSom(t, layer.Size{Width: 2, Height: 2}, nil, neighborhood.Wrap{})
+
+	c := &Clustering{Clusters: 3, Nodes: []int{0, 1, 1, 2}}
+	assert.NoError(t, s.AddClusterLayer("cluster", c))
+	assert.Len(t, s.layers, 2)
+
+	lay := s.layers[1]
+	assert.True(t, lay.IsCategorical())
+	assert.Equal(t, 0.0, lay.Weight())
+	classes, nodes := conv.LayerToClasses(lay)
+	assert.Equal(t, []string{"c1", "c2", "c3"}, classes)
+	assert.Equal(t, c.Nodes, nodes)
+
+	assert.Error(t, s.AddClusterLayer("cluster", c))
+	assert.Error(t, s.AddClusterLayer("cluster2", &Clustering{Clusters: 1, Nodes: []int{0}}))
+}
+
+func uniqueInts(values []int) map[int]bool {
+	unique := map[int]bool{}
+	for _, v := range values {
+		unique[v] = true
+	}
+	return unique
+}
@@ -17,6 +17,7 @@ func bmuCommand() *cobra.Command {
 	var noData string
 	var preserve []string
 	var ignore []string
+	var threads int
 
 	command := &cobra.Command{
 		Use:   "bmu [flags] <som-file> <data-file>",
@@ -39,6 +40,14 @@ Columns from the input table can be transferred to the output table by use of
 the --preserve flag. Here is how to transfer 'ID' and 'Name' columns:
 
   sum bmu som.yml data.csv --preserve ID,Name > bmu.csv
+
+For hierarchical SOMs (GHSOM), the table contains the path of BMU nodes
+through the hierarchy instead. Columns are:
+
+ - level_<L>: the index of the BMU node in the map at depth L,
+   or no-data if the row is not mapped to a child map at that depth
+ - node_dist: the distance between the input data and the BMU node
+   of the deepest map
 `,
 		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -78,16 +87,27 @@ the --preserve flag. Here is how to transfer 'ID' and 'Name' columns:
 				return err
 			}
 
-			s, err := som.New(config)
-			if err != nil {
-				return err
-			}
-			pred, err := som.NewPredictor(s, tables)
+			hierarchy, err := yml.ToHierarchy(somYaml)
 			if err != nil {
 				return err
 			}
 
-			bmu := pred.GetBMUTable()
+			var bmu *table.Table
+			if hierarchy.Depth() > 0 {
+				pred, err := som.NewHierarchyPredictor(hierarchy, tables)
+				if err != nil {
+					return err
+				}
+				pred.SetThreads(threads)
+				bmu = pred.GetBMUTable()
+			} else {
+				pred, err := som.NewPredictor(hierarchy.Som(), tables)
+				if err != nil {
+					return err
+				}
+				pred.SetThreads(threads)
+				bmu = pred.GetBMUTable()
+			}
 			writer := strings.Builder{}
 			err = csv.TablesToCsv([]*table.Table{bmu}, preserve, preserved, &writer, del[0], noData)
 			if err != nil {
@@ -101,6 +121,7 @@ the --preserve flag. Here is how to transfer 'ID' and 'Name' columns:
 	}
 	command.Flags().StringSliceVarP(&preserve, "preserve", "p", nil, "Preserve columns and prepend them to the output table")
 	command.Flags().StringSliceVarP(&ignore, "ignore", "i", []string{}, "Ignore these layers for BMU search")
+	command.Flags().IntVarP(&threads, "threads", "T", 0, "Number of threads for BMU search (default number of CPUs)")
 
 	command.Flags().StringVarP(&delim, "delimiter", "D", ",", "CSV delimiter for CSV input and output")
 	command.Flags().StringVarP(&noData, "no-data", "N", "", "No-data string for CSV input and output")
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+
+	"github.com/mlange-42/som"
+	"github.com/mlange-42/som/yml"
+	"github.com/spf13/cobra"
+)
+
+func clusterCommand() *cobra.Command {
+	var name string
+	var method string
+	var clusters int
+	var minClusters int
+	var maxClusters int
+	var index string
+	var connected bool
+	var seed int64
+
+	command := &cobra.Command{
+		Use:   "cluster [flags] <som-file>",
+		Short: "Clusters SOM nodes by their codebook vectors.",
+		Long: `Clusters SOM nodes by their codebook vectors.
+
+Adds a new categorical layer to the SOM with the cluster of each node.
+Clustering uses the layer weights and metrics, like BMU search.
+Layers with zero weight are not considered.
+
+Methods are k-means (kmeans), and agglomerative hierarchical clustering
+with Ward's criterion (ward) or average linkage (average).
+For hierarchical methods, --connected restricts merges to clusters
+that are adjacent on the map.
+
+Without --clusters, the number of clusters is selected automatically
+in the range given by --min and --max, using the Davies-Bouldin index
+(davies-bouldin) or the mean silhouette coefficient (silhouette).
+
+The clustered SOM is written to STDOUT in YAML format.
+Redirect output to a file like this:
+
+  som cluster som.yml --method ward --connected > clustered.yml
+
+The resulting SOM can subsequently used with other commands,
+e.g. for prediction of the clusters, or to show cluster boundaries:
+
+  som predict clustered.yml data.csv --layers cluster > predicted.csv
+  som plot u-matrix clustered.yml u-matrix.png --boundaries cluster`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			somFile := args[0]
+
+			somYaml, err := os.ReadFile(somFile)
+			if err != nil {
+				return err
+			}
+			config, _, err := yml.ToSomConfig(somYaml)
+			if err != nil {
+				return err
+			}
+
+			m, ok := som.GetClusterMethod(method)
+			if !ok {
+				return fmt.Errorf("unknown clustering method '%s'", method)
+			}
+			idx, ok := som.GetClusterIndex(index)
+			if !ok {
+				return fmt.Errorf("unknown cluster validity index '%s'", index)
+			}
+
+			s, err := som.New(config)
+			if err != nil {
+				return err
+			}
+
+			clustering, err := s.Cluster(&som.ClusterConfig{
+				Method:      m,
+				Clusters:    clusters,
+				MinClusters: minClusters,
+				MaxClusters: maxClusters,
+				Index:       idx,
+				Connected:   connected,
+			}, rand.New(rand.NewSource(seed)))
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Clusters: %d, %s: %.3f\n", clustering.Clusters, idx, clustering.Score)
+
+			if err := s.AddClusterLayer(name, clustering); err != nil {
+				return err
+			}
+
+			outYaml, err := yml.ToYAML(s)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(outYaml))
+
+			return nil
+		},
+	}
+
+	command.Flags().StringVarP(&name, "name", "n", "cluster", "Name of the layer to add for the clusters")
+	command.Flags().StringVarP(&method, "method", "m", "kmeans", "Clustering method, one of kmeans, ward or average")
+	command.Flags().IntVarP(&clusters, "clusters", "k", 0, "Number of clusters (default automatic selection)")
+	command.Flags().IntVar(&minClusters, "min", 2, "Minimum number of clusters for automatic selection")
+	command.Flags().IntVar(&maxClusters, "max", 10, "Maximum number of clusters for automatic selection")
+	command.Flags().StringVarP(&index, "index", "x", "davies-bouldin", "Validity index for automatic selection, one of davies-bouldin or silhouette")
+	command.Flags().BoolVarP(&connected, "connected", "c", false, "Only merge clusters that are adjacent on the map (hierarchical methods only)")
+	command.Flags().Int64VarP(&seed, "seed", "s", 42, "Random seed")
+
+	command.Flags().SortFlags = false
+
+	return command
+}
@@ -0,0 +1,342 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mlange-42/som"
+	"github.com/mlange-42/som/csv"
+	"github.com/mlange-42/som/layer"
+	"github.com/mlange-42/som/table"
+	"github.com/mlange-42/som/yml"
+	"github.com/spf13/cobra"
+)
+
+func evaluateCommand() *cobra.Command {
+	var delim string
+	var noData string
+	var ignore []string
+	var threads int
+	var layers []string
+	var format string
+
+	command := &cobra.Command{
+		Use:   "evaluate [flags] <som-file> <data-file>",
+		Short: "Evaluates predictions of a trained SOM against labeled data.",
+		Long: `Evaluates predictions of a trained SOM against labeled data.
+
+The layers given by --layers are predicted from all other layers,
+like in the predict command, and compared to their true values in the data file.
+
+For categorical layers, accuracy, precision, recall and F1 score per class,
+and the confusion matrix are reported.
+For continuous layers, the root mean square error (RMSE), mean absolute
+error (MAE) and coefficient of determination (R²) are reported per column.
+Rows with missing true values are skipped.
+
+Metrics are written to STDOUT, as text (default), CSV or JSON:
+
+  som evaluate som.yml data.csv --layers species --format json > metrics.json
+
+For hierarchical SOMs (GHSOM), predictions are taken from the BMU
+of the deepest map each row is mapped to.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(layers) == 0 {
+				return fmt.Errorf("at least one layer must be specified")
+			}
+			for _, l := range layers {
+				if slices.Contains(ignore, l) {
+					return fmt.Errorf("layer %s to evaluate can't be ignored", l)
+				}
+			}
+			if format != "text" && format != "csv" && format != "json" {
+				return fmt.Errorf("unknown output format '%s'; must be one of text, csv or json", format)
+			}
+
+			somFile := args[0]
+			dataFile := args[1]
+
+			somYaml, err := os.ReadFile(somFile)
+			if err != nil {
+				return err
+			}
+			config, _, err := yml.ToSomConfig(somYaml)
+			if err != nil {
+				return err
+			}
+
+			del := []rune(delim)
+			if len(delim) != 1 {
+				return fmt.Errorf("delimiter must be a single character")
+			}
+
+			reader, err := csv.NewFileReader(dataFile, del[0], noData)
+			if err != nil {
+				return err
+			}
+
+			tables, original, err := config.PrepareTables(reader, ignore, false, true)
+			if err != nil {
+				return err
+			}
+
+			hierarchy, err := yml.ToHierarchy(somYaml)
+			if err != nil {
+				return err
+			}
+			s := hierarchy.Som()
+
+			bmuTables := slices.Clone(tables)
+			for i, l := range s.Layers() {
+				if slices.Contains(layers, l.Name()) {
+					bmuTables[i] = nil
+				}
+			}
+			if !slices.ContainsFunc(bmuTables, func(t *table.Table) bool { return t != nil }) {
+				return fmt.Errorf("no layers left for BMU search")
+			}
+			predicted := make([]*table.Table, len(tables))
+
+			if hierarchy.Depth() > 0 {
+				pred, err := som.NewHierarchyPredictor(hierarchy, bmuTables)
+				if err != nil {
+					return err
+				}
+				pred.SetThreads(threads)
+				err = pred.Predict(predicted, layers)
+				if err != nil {
+					return err
+				}
+			} else {
+				pred, err := som.NewPredictor(s, bmuTables)
+				if err != nil {
+					return err
+				}
+				pred.SetThreads(threads)
+				err = pred.Predict(predicted, layers)
+				if err != nil {
+					return err
+				}
+			}
+
+			result := evaluation{}
+			for _, name := range layers {
+				idx := slices.IndexFunc(s.Layers(), func(l *layer.Layer) bool { return l.Name() == name })
+				if idx < 0 {
+					return fmt.Errorf("layer %s not found", name)
+				}
+				if s.Layers()[idx].IsCategorical() {
+					m, err := som.EvaluateClassification(name, original[idx], predicted[idx])
+					if err != nil {
+						return err
+					}
+					result.Classification = append(result.Classification, m)
+				} else {
+					m, err := som.EvaluateRegression(name, original[idx], predicted[idx])
+					if err != nil {
+						return err
+					}
+					result.Regression = append(result.Regression, m...)
+				}
+			}
+
+			switch format {
+			case "csv":
+				writeEvaluationCsv(os.Stdout, &result, del[0])
+			case "json":
+				return writeEvaluationJson(os.Stdout, &result)
+			default:
+				writeEvaluationText(os.Stdout, &result)
+			}
+			return nil
+		},
+	}
+
+	command.Flags().StringSliceVarP(&layers, "layers", "l", nil, "Evaluate predictions of these layers from all other layers")
+	command.Flags().StringSliceVarP(&ignore, "ignore", "i", []string{}, "Ignore these layers for BMU search")
+	command.Flags().IntVarP(&threads, "threads", "T", 0, "Number of threads for BMU search (default number of CPUs)")
+	command.Flags().StringVarP(&format, "format", "f", "text", "Output format, one of text, csv or json")
+
+	command.Flags().StringVarP(&delim, "delimiter", "D", ",", "CSV delimiter for CSV input and output")
+	command.Flags().StringVarP(&noData, "no-data", "N", "", "No-data string for CSV input")
+
+	command.MarkFlagRequired("layers")
+	command.Flags().SortFlags = false
+
+	return command
+}
+
+// evaluation holds the metrics of all evaluated layers.
+type evaluation struct {
+	Classification []*som.ClassificationMetrics
+	Regression     []som.RegressionMetrics
+}
+
+// writeEvaluationText writes evaluation metrics as human-readable text.
+func writeEvaluationText(w io.Writer, e *evaluation) {
+	for _, m := range e.Classification {
+		fmt.Fprintf(w, "Layer %s (%d rows)\n\n", m.Layer, m.Count)
+		fmt.Fprintf(w, "  Accuracy: %.3f\n\n", m.Accuracy)
+
+		width := len("Class")
+		for _, c := range m.Classes {
+			width = max(width, len(c))
+		}
+		fmt.Fprintf(w, "  %-*s %9s %9s %9s %9s\n", width, "Class", "Precision", "Recall", "F1", "Support")
+		for i, c := range m.Classes {
+			fmt.Fprintf(w, "  %-*s %9.3f %9.3f %9.3f %9d\n", width, c, m.Precision[i], m.Recall[i], m.F1[i], m.Support[i])
+		}
+
+		fmt.Fprintf(w, "\n  Confusion matrix (rows: true, columns: predicted)\n")
+		fmt.Fprintf(w, "  %-*s", width, "")
+		for _, c := range m.Classes {
+			fmt.Fprintf(w, " %*s", max(len(c), 6), c)
+		}
+		fmt.Fprintln(w)
+		for i, c := range m.Classes {
+			fmt.Fprintf(w, "  %-*s", width, c)
+			for j, c2 := range m.Classes {
+				fmt.Fprintf(w, " %*d", max(len(c2), 6), m.Confusion[i][j])
+			}
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(e.Regression) == 0 {
+		return
+	}
+	width := len("Column")
+	for _, m := range e.Regression {
+		width = max(width, len(m.Layer)+len(m.Column)+1)
+	}
+	fmt.Fprintf(w, "  %-*s %9s %9s %9s %9s\n", width, "Column", "RMSE", "MAE", "R2", "Count")
+	for _, m := range e.Regression {
+		fmt.Fprintf(w, "  %-*s %9.3f %9.3f %9.3f %9d\n", width, m.Layer+"."+m.Column, m.RMSE, m.MAE, m.R2, m.Count)
+	}
+}
+
+// writeEvaluationCsv writes evaluation metrics as a CSV table in long format,
+// with columns Layer, Column, Metric and Value.
+// For categorical layers, the Column is the class. Confusion matrix entries
+// use the true class as Column, and the predicted class in the metric name.
+func writeEvaluationCsv(w io.Writer, e *evaluation, delim rune) {
+	del := string(delim)
+	write := func(lay, column, metric string, value float64) {
+		fmt.Fprintln(w, strings.Join([]string{lay, column, metric, strconv.FormatFloat(value, 'f', -1, 64)}, del))
+	}
+
+	fmt.Fprintln(w, strings.Join([]string{"Layer", "Column", "Metric", "Value"}, del))
+	for _, m := range e.Classification {
+		write(m.Layer, "", "accuracy", m.Accuracy)
+		write(m.Layer, "", "count", float64(m.Count))
+		for i, c := range m.Classes {
+			write(m.Layer, c, "precision", m.Precision[i])
+			write(m.Layer, c, "recall", m.Recall[i])
+			write(m.Layer, c, "f1", m.F1[i])
+			write(m.Layer, c, "support", float64(m.Support[i]))
+		}
+		for i, c := range m.Classes {
+			for j, c2 := range m.Classes {
+				write(m.Layer, c, "predicted:"+c2, float64(m.Confusion[i][j]))
+			}
+		}
+	}
+	for _, m := range e.Regression {
+		write(m.Layer, m.Column, "rmse", m.RMSE)
+		write(m.Layer, m.Column, "mae", m.MAE)
+		write(m.Layer, m.Column, "r2", m.R2)
+		write(m.Layer, m.Column, "count", float64(m.Count))
+	}
+}
+
+// jsonFloat is a float that is encoded as null in JSON if it is NaN or infinite.
+type jsonFloat float64
+
+// MarshalJSON implements [json.Marshaler].
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return []byte("null"), nil
+	}
+	return json.Marshal(v)
+}
+
+type jsonClass struct {
+	Class     string    `json:"class"`
+	Precision jsonFloat `json:"precision"`
+	Recall    jsonFloat `json:"recall"`
+	F1        jsonFloat `json:"f1"`
+	Support   int       `json:"support"`
+}
+
+type jsonClassification struct {
+	Layer     string      `json:"layer"`
+	Count     int         `json:"count"`
+	Accuracy  jsonFloat   `json:"accuracy"`
+	Classes   []jsonClass `json:"classes"`
+	Confusion [][]int     `json:"confusion"`
+}
+
+type jsonRegression struct {
+	Layer  string    `json:"layer"`
+	Column string    `json:"column"`
+	Count  int       `json:"count"`
+	RMSE   jsonFloat `json:"rmse"`
+	MAE    jsonFloat `json:"mae"`
+	R2     jsonFloat `json:"r2"`
+}
+
+type jsonEvaluation struct {
+	Classification []jsonClassification `json:"classification"`
+	Regression     []jsonRegression     `json:"regression"`
+}
+
+// writeEvaluationJson writes evaluation metrics in JSON format.
+// Undefined metrics are written as null.
+func writeEvaluationJson(w io.Writer, e *evaluation) error {
+	out := jsonEvaluation{
+		Classification: []jsonClassification{},
+		Regression:     []jsonRegression{},
+	}
+	for _, m := range e.Classification {
+		classes := make([]jsonClass, len(m.Classes))
+		for i, c := range m.Classes {
+			classes[i] = jsonClass{
+				Class:     c,
+				Precision: jsonFloat(m.Precision[i]),
+				Recall:    jsonFloat(m.Recall[i]),
+				F1:        jsonFloat(m.F1[i]),
+				Support:   m.Support[i],
+			}
+		}
+		out.Classification = append(out.Classification, jsonClassification{
+			Layer:     m.Layer,
+			Count:     m.Count,
+			Accuracy:  jsonFloat(m.Accuracy),
+			Classes:   classes,
+			Confusion: m.Confusion,
+		})
+	}
+	for _, m := range e.Regression {
+		out.Regression = append(out.Regression, jsonRegression{
+			Layer:  m.Layer,
+			Column: m.Column,
+			Count:  m.Count,
+			RMSE:   jsonFloat(m.RMSE),
+			MAE:    jsonFloat(m.MAE),
+			R2:     jsonFloat(m.R2),
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
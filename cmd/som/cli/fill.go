@@ -18,6 +18,7 @@ func fillCommand() *cobra.Command {
 	var noData string
 	var preserve []string
 	var ignore []string
+	var threads int
 
 	command := &cobra.Command{
 		Use:   "fill [flags] <som-file> <data-file>",
@@ -84,6 +85,7 @@ Redirect output to a file like this:
 			if err != nil {
 				return err
 			}
+			pred.SetThreads(threads)
 
 			err = pred.FillMissing(original)
 			if err != nil {
@@ -95,6 +97,7 @@ Redirect output to a file like this:
 	}
 	command.Flags().StringSliceVarP(&preserve, "preserve", "p", nil, "Preserve columns and prepend them to the output table")
 	command.Flags().StringSliceVarP(&ignore, "ignore", "i", []string{}, "Ignore these layers for BMU search")
+	command.Flags().IntVarP(&threads, "threads", "T", 0, "Number of threads for BMU search (default number of CPUs)")
 
 	command.Flags().StringVarP(&delim, "delimiter", "D", ",", "CSV delimiter")
 	command.Flags().StringVarP(&noData, "no-data", "N", "", "No-data string")
@@ -6,6 +6,7 @@ import (
 
 	"github.com/mlange-42/som"
 	"github.com/mlange-42/som/csv"
+	"github.com/mlange-42/som/neighborhood"
 	"github.com/mlange-42/som/plot"
 	"github.com/mlange-42/som/table"
 	"github.com/spf13/cobra"
@@ -36,6 +37,8 @@ Python or R to create more refined and customized graphics.`,
 	command.AddCommand(plotHeatmapCommand())
 	command.AddCommand(plotCodesCommand())
 	command.AddCommand(plotUMatrixCommand())
+	command.AddCommand(plotPMatrixCommand())
+	command.AddCommand(plotUStarCommand())
 	command.AddCommand(plotXyCommand())
 	command.AddCommand(plotDensityCommand())
 	command.AddCommand(plotErrorCommand())
@@ -45,12 +48,14 @@ Python or R to create more refined and customized graphics.`,
 	return command
 }
 
+// plotHeatmap plots the grid returned by getData as a heatmap.
+// If getData returns boundaries, they are shown instead of the boundaries of the given layer.
 func plotHeatmap(size []int,
 	somFile, outFile, dataFile,
 	labelsColumn, delim, noData string,
 	title string,
-	ignoreLayers []string, boundaries string, sampleData int,
-	getData func(s *som.Som, p *som.Predictor, r table.Reader) (plotter.GridXYZ, []string, error)) error {
+	ignoreLayers []string, boundaries string, sampleData int, tiled bool,
+	getData func(s *som.Som, p *som.Predictor, r table.Reader) (plotter.GridXYZ, plotter.GridXYZ, []string, error)) error {
 
 	del := []rune(delim)
 	if len(delim) != 1 {
@@ -98,12 +103,22 @@ func plotHeatmap(size []int,
 		return err
 	}
 
-	grid, cats, err := getData(s, predictor, reader)
+	grid, dataBounds, cats, err := getData(s, predictor, reader)
 	if err != nil {
 		return err
 	}
+	if dataBounds != nil {
+		bounds = dataBounds
+	}
+
+	if tiled {
+		grid, bounds, labels, positions, err = tileView(s, grid, bounds, labels, positions)
+		if err != nil {
+			return err
+		}
+	}
 
-	img, err := plot.Heatmap(title, grid, bounds, size[0], size[1], cats, labels, positions)
+	img, err := plot.Heatmap(title, grid, bounds, isHexagonal(s), size[0], size[1], cats, labels, positions)
 	if err != nil {
 		return err
 	}
@@ -111,6 +126,52 @@ func plotHeatmap(size []int,
 	return writeImage(img, outFile)
 }
 
+func isHexagonal(s *som.Som) bool {
+	_, ok := s.Topology().(*neighborhood.Hexagonal)
+	return ok
+}
+
+// tileView repeats grids and label positions twice along the periodic axes of the SOM.
+func tileView(s *som.Som, grid, bounds plotter.GridXYZ,
+	labels []string, positions []plotter.XY) (plotter.GridXYZ, plotter.GridXYZ, []string, []plotter.XY, error) {
+	wrap := s.Wrap()
+	if !wrap.Any() {
+		return nil, nil, nil, nil, fmt.Errorf("tiled view requires a SOM with wrap boundaries")
+	}
+	tilesX, tilesY := 1, 1
+	if wrap.X {
+		tilesX = 2
+	}
+	if wrap.Y {
+		tilesY = 2
+	}
+
+	grid = &plot.TiledGrid{Grid: grid, TilesX: tilesX, TilesY: tilesY}
+	if bounds != nil {
+		bounds = &plot.TiledGrid{Grid: bounds, TilesX: tilesX, TilesY: tilesY}
+	}
+
+	size := s.Size()
+	periodX, _ := s.Topology().Position(size.Width, 0)
+	_, periodY := s.Topology().Position(0, size.Height)
+
+	tiledLabels := make([]string, 0, len(labels)*tilesX*tilesY)
+	tiledPositions := make([]plotter.XY, 0, len(positions)*tilesX*tilesY)
+	for i := 0; i < tilesX; i++ {
+		for j := 0; j < tilesY; j++ {
+			for k, p := range positions {
+				tiledLabels = append(tiledLabels, labels[k])
+				tiledPositions = append(tiledPositions, plotter.XY{
+					X: p.X + float64(i)*periodX,
+					Y: p.Y + float64(j)*periodY,
+				})
+			}
+		}
+	}
+
+	return grid, bounds, tiledLabels, tiledPositions, nil
+}
+
 func stringsToColors(colors []string) ([]color.Color, error) {
 	cols := make([]color.Color, len(colors))
 	var ok bool
@@ -18,6 +18,8 @@ func plotErrorCommand() *cobra.Command {
 	var rmse bool
 	var ignore []string
 	var sample int
+	var tiled bool
+	var weightColumn string
 
 	command := &cobra.Command{
 		Use:   "error [flags] <som-file> <out-file>",
@@ -35,7 +37,10 @@ showing the values in the column given by the --label flag:
 For large datasets, --sample can be used to show only a sub-set of the data.
 
 For SOMs with categorical variables, --boundaries can be used to show
-boundaries between categories.`,
+boundaries between categories.
+
+For SOMs with wrap boundaries, --tiled shows the map repeated along
+its periodic axes, so that clusters across the edges are visible.`,
 		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			somFile := args[0]
@@ -51,10 +56,15 @@ boundaries between categories.`,
 			return plotHeatmap(size,
 				somFile, outFile, dataFile,
 				labelsColumn, delim, noData, title,
-				ignore, boundaries, sample,
-				func(s *som.Som, p *som.Predictor, r table.Reader) (plotter.GridXYZ, []string, error) {
+				ignore, boundaries, sample, tiled,
+				func(s *som.Som, p *som.Predictor, r table.Reader) (plotter.GridXYZ, plotter.GridXYZ, []string, error) {
+					if weightColumn != "" {
+						if err := setPredictorWeights(p, r, weightColumn); err != nil {
+							return nil, nil, nil, err
+						}
+					}
 					mse := p.GetError(rmse)
-					return &plot.FloatGrid{Size: *s.Size(), Values: mse}, nil, nil
+					return &plot.FloatGrid{Size: *s.Size(), Values: mse}, nil, nil, nil
 				},
 			)
 		},
@@ -68,6 +78,8 @@ boundaries between categories.`,
 	command.Flags().StringVarP(&labelsColumn, "label", "l", "", "Label column in the data file")
 	command.Flags().StringSliceVarP(&ignore, "ignore", "i", []string{}, "Ignore these layers for BMU search")
 	command.Flags().IntVarP(&sample, "sample", "S", 0, "Sample this many rows from the data file (default all)")
+	command.Flags().BoolVarP(&tiled, "tiled", "t", false, "Show periodic maps tiled along wrapped axes")
+	command.Flags().StringVarP(&weightColumn, "weight-column", "W", "", "Column with row weights in the data file (default unweighted)")
 
 	command.Flags().StringVarP(&delim, "delimiter", "D", ",", "CSV delimiter")
 	command.Flags().StringVarP(&noData, "no-data", "N", "", "No-data value (default \"\")")
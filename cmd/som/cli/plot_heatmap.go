@@ -31,6 +31,7 @@ func plotHeatmapCommand() *cobra.Command {
 	var noData string
 	var ignore []string
 	var sample int
+	var tiled bool
 
 	command := &cobra.Command{
 		Use:   "heatmap [flags] <som-file> <out-file>",
@@ -52,7 +53,10 @@ showing the values in the column given by the --label flag:
 
   som plot heatmap som.yml heatmap.png --data-file data.csv --label name
 
-For large datasets, --sample can be used to show only a sub-set of the data.`,
+For large datasets, --sample can be used to show only a sub-set of the data.
+
+For SOMs with wrap boundaries, --tiled shows the map repeated along
+its periodic axes, so that clusters across the edges are visible.`,
 		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			somFile := args[0]
@@ -122,7 +126,14 @@ For large datasets, --sample can be used to show only a sub-set of the data.`,
 				c, r := i%plotColumns, i/plotColumns
 
 				title, classes, grid := createTitleAndGrid(s, layer, col)
-				subImg, err := plot.Heatmap(title, grid, bounds, size[0], size[1], classes, labels, positions)
+				subBounds, subLabels, subPositions := bounds, labels, positions
+				if tiled {
+					grid, subBounds, subLabels, subPositions, err = tileView(s, grid, bounds, labels, positions)
+					if err != nil {
+						return err
+					}
+				}
+				subImg, err := plot.Heatmap(title, grid, subBounds, isHexagonal(s), size[0], size[1], classes, subLabels, subPositions)
 				if err != nil {
 					return err
 				}
@@ -142,6 +153,7 @@ For large datasets, --sample can be used to show only a sub-set of the data.`,
 	command.Flags().StringVarP(&labelsColumn, "label", "l", "", "Label column in the data file")
 	command.Flags().StringSliceVarP(&ignore, "ignore", "i", []string{}, "Ignore these layers for BMU search")
 	command.Flags().IntVarP(&sample, "sample", "S", 0, "Sample this many rows from the data file (default all)")
+	command.Flags().BoolVarP(&tiled, "tiled", "t", false, "Show periodic maps tiled along wrapped axes")
 
 	command.Flags().StringVarP(&delim, "delimiter", "D", ",", "CSV delimiter")
 	command.Flags().StringVarP(&noData, "no-data", "N", "", "No-data value (default \"\")")
@@ -296,6 +308,7 @@ func extractLabels(predictor *som.Predictor,
 
 	bmu := predictor.GetBMUTable()
 	nodes := predictor.Som().Size().Nodes()
+	topology := predictor.Som().Topology()
 
 	indices := make([]int, len(labels))
 	for i := range indices {
@@ -322,7 +335,11 @@ func extractLabels(predictor *som.Predictor,
 
 		frac := float64(count[idx]+1) / float64(perCell[idx]+1)
 
-		xy[c].X, xy[c].Y = bmu.Get(i, 1), bmu.Get(i, 2)-0.5+frac
+		x, y := int(bmu.Get(i, 1)), int(bmu.Get(i, 2))
+		px, py := topology.Position(x, y)
+		_, pyNext := topology.Position(x, y+1)
+
+		xy[c].X, xy[c].Y = px, py+(frac-0.5)*(pyNext-py)
 		count[idx]++
 
 		if outLabel != nil {
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mlange-42/som"
+	"github.com/mlange-42/som/plot"
+	"github.com/mlange-42/som/table"
+	"github.com/spf13/cobra"
+	"gonum.org/v1/plot/plotter"
+)
+
+func plotPMatrixCommand() *cobra.Command {
+	return densityMatrixCommand(
+		"pmatrix",
+		"Plots the P-matrix of an SOM, showing the data density around nodes.",
+		`Plots the P-matrix of an SOM, showing the data density around nodes.
+
+For each node, counts the data points from --data-file that are within
+--radius of the node's vector in data space. Distances are calculated like
+for BMU search. Without --radius, the Pareto radius of the data is used.
+
+Data provided via --data-file can be displayed on top of the P-matrix,
+showing the values in the column given by the --label flag:
+
+  som plot pmatrix som.yml pmatrix.png --data-file data.csv --label name`,
+		"P-Matrix",
+		func(p *som.Predictor, radius float64) []float64 { return p.PMatrix(radius) },
+	)
+}
+
+func plotUStarCommand() *cobra.Command {
+	return densityMatrixCommand(
+		"ustar",
+		"Plots the U*-matrix of an SOM, the u-matrix scaled by data density.",
+		`Plots the U*-matrix of an SOM, the u-matrix scaled by data density.
+
+For each node, the mean distance to its neighbors is scaled by the
+data density of the P-matrix, so that distances inside dense regions
+are reduced. See also 'som plot pmatrix' and 'som plot u-matrix'.
+Without --radius, the Pareto radius of the data is used.
+
+Data provided via --data-file can be displayed on top of the U*-matrix,
+showing the values in the column given by the --label flag:
+
+  som plot ustar som.yml ustar.png --data-file data.csv --label name`,
+		"U*-Matrix",
+		func(p *som.Predictor, radius float64) []float64 { return p.UStarMatrix(radius) },
+	)
+}
+
+// densityMatrixCommand creates a plot command for a node matrix that is derived from data density.
+func densityMatrixCommand(use, short, long, title string, getValues func(p *som.Predictor, radius float64) []float64) *cobra.Command {
+	var size []int
+	var dataFile string
+	var labelsColumn string
+	var boundaries string
+	var delim string
+	var noData string
+	var ignore []string
+	var sample int
+	var tiled bool
+	var weightColumn string
+	var radius float64
+
+	command := &cobra.Command{
+		Use:   use + " [flags] <som-file> <out-file>",
+		Short: short,
+		Long: long + `
+
+For large datasets, --sample can be used to show only a sub-set of the data.
+
+For SOMs with categorical variables, --boundaries can be used to show
+boundaries between categories.
+
+For SOMs with wrap boundaries, --tiled shows the map repeated along
+its periodic axes, so that clusters across the edges are visible.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			somFile := args[0]
+			outFile := args[1]
+
+			return plotHeatmap(size,
+				somFile, outFile, dataFile,
+				labelsColumn, delim, noData, title,
+				ignore, boundaries, sample, tiled,
+				func(s *som.Som, p *som.Predictor, r table.Reader) (plotter.GridXYZ, plotter.GridXYZ, []string, error) {
+					if weightColumn != "" {
+						if err := setPredictorWeights(p, r, weightColumn); err != nil {
+							return nil, nil, nil, err
+						}
+					}
+					rad := radius
+					if rad <= 0 {
+						rad = p.ParetoRadius()
+						fmt.Fprintf(os.Stderr, "Pareto radius: %f\n", rad)
+					}
+					return &plot.FloatGrid{Size: *s.Size(), Values: getValues(p, rad)}, nil, nil, nil
+				},
+			)
+		},
+	}
+
+	command.Flags().Float64VarP(&radius, "radius", "r", 0, "Radius for data density in data space (default Pareto radius)")
+	command.Flags().StringVarP(&boundaries, "boundaries", "b", "", "Optional categorical variable to show boundaries for")
+	command.Flags().IntSliceVarP(&size, "size", "s", []int{600, 400}, "Size of the plot in pixels")
+	command.Flags().StringVarP(&dataFile, "data-file", "f", "", "Data file. Required")
+	command.Flags().StringVarP(&labelsColumn, "label", "l", "", "Label column in the data file")
+	command.Flags().StringSliceVarP(&ignore, "ignore", "i", []string{}, "Ignore these layers for BMU search")
+	command.Flags().IntVarP(&sample, "sample", "S", 0, "Sample this many rows from the data file (default all)")
+	command.Flags().BoolVarP(&tiled, "tiled", "t", false, "Show periodic maps tiled along wrapped axes")
+	command.Flags().StringVarP(&weightColumn, "weight-column", "W", "", "Column with row weights in the data file (default unweighted)")
+
+	command.Flags().StringVarP(&delim, "delimiter", "D", ",", "CSV delimiter")
+	command.Flags().StringVarP(&noData, "no-data", "N", "", "No-data value (default \"\")")
+
+	command.Flags().SortFlags = false
+	command.MarkFlagRequired("data-file")
+	command.MarkFlagFilename("data-file", "csv")
+
+	return command
+}
@@ -1,6 +1,8 @@
 package cli
 
 import (
+	"fmt"
+
 	"github.com/mlange-42/som"
 	"github.com/mlange-42/som/plot"
 	"github.com/mlange-42/som/table"
@@ -17,6 +19,10 @@ func plotUMatrixCommand() *cobra.Command {
 	var noData string
 	var ignore []string
 	var sample int
+	var tiled bool
+	var segments bool
+	var minSize int
+	var mergeThreshold float64
 
 	command := &cobra.Command{
 		Use:   "u-matrix [flags] <som-file> <out-file>",
@@ -31,19 +37,41 @@ showing the values in the column given by the --label flag:
 For large datasets, --sample can be used to show only a sub-set of the data.
 
 For SOMs with categorical variables, --boundaries can be used to show
-boundaries between categories.`,
+boundaries between categories.
+
+With --segments, boundaries of a watershed segmentation of the u-matrix
+are shown instead. See 'som segment' for details and the parameters
+--min-size and --merge-threshold.
+
+For SOMs with wrap boundaries, --tiled shows the map repeated along
+its periodic axes, so that clusters across the edges are visible.
+
+For hexagonal SOMs, each node's cell shows the average distance
+to its neighbors.`,
 		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			somFile := args[0]
 			outFile := args[1]
 
+			if segments && boundaries != "" {
+				return fmt.Errorf("only one of --boundaries and --segments can be used")
+			}
+
 			return plotHeatmap(size,
 				somFile, outFile, dataFile,
 				labelsColumn, delim, noData, "U-Matrix",
-				ignore, boundaries, sample,
-				func(s *som.Som, p *som.Predictor, r table.Reader) (plotter.GridXYZ, []string, error) {
+				ignore, boundaries, sample, tiled,
+				func(s *som.Som, p *som.Predictor, r table.Reader) (plotter.GridXYZ, plotter.GridXYZ, []string, error) {
+					var bounds plotter.GridXYZ
+					if segments {
+						seg := s.Segment(&som.SegmentConfig{MinSize: minSize, MergeThreshold: mergeThreshold})
+						bounds = &plot.IntGrid{Size: *s.Size(), Values: seg.Nodes}
+					}
 					uMatrix := s.UMatrix(true)
-					return &plot.UMatrixGrid{UMatrix: uMatrix}, nil, nil
+					if isHexagonal(s) {
+						return &plot.UMatrixNodeGrid{UMatrix: uMatrix}, bounds, nil, nil
+					}
+					return &plot.UMatrixGrid{UMatrix: uMatrix}, bounds, nil, nil
 				},
 			)
 		},
@@ -55,6 +83,10 @@ boundaries between categories.`,
 	command.Flags().StringSliceVarP(&ignore, "ignore", "i", []string{}, "Ignore these layers for BMU search")
 	command.Flags().StringVarP(&labelsColumn, "label", "l", "", "Label column in the data file")
 	command.Flags().IntVarP(&sample, "sample", "S", 0, "Sample this many rows from the data file (default all)")
+	command.Flags().BoolVarP(&tiled, "tiled", "t", false, "Show periodic maps tiled along wrapped axes")
+	command.Flags().BoolVar(&segments, "segments", false, "Show boundaries of a watershed segmentation of the u-matrix")
+	command.Flags().IntVar(&minSize, "min-size", 1, "Minimum number of nodes per segment, for --segments")
+	command.Flags().Float64Var(&mergeThreshold, "merge-threshold", 0, "Minimum height of segment boundaries above the higher of the two segments' minima, for --segments")
 
 	command.Flags().StringVarP(&delim, "delimiter", "D", ",", "CSV delimiter")
 	command.Flags().StringVarP(&noData, "no-data", "N", "", "No-data value (default \"\")")
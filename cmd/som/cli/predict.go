@@ -16,6 +16,7 @@ func predictCommand() *cobra.Command {
 	var noData string
 	var preserve []string
 	var ignore []string
+	var threads int
 	var layers []string
 	var writeAllLayers bool
 
@@ -33,7 +34,10 @@ not SOM variables can be transferred from the input table using --preserve.
 The result table is written to STDOUT in CSV format.
 Redirect output to a file like this:
 
-  som predict som.yml data.csv --layers class > predicted.csv`,
+  som predict som.yml data.csv --layers class > predicted.csv
+
+For hierarchical SOMs (GHSOM), predictions are taken from the BMU
+of the deepest map each row is mapped to.`,
 		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(layers) == 0 {
@@ -78,18 +82,32 @@ Redirect output to a file like this:
 				return err
 			}
 
-			s, err := som.New(config)
-			if err != nil {
-				return err
-			}
-			pred, err := som.NewPredictor(s, tables)
+			hierarchy, err := yml.ToHierarchy(somYaml)
 			if err != nil {
 				return err
 			}
+			s := hierarchy.Som()
 
-			err = pred.Predict(original, layers)
-			if err != nil {
-				return err
+			if hierarchy.Depth() > 0 {
+				pred, err := som.NewHierarchyPredictor(hierarchy, tables)
+				if err != nil {
+					return err
+				}
+				pred.SetThreads(threads)
+				err = pred.Predict(original, layers)
+				if err != nil {
+					return err
+				}
+			} else {
+				pred, err := som.NewPredictor(s, tables)
+				if err != nil {
+					return err
+				}
+				pred.SetThreads(threads)
+				err = pred.Predict(original, layers)
+				if err != nil {
+					return err
+				}
 			}
 
 			if !writeAllLayers {
@@ -108,6 +126,7 @@ Redirect output to a file like this:
 	command.Flags().StringSliceVarP(&layers, "layers", "l", nil, "Predict these layers from all other layers")
 	command.Flags().StringSliceVarP(&preserve, "preserve", "p", nil, "Preserve columns and prepend them to the output table")
 	command.Flags().StringSliceVarP(&ignore, "ignore", "i", []string{}, "Ignore these layers for BMU search")
+	command.Flags().IntVarP(&threads, "threads", "T", 0, "Number of threads for BMU search (default number of CPUs)")
 	command.Flags().BoolVarP(&writeAllLayers, "all", "a", false, "Write all layers instead of just predicted layers")
 
 	command.Flags().StringVarP(&delim, "delimiter", "D", ",", "CSV delimiter")
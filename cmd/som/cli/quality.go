@@ -2,11 +2,11 @@ package cli
 
 import (
 	"fmt"
+	"math"
 	"os"
 
 	"github.com/mlange-42/som"
 	"github.com/mlange-42/som/csv"
-	"github.com/mlange-42/som/neighborhood"
 	"github.com/mlange-42/som/yml"
 	"github.com/spf13/cobra"
 )
@@ -15,6 +15,11 @@ func qualityCommand() *cobra.Command {
 	var delim string
 	var noData string
 	var ignore []string
+	var threads int
+	var weightColumn string
+	var neighbors int
+	var radius float64
+	var perLayer bool
 
 	command := &cobra.Command{
 		Use:   "quality [flags] <som-file> <data-file>",
@@ -26,7 +31,29 @@ Calculates the following SOM quality metrics and prints them to STDOUT:
  - Quantization error
  - Mean square error
  - Root mean square error
- - Topographic error`,
+ - Topographic error
+ - Topographic product
+ - Trustworthiness and continuity, with --neighbors
+ - Neighborhood preservation, with --neighbors
+ - Kaski-Lagus combined error
+ - Distortion, with neighborhood --radius
+ - Explained variance
+
+All metrics use the SOM's map metric, topology and boundaries for distances
+on the map.
+
+Trustworthiness, continuity and neighborhood preservation are only calculated
+with --neighbors, for the given number of nearest neighbors. Note that they are
+quadratic in the number of data rows, and require more than twice as many rows
+as neighbors.
+
+With --per-layer, the contribution of each layer to the quantization error
+is shown in addition, with its share of the sum over all layers.
+
+With --weight-column, the contribution of each data row to the metrics
+is weighted by the values in that column, e.g. for survey sampling weights.
+Trustworthiness, continuity, neighborhood preservation and the topographic
+product are not weighted.`,
 		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			somFile := args[0]
@@ -64,21 +91,72 @@ Calculates the following SOM quality metrics and prints them to STDOUT:
 			if err != nil {
 				return err
 			}
+			pred.SetThreads(threads)
+			if weightColumn != "" {
+				if err := setPredictorWeights(pred, reader, weightColumn); err != nil {
+					return err
+				}
+			}
 			eval := som.NewEvaluator(pred)
 
 			qe, mse, rmse := eval.Error()
-			te := eval.TopographicError(&neighborhood.ManhattanMetric{})
-
-			fmt.Printf(`Quantization error:     %7.3f
-Mean square error:      %7.3f
-Root mean square error: %7.3f
-Topographic error:      %7.3f
-`, qe, mse, rmse, te)
+			te := eval.TopographicError(s.MapMetric())
+			tp := eval.TopographicProduct()
+			var neigh som.Neighborhoods
+			if neighbors > 0 {
+				neigh, err = eval.Neighborhoods(neighbors)
+				if err != nil {
+					return err
+				}
+			}
+			kl := eval.KaskiLagusError()
+			distortion := eval.Distortion(radius)
+			ev := eval.ExplainedVariance()
+
+			fmt.Printf(`Quantization error:        %7.3f
+Mean square error:         %7.3f
+Root mean square error:    %7.3f
+Topographic error:         %7.3f
+Topographic product:       %7.3f
+`, qe, mse, rmse, te, tp)
+			if neighbors > 0 {
+				fmt.Printf(`Trustworthiness:           %7.3f
+Continuity:                %7.3f
+Neighborhood preservation: %7.3f
+`, neigh.Trustworthiness, neigh.Continuity, neigh.Preservation)
+			}
+			fmt.Printf(`Kaski-Lagus error:         %7.3f
+Distortion:                %7.3f
+Explained variance:        %7.3f
+`, kl, distortion, ev)
+
+			if perLayer {
+				layerErrors := eval.LayerErrors()
+				total := 0.0
+				for _, e := range layerErrors {
+					if !math.IsNaN(e) {
+						total += e
+					}
+				}
+				fmt.Printf("\nQuantization error per layer:\n")
+				for i, l := range s.Layers() {
+					if math.IsNaN(layerErrors[i]) {
+						fmt.Printf("  %-24s ignored\n", l.Name()+":")
+						continue
+					}
+					fmt.Printf("  %-24s %7.3f (%5.1f%%)\n", l.Name()+":", layerErrors[i], 100*layerErrors[i]/total)
+				}
+			}
 
 			return nil
 		},
 	}
 	command.Flags().StringSliceVarP(&ignore, "ignore", "i", []string{}, "Ignore these layers for BMU search")
+	command.Flags().IntVarP(&threads, "threads", "T", 0, "Number of threads for BMU search (default number of CPUs)")
+	command.Flags().StringVarP(&weightColumn, "weight-column", "W", "", "Column with row weights (default unweighted)")
+	command.Flags().IntVarP(&neighbors, "neighbors", "k", 0, "Number of nearest neighbors for trustworthiness, continuity and neighborhood preservation (default not calculated)")
+	command.Flags().Float64VarP(&radius, "radius", "r", 1, "Neighborhood radius for the distortion measure")
+	command.Flags().BoolVarP(&perLayer, "per-layer", "l", false, "Show the contribution of each layer to the quantization error")
 
 	command.Flags().StringVarP(&delim, "delimiter", "D", ",", "CSV delimiter for CSV input and output")
 	command.Flags().StringVarP(&noData, "no-data", "N", "", "No-data string for CSV input and output")
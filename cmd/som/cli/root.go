@@ -44,10 +44,14 @@ Use 'som [command] --help' for more information about a command.`,
 	}
 
 	root.AddCommand(trainCommand())
+	root.AddCommand(tuneCommand())
 	root.AddCommand(qualityCommand())
 	root.AddCommand(labelCommand())
+	root.AddCommand(clusterCommand())
+	root.AddCommand(segmentCommand())
 	root.AddCommand(exportCommand())
 	root.AddCommand(predictCommand())
+	root.AddCommand(evaluateCommand())
 	root.AddCommand(bmuCommand())
 	root.AddCommand(fillCommand())
 	root.AddCommand(plotCommand())
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mlange-42/som"
+	"github.com/mlange-42/som/yml"
+	"github.com/spf13/cobra"
+)
+
+func segmentCommand() *cobra.Command {
+	var name string
+	var minSize int
+	var mergeThreshold float64
+
+	command := &cobra.Command{
+		Use:   "segment [flags] <som-file>",
+		Short: "Segments the u-matrix of an SOM into regions using watershed.",
+		Long: `Segments the u-matrix of an SOM into regions using watershed.
+
+Adds a new categorical layer to the SOM with the segment of each node.
+
+The u-matrix is flooded from its local minima, so that segments are
+separated by ridges of high inter-node distances. Subsequently, adjacent
+segments are merged, starting at the lowest boundary, if one of them has
+less than --min-size nodes, or if their boundary is less than
+--merge-threshold above the higher of the two segments' minima.
+Heights of boundaries and minima are the node heights of the u-matrix.
+
+The segmented SOM is written to STDOUT in YAML format.
+Redirect output to a file like this:
+
+  som segment som.yml --min-size 4 --merge-threshold 0.2 > segmented.yml
+
+The resulting SOM can subsequently used with other commands,
+e.g. for prediction of the segments, or to show segment boundaries:
+
+  som predict segmented.yml data.csv --layers segment > predicted.csv
+  som plot heatmap segmented.yml heatmap.png --boundaries segment
+
+Segment boundaries can also be shown directly in 'som plot u-matrix'
+using --segments.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			somFile := args[0]
+
+			somYaml, err := os.ReadFile(somFile)
+			if err != nil {
+				return err
+			}
+			config, _, err := yml.ToSomConfig(somYaml)
+			if err != nil {
+				return err
+			}
+
+			s, err := som.New(config)
+			if err != nil {
+				return err
+			}
+
+			segments := s.Segment(&som.SegmentConfig{
+				MinSize:        minSize,
+				MergeThreshold: mergeThreshold,
+			})
+			fmt.Fprintf(os.Stderr, "Segments: %d\n", segments.Clusters)
+
+			if err := s.AddClusterLayer(name, segments); err != nil {
+				return err
+			}
+
+			outYaml, err := yml.ToYAML(s)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(outYaml))
+
+			return nil
+		},
+	}
+
+	command.Flags().StringVarP(&name, "name", "n", "segment", "Name of the layer to add for the segments")
+	command.Flags().IntVarP(&minSize, "min-size", "m", 1, "Minimum number of nodes per segment")
+	command.Flags().Float64VarP(&mergeThreshold, "merge-threshold", "t", 0, "Minimum height of segment boundaries above the higher of the two segments' minima")
+
+	command.Flags().SortFlags = false
+
+	return command
+}
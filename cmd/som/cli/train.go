@@ -1,15 +1,21 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"math"
 	"math/rand"
 	"os"
+	"os/signal"
+	"slices"
 	"time"
 
 	"github.com/mlange-42/som"
+	"github.com/mlange-42/som/conv"
 	"github.com/mlange-42/som/csv"
 	"github.com/mlange-42/som/decay"
+	"github.com/mlange-42/som/layer"
 	"github.com/mlange-42/som/neighborhood"
 	"github.com/mlange-42/som/table"
 	"github.com/mlange-42/som/yml"
@@ -27,13 +33,17 @@ const full = '█'
 
 func trainCommand() *cobra.Command {
 	var seed int64
+	var algorithm string
 	var alpha string
 	var radius string
 	var decayFunc string
 	var epochs int
 	var visomLambda float64
+	var initialization string
 
 	var size []int
+	var topology string
+	var wrap string
 	var neighborhood string
 	var metric string
 	var viSomMetric string
@@ -44,6 +54,24 @@ func trainCommand() *cobra.Command {
 	var progressFile string
 	var progressInterval int
 
+	var weightColumn string
+	var balance string
+	var balanceColumn string
+
+	var validationFile string
+	var validationFraction float64
+	var validationInterval int
+
+	var hierarchical bool
+	var continueTraining bool
+
+	var checkpointFile string
+	var checkpointEpochs int
+	var checkpointMinutes float64
+	var resumeFile string
+
+	var threads int
+
 	var cpuProfile bool
 
 	var command *cobra.Command
@@ -58,7 +86,51 @@ Redirect output to a file like this:
   som train som.yml data.csv > trained.yml
 
 Learning parameters are usually specified in the SOM's YAML file,
-but can also be set or overwritten using the provided CLI flags.`,
+but can also be set or overwritten using the provided CLI flags.
+
+With --hierarchical, a Growing Hierarchical SOM (GHSOM) is trained.
+Nodes of the trained map with a high quantization error are expanded
+into child maps, trained only on the rows mapped to them, recursively.
+Parameters are taken from the 'hierarchy' entry of the training
+section in the SOM's YAML file. Child maps are written as nested
+'children' entries of the SOM's YAML output.
+
+With --continue, training starts from the weights of an already trained
+SOM file instead of initializing them. The normalizers of the trained SOM
+are kept, rather than being fitted to the new data. Use this with a small
+learning rate and radius to fine-tune an existing SOM on new data:
+
+  som train trained.yml new-data.csv --continue -a "linear 0.05 0.01" -r "linear 2 0.7" > tuned.yml
+
+With --checkpoint, the SOM and the training state are written to a checkpoint
+file periodically, every --checkpoint-epochs epochs and/or every
+--checkpoint-minutes minutes. To resume an interrupted run, repeat the
+original command with --resume. Results are identical to an uninterrupted run:
+
+  som train som.yml data.csv --checkpoint ckpt.yml --checkpoint-epochs 100 > trained.yml
+  som train som.yml data.csv --checkpoint ckpt.yml --checkpoint-epochs 100 --resume ckpt.yml > trained.yml
+
+With --balance, classes of a categorical column are balanced during training,
+so that frequent classes do not dominate the map. Mode 'balanced' draws each
+epoch's samples with equal numbers per class, while mode 'inverse' weights rows
+by the inverse frequency of their class. The column can be a categorical layer
+of the SOM, or any other column of the data. The share of each class among the
+presented rows is written to the progress file:
+
+  som train som.yml data.csv --balance balanced --balance-column species > trained.yml
+
+For detecting overfitting, validation data can be held out from training
+with --validation-fraction, or read from a separate --validation-file.
+The validation quantization error (MSE) and topographic error are evaluated
+every --validation-interval epochs and written to the progress file:
+
+  som train som.yml data.csv --validation-fraction 0.2 --validation-interval 10 -p progress.csv > trained.yml
+
+Validation data is also required for stopping on a validation plateau
+(see 'training.stopping.patience' in the SOM's YAML file).
+
+Training can be interrupted with Ctrl+C. Training then stops after the
+current epoch, and the partially trained SOM is written to STDOUT.`,
 		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if cpuProfile {
@@ -79,28 +151,120 @@ but can also be set or overwritten using the provided CLI flags.`,
 				return fmt.Errorf("delimiter must be a single character")
 			}
 
-			tables, err := prepareTables(config, dataFile, del[0], noData)
+			tables, err := prepareTables(config, dataFile, del[0], noData, !continueTraining)
 			if err != nil {
 				return err
 			}
 
 			err = overwriteSomParameters(command, config,
-				size, neighborhood, metric, viSomMetric)
+				size, topology, wrap, neighborhood, metric, viSomMetric)
 			if err != nil {
 				return err
 			}
 			err = overwriteTrainingParameters(command, trainingConfig,
-				epochs, visomLambda, alpha, radius, decayFunc)
+				algorithm, initialization, epochs, visomLambda, alpha, radius, decayFunc, weightColumn,
+				balance, balanceColumn, validationFraction, validationInterval)
 			if err != nil {
 				return err
 			}
 
-			s, err := runTraining(config, trainingConfig, tables, seed, progressFile, progressInterval, del[0])
+			data := trainingData{Tables: tables}
+			if validationFile != "" {
+				if trainingConfig.ValidationFraction > 0 {
+					return fmt.Errorf("validation fraction can't be used together with a validation file")
+				}
+				data.Validation, err = prepareValidationTables(config, validationFile, del[0], noData)
+				if err != nil {
+					return err
+				}
+			}
+
+			if trainingConfig.WeightColumn != "" {
+				reader, err := csv.NewFileReader(dataFile, del[0], noData)
+				if err != nil {
+					return err
+				}
+				data.Weights, err = readWeights(reader, trainingConfig.WeightColumn)
+				if err != nil {
+					return err
+				}
+			}
+
+			if b := trainingConfig.Balance; b != nil && b.Mode != som.NoBalance {
+				reader, err := csv.NewFileReader(dataFile, del[0], noData)
+				if err != nil {
+					return err
+				}
+				data.Classes, err = readClasses(reader, config, tables, b.Column, noData)
+				if err != nil {
+					return err
+				}
+			}
+
+			if trainingConfig.ValidationFraction > 0 {
+				data, err = data.split(trainingConfig.ValidationFraction, rand.New(rand.NewSource(seed)))
+				if err != nil {
+					return err
+				}
+			}
+
+			if continueTraining {
+				for _, l := range config.Layers {
+					if len(l.Weights) == 0 {
+						return fmt.Errorf("continued training requires a trained SOM file, but layer %s has no weights", l.Name)
+					}
+				}
+				trainingConfig.Continue = true
+			}
+
+			if trainingConfig.Stopping != nil && trainingConfig.Stopping.Patience > 0 && data.Validation == nil {
+				return fmt.Errorf("stopping on validation plateau requires validation data, see --validation-fraction and --validation-file")
+			}
+
+			checkpoints := checkpointOptions{
+				File:     checkpointFile,
+				Epochs:   checkpointEpochs,
+				Interval: time.Duration(checkpointMinutes * float64(time.Minute)),
+				Source:   som.NewCountingSource(seed),
+			}
+			if checkpointFile != "" && checkpoints.Epochs <= 0 && checkpoints.Interval <= 0 {
+				return fmt.Errorf("checkpoints require a positive --checkpoint-epochs or --checkpoint-minutes")
+			}
+			if resumeFile != "" {
+				checkpoints.Resume, err = applyCheckpoint(resumeFile, config, trainingConfig)
+				if err != nil {
+					return err
+				}
+				checkpoints.Source = som.NewCountingSourceAt(checkpoints.Resume.Seed, checkpoints.Resume.Draws)
+			}
+
+			rng := rand.New(checkpoints.Source)
+			s, interrupted, err := runTraining(config, trainingConfig, &data, rng, &checkpoints, progressFile, progressInterval, del[0], threads)
 			if err != nil {
 				return err
 			}
+			if interrupted && hierarchical {
+				fmt.Fprintln(os.Stderr, "Warning: skipping hierarchical expansion of the interrupted SOM")
+			}
 
-			outYaml, err := yml.ToYAML(s)
+			var outYaml []byte
+			if hierarchical && !interrupted {
+				hParams := trainingConfig.Hierarchy
+				if hParams == nil {
+					hParams = defaultHierarchyConfig()
+				}
+				h := som.NewHierarchy(s)
+				h.SetWeights(data.Weights)
+				if data.Classes != nil {
+					h.SetClasses(data.Classes.Names, data.Classes.Indices)
+				}
+				if err := h.Expand(config, trainingConfig, hParams, data.Tables, rng); err != nil {
+					return err
+				}
+				outYaml, err = yml.HierarchyToYAML(h)
+			} else {
+				outYaml, err = yml.ToYAML(s)
+			}
 			if err != nil {
 				return err
 			}
@@ -110,6 +274,8 @@ but can also be set or overwritten using the provided CLI flags.`,
 		},
 	}
 
+	command.Flags().StringVarP(&algorithm, "algorithm", "A", "", "Overwrites the training algorithm of the SOM file.\nOptions: online, batch")
+	command.Flags().StringVarP(&initialization, "init", "i", "", "Overwrites the weight initialization of the SOM file.\nOptions: random, pca, samples, data-range")
 	command.Flags().IntVarP(&epochs, "epochs", "e", 1000, "Overwrites the number of epochs of the SOM file")
 	command.Flags().Int64VarP(&seed, "seed", "s", 42, "Random seed")
 
@@ -125,6 +291,10 @@ Options:
 	command.Flags().Float64VarP(&visomLambda, "vi-lambda", "v", 0.0, "Overwrites ViSOM resolution. 0 = no ViSOM")
 
 	command.Flags().IntSliceVarP(&size, "size", "z", []int{}, "Overwrites SOM size (columns,rows)")
+	command.Flags().StringVarP(&topology, "topology", "t", "", `Overwrites SOM topology.
+Options: rectangular, hexagonal`)
+	command.Flags().StringVarP(&wrap, "wrap", "w", "", `Overwrites SOM boundary conditions.
+Options: none, torus, cylinder-x, cylinder-y`)
 	command.Flags().StringVarP(&neighborhood, "neighborhood", "n", "", `Overwrites SOM neighborhood function.
 Options: gaussian, cutgaussian, linear, box`)
 	command.Flags().StringVarP(&metric, "metric", "m", "", `Overwrites SOM map distance metric.
@@ -132,33 +302,97 @@ Options: euclidean, manhattan, chebyshev`)
 	command.Flags().StringVarP(&viSomMetric, "vi-metric", "V", "", `Overwrites ViSOM map distance metric.
 Options: euclidean, manhattan, chebyshev`)
 
+	command.Flags().StringVarP(&weightColumn, "weight-column", "W", "", "Overwrites the column with row weights of the SOM file")
+	command.Flags().StringVar(&balance, "balance", "", "Overwrites the class balancing mode of the SOM file.\nOptions: none, balanced, inverse")
+	command.Flags().StringVar(&balanceColumn, "balance-column", "", "Overwrites the categorical column for class balancing of the SOM file")
+
+	command.Flags().StringVar(&validationFile, "validation-file", "", "CSV file with validation data")
+	command.Flags().Float64Var(&validationFraction, "validation-fraction", 0, "Overwrites the fraction of rows held out for validation of the SOM file")
+	command.Flags().IntVar(&validationInterval, "validation-interval", 1, "Overwrites the interval for evaluating validation data of the SOM file, in epochs")
+
+	command.Flags().IntVarP(&threads, "threads", "T", 0, "Number of threads for BMU search in batch training (default number of CPUs)")
+
 	command.Flags().StringVarP(&delim, "delimiter", "D", ",", "CSV delimiter")
 	command.Flags().StringVarP(&noData, "no-data", "N", "", "No data string")
 
 	command.Flags().IntVarP(&progressInterval, "progress", "P", 100, "Interval for progress output.\nIgnored if no <progress-file> is given")
 	command.Flags().StringVarP(&progressFile, "progress-file", "p", "", "CSV file for training progress output")
 
+	command.Flags().BoolVarP(&hierarchical, "hierarchical", "H", false, "Train a Growing Hierarchical SOM (GHSOM)")
+	command.Flags().BoolVarP(&continueTraining, "continue", "c", false, "Continue training from the weights of a trained SOM file")
+
+	command.Flags().StringVar(&checkpointFile, "checkpoint", "", "File for writing checkpoints during training")
+	command.Flags().IntVar(&checkpointEpochs, "checkpoint-epochs", 0, "Interval for writing checkpoints, in epochs")
+	command.Flags().Float64Var(&checkpointMinutes, "checkpoint-minutes", 0, "Interval for writing checkpoints, in minutes")
+	command.Flags().StringVar(&resumeFile, "resume", "", "Checkpoint file to resume training from")
+
 	command.Flags().BoolVar(&cpuProfile, "profile", false, "Enable CPU profiling")
 
 	command.Flags().SortFlags = false
 	command.MarkFlagFilename("progress-file", "csv")
+	command.MarkFlagFilename("validation-file", "csv")
 
 	return command
 }
 
 func overwriteTrainingParameters(command *cobra.Command, conf *som.TrainingConfig,
-	epochs int, visomLambda float64, alpha, radius, decayFunc string) error {
+	algorithm, initialization string, epochs int, visomLambda float64, alpha, radius, decayFunc, weightColumn string,
+	balance, balanceColumn string, validationFraction float64, validationInterval int) error {
 	flagUsed := map[string]bool{}
 	command.Flags().Visit(func(f *pflag.Flag) {
 		flagUsed[f.Name] = true
 	})
 
+	if _, ok := flagUsed["algorithm"]; ok {
+		conf.Algorithm, ok = som.GetAlgorithm(algorithm)
+		if !ok {
+			return fmt.Errorf("unknown training algorithm: %s", algorithm)
+		}
+	}
+	if _, ok := flagUsed["init"]; ok {
+		conf.Init, ok = som.GetInitialization(initialization)
+		if !ok {
+			return fmt.Errorf("unknown initialization: %s", initialization)
+		}
+	}
 	if _, ok := flagUsed["epochs"]; ok {
 		conf.Epochs = epochs
 	}
 	if _, ok := flagUsed["vi-lambda"]; ok {
 		conf.ViSomLambda = visomLambda
 	}
+	if _, ok := flagUsed["weight-column"]; ok {
+		conf.WeightColumn = weightColumn
+	}
+	if _, ok := flagUsed["validation-fraction"]; ok {
+		if validationFraction < 0 || validationFraction >= 1 {
+			return fmt.Errorf("validation fraction must be in range [0, 1), got %f", validationFraction)
+		}
+		conf.ValidationFraction = validationFraction
+	}
+	if _, ok := flagUsed["validation-interval"]; ok {
+		conf.ValidationInterval = validationInterval
+	}
+	_, balanceUsed := flagUsed["balance"]
+	_, balanceColumnUsed := flagUsed["balance-column"]
+	if balanceUsed || balanceColumnUsed {
+		if conf.Balance == nil {
+			conf.Balance = &som.BalanceConfig{Mode: som.Balanced}
+		}
+		if balanceUsed {
+			var ok bool
+			conf.Balance.Mode, ok = som.GetBalance(balance)
+			if !ok {
+				return fmt.Errorf("unknown class balancing mode: %s", balance)
+			}
+		}
+		if balanceColumnUsed {
+			conf.Balance.Column = balanceColumn
+		}
+		if conf.Balance.Mode != som.NoBalance && conf.Balance.Column == "" {
+			return fmt.Errorf("class balancing requires a column, see --balance-column")
+		}
+	}
 
 	var err error
 	if _, ok := flagUsed["alpha"]; ok {
@@ -187,7 +421,7 @@ func overwriteTrainingParameters(command *cobra.Command, conf *som.TrainingConfi
 }
 
 func overwriteSomParameters(command *cobra.Command, conf *som.SomConfig,
-	size []int, neigh, metric, viSomMetric string) error {
+	size []int, topology, wrap, neigh, metric, viSomMetric string) error {
 	flagUsed := map[string]bool{}
 	command.Flags().Visit(func(f *pflag.Flag) {
 		flagUsed[f.Name] = true
@@ -199,6 +433,18 @@ func overwriteSomParameters(command *cobra.Command, conf *som.SomConfig,
 		}
 		conf.Size.Width, conf.Size.Height = size[0], size[1]
 	}
+	if _, ok := flagUsed["topology"]; ok {
+		conf.Topology, ok = neighborhood.GetTopology(topology)
+		if !ok {
+			return fmt.Errorf("unknown topology: %s", topology)
+		}
+	}
+	if _, ok := flagUsed["wrap"]; ok {
+		conf.Wrap, ok = neighborhood.GetWrap(wrap)
+		if !ok {
+			return fmt.Errorf("unknown wrap: %s", wrap)
+		}
+	}
 	if _, ok := flagUsed["neighborhood"]; ok {
 		conf.Neighborhood, ok = neighborhood.GetNeighborhood(neigh)
 		if !ok {
@@ -221,18 +467,64 @@ func overwriteSomParameters(command *cobra.Command, conf *som.SomConfig,
 	return nil
 }
 
+type checkpointOptions struct {
+	File     string
+	Epochs   int
+	Interval time.Duration
+	Resume   *som.Checkpoint
+	Source   *som.CountingSource
+}
+
 func runTraining(config *som.SomConfig, trainingConfig *som.TrainingConfig,
-	tables []*table.Table, seed int64,
-	progressFile string, writeInterval int, csvDelim rune,
-) (*som.Som, error) {
+	data *trainingData, rng *rand.Rand, checkpoints *checkpointOptions,
+	progressFile string, writeInterval int, csvDelim rune, threads int,
+) (*som.Som, bool, error) {
 
 	s, err := som.New(config)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	trainer, err := som.NewTrainer(s, tables, trainingConfig, rand.New(rand.NewSource(seed)))
+	trainer, err := som.NewTrainer(s, data.Tables, trainingConfig, rng)
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+	trainer.SetThreads(threads)
+	if data.Weights != nil {
+		if err := trainer.SetWeights(data.Weights); err != nil {
+			return nil, false, err
+		}
+	}
+	if data.Classes != nil {
+		if err := trainer.SetClasses(data.Classes.Names, data.Classes.Indices); err != nil {
+			return nil, false, err
+		}
+		fmt.Fprintf(os.Stderr, "Class balancing (%s) by column %s: %d classes\n",
+			trainingConfig.Balance.Mode, trainingConfig.Balance.Column, len(data.Classes.Names))
+	}
+	if data.Validation != nil {
+		if err := trainer.SetValidation(data.Validation); err != nil {
+			return nil, false, err
+		}
+	}
+
+	first := 0
+	if checkpoints.Resume != nil {
+		first = checkpoints.Resume.Epoch
+		trainer.Resume(first)
+	}
+	if checkpoints.File != "" {
+		trainer.SetCheckpoints(checkpoints.Epochs, checkpoints.Interval, func(epoch int) {
+			seed, draws := checkpoints.Source.State()
+			err := writeCheckpoint(checkpoints.File, s, &som.Checkpoint{
+				Epoch:  epoch,
+				Epochs: trainingConfig.Epochs,
+				Seed:   seed,
+				Draws:  draws,
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "\nWarning: failed to write checkpoint: %s\n", err)
+			}
+		})
 	}
 
 	var writer io.Writer
@@ -241,24 +533,95 @@ func runTraining(config *som.SomConfig, trainingConfig *som.TrainingConfig,
 	} else {
 		file, err := os.Create(progressFile)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 		defer file.Close()
 		writer = file
 	}
-	tracker := newProgressTracker(trainingConfig.Epochs, tables[0].Rows(), writer, writeInterval, csvDelim)
+	tracker := newProgressTracker(trainingConfig.Epochs, data.Tables[0].Rows(), writer, writeInterval, csvDelim)
+
+	// On SIGINT, training stops after the current epoch.
+	// A second SIGINT terminates the program immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
 
 	progress := make(chan som.TrainingProgress, 100)
-	go trainer.Train(progress)
+	go trainer.TrainContext(ctx, progress)
 
-	epoch := 0
+	epoch, count := first, 0
+	var last som.TrainingProgress
 	for p := range progress {
-		tracker.Update(epoch, &p)
-		epoch++
+		tracker.Update(p.Epoch, &p)
+		epoch = p.Epoch + 1
+		count++
+		last = p
 	}
 	tracker.Finish()
 
-	return s, nil
+	if last.Validation && !math.IsNaN(last.ValidationError) {
+		fmt.Fprintf(os.Stderr, "Validation error: %f (MSE), topographic error: %f\n", last.ValidationError, last.ValidationTopoError)
+	}
+
+	interrupted := epoch < trainingConfig.Epochs
+	if interrupted {
+		fmt.Fprintf(os.Stderr, "Warning: training interrupted after %d of %d epochs\n", epoch, trainingConfig.Epochs)
+	} else if count < trainingConfig.Epochs-first {
+		fmt.Fprintf(os.Stderr, "Training stopped early after %d of %d epochs\n", first+count, trainingConfig.Epochs)
+	}
+
+	return s, interrupted, nil
+}
+
+// applyCheckpoint reads a checkpoint file and applies its map size and weights to the SOM configuration.
+// Returns the checkpoint's training state.
+func applyCheckpoint(path string, config *som.SomConfig, trainingConfig *som.TrainingConfig) (*som.Checkpoint, error) {
+	ckYaml, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ckConfig, checkpoint, err := yml.ToCheckpoint(ckYaml)
+	if err != nil {
+		return nil, err
+	}
+
+	if checkpoint.Epochs != trainingConfig.Epochs {
+		return nil, fmt.Errorf("checkpoint was created for %d epochs, but training is configured for %d epochs",
+			checkpoint.Epochs, trainingConfig.Epochs)
+	}
+	if len(ckConfig.Layers) != len(config.Layers) {
+		return nil, fmt.Errorf("number of layers in checkpoint (%d) does not match the SOM (%d)",
+			len(ckConfig.Layers), len(config.Layers))
+	}
+	for i, l := range config.Layers {
+		ck := ckConfig.Layers[i]
+		if ck.Name != l.Name || !slices.Equal(ck.Columns, l.Columns) {
+			return nil, fmt.Errorf("layer %s in checkpoint does not match layer %s of the SOM", ck.Name, l.Name)
+		}
+	}
+
+	config.Size = ckConfig.Size
+	for i, l := range config.Layers {
+		l.Weights = ckConfig.Layers[i].Weights
+	}
+
+	return checkpoint, nil
+}
+
+// writeCheckpoint writes a checkpoint file, replacing any previous checkpoint only after writing succeeded.
+func writeCheckpoint(path string, s *som.Som, checkpoint *som.Checkpoint) error {
+	ckYaml, err := yml.CheckpointToYAML(s, checkpoint)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, ckYaml, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
 }
 
 func defaultTrainingConfig() *som.TrainingConfig {
@@ -270,15 +633,124 @@ func defaultTrainingConfig() *som.TrainingConfig {
 	}
 }
 
-func prepareTables(config *som.SomConfig, path string, delim rune, noData string) ([]*table.Table, error) {
+func defaultHierarchyConfig() *som.HierarchyConfig {
+	return &som.HierarchyConfig{
+		Size:     layer.Size{Width: 3, Height: 3},
+		Tau:      0.1,
+		MaxDepth: 3,
+	}
+}
+
+// prepareTables reads the training data. With updateNormalizers, normalizers and covariance matrices
+// are fitted to the data. Otherwise, those of the SOM's YAML file are used, e.g. for continued training.
+func prepareTables(config *som.SomConfig, path string, delim rune, noData string, updateNormalizers bool) ([]*table.Table, error) {
 	reader, err := csv.NewFileReader(path, delim, noData)
 	if err != nil {
 		return nil, err
 	}
-	tables, _, err := config.PrepareTables(reader, nil, true, false)
+	tables, _, err := config.PrepareTables(reader, nil, updateNormalizers, false)
 	return tables, err
 }
 
+// prepareValidationTables reads validation data, normalized using the normalizers of the training data.
+// Must be called after the training data was prepared.
+func prepareValidationTables(config *som.SomConfig, path string, delim rune, noData string) ([]*table.Table, error) {
+	reader, err := csv.NewFileReader(path, delim, noData)
+	if err != nil {
+		return nil, err
+	}
+	tables, _, err := config.PrepareTables(reader, nil, false, false)
+	return tables, err
+}
+
+// trainingData holds the prepared training data, with optional validation data, row weights and classes.
+type trainingData struct {
+	Tables     []*table.Table
+	Validation []*table.Table
+	Weights    []float64
+	Classes    *classLabels
+}
+
+// split randomly holds out the given fraction of rows as validation data.
+func (d *trainingData) split(fraction float64, rng *rand.Rand) (trainingData, error) {
+	rows := d.Tables[0].Rows()
+	numValid := int(math.Round(fraction * float64(rows)))
+	if numValid == 0 || numValid == rows {
+		return trainingData{}, fmt.Errorf("validation fraction %f results in %d of %d rows for validation", fraction, numValid, rows)
+	}
+	perm := rng.Perm(rows)
+	validRows, trainRows := perm[:numValid], perm[numValid:]
+	slices.Sort(validRows)
+	slices.Sort(trainRows)
+
+	result := trainingData{
+		Tables:     make([]*table.Table, len(d.Tables)),
+		Validation: make([]*table.Table, len(d.Tables)),
+	}
+	for i, tab := range d.Tables {
+		result.Tables[i] = tab.SelectRows(trainRows)
+		result.Validation[i] = tab.SelectRows(validRows)
+	}
+	if d.Weights != nil {
+		result.Weights = make([]float64, len(trainRows))
+		for i, row := range trainRows {
+			result.Weights[i] = d.Weights[row]
+		}
+	}
+	if d.Classes != nil {
+		result.Classes = &classLabels{
+			Names:   d.Classes.Names,
+			Indices: make([]int, len(trainRows)),
+		}
+		for i, row := range trainRows {
+			result.Classes.Indices[i] = d.Classes.Indices[row]
+		}
+	}
+	return result, nil
+}
+
+// readWeights reads row weights from the given column.
+func readWeights(reader table.Reader, column string) ([]float64, error) {
+	tab, err := reader.ReadColumns([]string{column})
+	if err != nil {
+		return nil, err
+	}
+	return tab.Data(), nil
+}
+
+// classLabels holds class names and the class index of each data row, with -1 for missing classes.
+type classLabels struct {
+	Names   []string
+	Indices []int
+}
+
+// readClasses reads the class of each data row from the given column.
+// If the column is a categorical layer of the SOM, classes are derived from its prepared table.
+// Otherwise, class labels are read from the data.
+func readClasses(reader table.Reader, config *som.SomConfig, tables []*table.Table, column string, noData string) (*classLabels, error) {
+	for i, l := range config.Layers {
+		if l.Name == column && l.Categorical && tables[i] != nil {
+			names, indices := conv.TableToClasses(tables[i])
+			return &classLabels{Names: names, Indices: indices}, nil
+		}
+	}
+	labels, err := reader.ReadLabels(column)
+	if err != nil {
+		return nil, err
+	}
+	names, indices := conv.ClassesToIndices(labels, noData)
+	return &classLabels{Names: names, Indices: indices}, nil
+}
+
+// setPredictorWeights reads row weights from the given column and sets them on the predictor.
+func setPredictorWeights(p *som.Predictor, reader table.Reader, column string) error {
+	weights, err := readWeights(reader, column)
+	if err != nil {
+		return err
+	}
+	return p.SetWeights(weights)
+}
+
 func readConfig(path string) (*som.SomConfig, *som.TrainingConfig, error) {
 	somYaml, err := os.ReadFile(path)
 	if err != nil {
@@ -300,10 +772,12 @@ type progressTracker struct {
 	start         time.Time
 	update        time.Time
 	epochs        int
+	count         int
 	samples       int
 	writer        io.Writer
 	writeInterval int
 	csvDelim      rune
+	header        bool
 	bar           []rune
 }
 
@@ -321,9 +795,11 @@ func newProgressTracker(epochs int, samples int, writer io.Writer, writeInterval
 }
 
 func (t *progressTracker) Update(epoch int, progress *som.TrainingProgress) {
+	t.count++
 	if t.writeInterval > 0 && epoch%t.writeInterval == 0 {
-		if epoch == 0 {
+		if !t.header {
 			fmt.Fprintln(t.writer, progress.CsvHeader(t.csvDelim))
+			t.header = true
 		}
 		fmt.Fprintln(t.writer, progress.CsvRow(t.csvDelim))
 	}
@@ -332,7 +808,7 @@ func (t *progressTracker) Update(epoch int, progress *som.TrainingProgress) {
 		return
 	}
 
-	s := t.samples * (epoch + 1)
+	s := t.samples * t.count
 	barWidth := ((epoch + 1) * progressBarWidth) / t.epochs
 
 	for i := range t.bar {
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSomYaml = `som:
+  size: [3, 2]
+  neighborhood: gaussian
+  metric: manhattan
+  layers:
+    - name: xy
+      columns: [x, y]
+      norm: [gaussian]
+      metric: mahalanobis
+training:
+  epochs: 10
+  alpha: polynomial 0.25 0.01 2
+  radius: polynomial 2 0.7 2
+`
+
+func TestTrainContinue(t *testing.T) {
+	dir := t.TempDir()
+	somFile := writeTestFile(t, dir, "som.yml", testSomYaml)
+
+	all := "x,y\n"
+	subset := "x,y\n"
+	for i := 0; i < 20; i++ {
+		row := fmt.Sprintf("%d,%d\n", i, (i*7)%11)
+		all += row
+		if i < 5 {
+			subset += row
+		}
+	}
+	allFile := writeTestFile(t, dir, "all.csv", all)
+	subsetFile := writeTestFile(t, dir, "subset.csv", subset)
+
+	trained, err := runCommand(t, "train", somFile, allFile, "--epochs", "10")
+	assert.NoError(t, err)
+	assert.Contains(t, trained, "metric: mahalanobis ")
+	trainedFile := writeTestFile(t, dir, "trained.yml", trained)
+
+	continued, err := runCommand(t, "train", trainedFile, subsetFile, "--continue", "--epochs", "1", "--alpha", "constant 0")
+	assert.NoError(t, err)
+	assert.Equal(t, trained, continued)
+}
+
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+// runCommand runs the CLI with the given arguments and returns what was written to STDOUT.
+func runCommand(t *testing.T, args ...string) (string, error) {
+	root, err := RootCommand()
+	assert.NoError(t, err)
+	root.SetArgs(args)
+
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	assert.NoError(t, err)
+	defer devNull.Close()
+
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, devNull
+
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+
+	err = root.Execute()
+	w.Close()
+	os.Stdout, os.Stderr = stdout, stderr
+
+	return strings.TrimSpace(<-out), err
+}
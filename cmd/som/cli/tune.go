@@ -0,0 +1,262 @@
+package cli
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mlange-42/som"
+	"github.com/mlange-42/som/csv"
+	"github.com/mlange-42/som/table"
+	"github.com/mlange-42/som/yml"
+	"github.com/spf13/cobra"
+)
+
+func tuneCommand() *cobra.Command {
+	var seed int64
+	var threads int
+	var resultsFile string
+
+	var delim string
+	var noData string
+
+	command := &cobra.Command{
+		Use:   "tune [flags] <som-file> <data-file> <tune-file>",
+		Short: "Searches for the best SOM parameters using cross-validation.",
+		Long: `Searches for the best SOM parameters using cross-validation.
+
+Candidate configurations are generated from the SOM file and the parameter
+search space in the tune file. Each candidate is scored by k-fold
+cross-validation. All candidates use the same folds. Normalizers and the
+covariance matrices of mahalanobis metrics are fitted to the training rows of
+each fold, so that held-out rows are not used. Row weights, class balancing
+and a validation fraction in the training configuration are not supported.
+
+Scores are the quantization error (qe, MSE) or the topographic error (te) of
+the held-out rows, or the accuracy of predicting a categorical target layer
+(accuracy). For accuracy, the target layer is not used for BMU search.
+
+Candidates ranked by their mean score are written to the results CSV file.
+The best candidate is trained on all data, and written to STDOUT in YAML format:
+
+  som tune som.yml data.csv tune.yml --results-file results.csv > best.yml
+
+Tune file format:
+
+  search: grid                  # Search strategy, grid or random. Default grid
+  samples: 20                   # Number of candidates for random search
+  folds: 5                      # Number of cross-validation folds
+  score: accuracy               # Score, qe, te or accuracy. Default qe
+  target: species               # Categorical target layer for accuracy
+  parameters:                   # Parameters to tune. Optional, each parameter is optional
+    size: [[8, 6], [12, 8]]     #   Map sizes
+    epochs: [500, 1000]         #   Numbers of training epochs
+    alpha:                      #   Learning rate functions
+      - polynomial 0.25 0.01 2
+      - linear 0.5 0.01
+    radius:                     #   Neighborhood radius functions
+      - polynomial 10 0.7 2
+    lambda: {min: 0, max: 0.5}  #   ViSOM resolution, list or range (random search only)
+    layer-weights:              #   Layer weights, list or range (random search only)
+      species: [0.25, 0.5, 1]
+
+Candidates are evaluated in parallel, see --threads.`,
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			somFile := args[0]
+			dataFile := args[1]
+			tuneFile := args[2]
+
+			config, trainingConfig, err := readConfig(somFile)
+			if err != nil {
+				return err
+			}
+
+			del := []rune(delim)
+			if len(delim) != 1 {
+				return fmt.Errorf("delimiter must be a single character")
+			}
+
+			reader, err := csv.NewFileReader(dataFile, del[0], noData)
+			if err != nil {
+				return err
+			}
+			tables, raw, err := config.PrepareTables(reader, nil, true, true)
+			if err != nil {
+				return err
+			}
+
+			tuneYaml, err := os.ReadFile(tuneFile)
+			if err != nil {
+				return err
+			}
+			tuning, err := yml.ToTuning(tuneYaml)
+			if err != nil {
+				return err
+			}
+
+			candidates, err := tuning.Candidates(config, trainingConfig, rand.New(rand.NewSource(seed)))
+			if err != nil {
+				return err
+			}
+
+			results, err := evaluateCandidates(candidates, raw, &tuning.CrossValidation, seed, threads)
+			if err != nil {
+				return err
+			}
+			rankResults(results, tuning.CrossValidation.Score)
+
+			if err := writeTuningResults(resultsFile, tuning, results, del[0]); err != nil {
+				return err
+			}
+
+			best := results[0].Candidate
+			fmt.Fprintf(os.Stderr, "Best candidate: %s = %f\n", tuning.CrossValidation.Score, results[0].Mean)
+
+			s, err := som.New(best.Config)
+			if err != nil {
+				return err
+			}
+			trainer, err := som.NewTrainer(s, tables, best.Training, rand.New(rand.NewSource(seed)))
+			if err != nil {
+				return err
+			}
+			progress := make(chan som.TrainingProgress)
+			go trainer.Train(progress)
+			for range progress {
+			}
+
+			outYaml, err := yml.ToYAML(s)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(outYaml))
+
+			return nil
+		},
+	}
+
+	command.Flags().StringVarP(&resultsFile, "results-file", "r", "tuning.csv", "CSV file for the ranked results")
+	command.Flags().Int64VarP(&seed, "seed", "s", 42, "Random seed")
+	command.Flags().IntVarP(&threads, "threads", "T", 0, "Number of candidates to evaluate in parallel (default number of CPUs)")
+
+	command.Flags().StringVarP(&delim, "delimiter", "D", ",", "CSV delimiter for CSV input and output")
+	command.Flags().StringVarP(&noData, "no-data", "N", "", "No-data string for CSV input")
+
+	command.Flags().SortFlags = false
+	command.MarkFlagFilename("results-file", "csv")
+
+	return command
+}
+
+// tuningResult is the cross-validation result of a candidate configuration.
+type tuningResult struct {
+	Candidate som.Candidate
+	Mean      float64
+	StdDev    float64
+}
+
+// evaluateCandidates runs cross-validation for all candidates, in parallel.
+// All candidates use the same seed, so that they are evaluated on the same folds.
+// Tables are raw, as normalizers are fitted per fold.
+func evaluateCandidates(candidates []som.Candidate, tables []*table.Table, cv *som.CrossValidation, seed int64, threads int) ([]tuningResult, error) {
+	if threads < 1 {
+		threads = runtime.NumCPU()
+	}
+
+	results := make([]tuningResult, len(candidates))
+	errs := make([]error, len(candidates))
+	jobs := make(chan int)
+	done := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				c := candidates[idx]
+				mean, std, err := som.CrossValidate(c.Config, c.Training, tables, cv, rand.New(rand.NewSource(seed)))
+				results[idx] = tuningResult{Candidate: c, Mean: mean, StdDev: std}
+				errs[idx] = err
+				done <- idx
+			}
+		}()
+	}
+	go func() {
+		for i := range candidates {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(done)
+	}()
+
+	count := 0
+	for range done {
+		count++
+		fmt.Fprintf(os.Stderr, "\rEvaluated %d of %d candidates", count, len(candidates))
+	}
+	fmt.Fprintln(os.Stderr)
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("candidate %s: %s", strings.Join(candidates[i].Values, ", "), err.Error())
+		}
+	}
+	return results, nil
+}
+
+// rankResults sorts results from the best to the worst mean score. The sort is stable.
+// Results with a NaN score are ranked last.
+func rankResults(results []tuningResult, score som.Score) {
+	slices.SortStableFunc(results, func(a, b tuningResult) int {
+		if nanA, nanB := math.IsNaN(a.Mean), math.IsNaN(b.Mean); nanA || nanB {
+			if nanA == nanB {
+				return 0
+			}
+			if nanA {
+				return 1
+			}
+			return -1
+		}
+		if score.HigherIsBetter() {
+			a, b = b, a
+		}
+		if a.Mean < b.Mean {
+			return -1
+		}
+		if a.Mean > b.Mean {
+			return 1
+		}
+		return 0
+	})
+}
+
+// writeTuningResults writes the ranked results to a CSV file.
+func writeTuningResults(path string, tuning *som.Tuning, results []tuningResult, delim rune) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	header := append([]string{"Rank"}, tuning.Parameters()...)
+	header = append(header, tuning.CrossValidation.Score.String(), "StdDev")
+	fmt.Fprintln(file, strings.Join(header, string(delim)))
+
+	for i, r := range results {
+		row := append([]string{strconv.Itoa(i + 1)}, r.Candidate.Values...)
+		row = append(row,
+			strconv.FormatFloat(r.Mean, 'f', -1, 64),
+			strconv.FormatFloat(r.StdDev, 'f', -1, 64))
+		fmt.Fprintln(file, strings.Join(row, string(delim)))
+	}
+	return nil
+}
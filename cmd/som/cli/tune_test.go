@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mlange-42/som"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRankResults(t *testing.T) {
+	means := func(results []tuningResult) []float64 {
+		m := make([]float64, len(results))
+		for i, r := range results {
+			m[i] = r.Mean
+		}
+		return m
+	}
+	nan := math.NaN()
+
+	results := []tuningResult{{Mean: 0.5}, {Mean: nan}, {Mean: 0.9}, {Mean: 0.1}, {Mean: nan}}
+	rankResults(results, som.ScoreAccuracy)
+	best := means(results)
+	assert.Equal(t, []float64{0.9, 0.5, 0.1}, best[:3])
+	assert.True(t, math.IsNaN(best[3]))
+	assert.True(t, math.IsNaN(best[4]))
+
+	results = []tuningResult{{Mean: nan}, {Mean: 0.5}, {Mean: 0.1}}
+	rankResults(results, som.ScoreQuantization)
+	best = means(results)
+	assert.Equal(t, []float64{0.1, 0.5}, best[:2])
+	assert.True(t, math.IsNaN(best[2]))
+}
@@ -0,0 +1,241 @@
+package som
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+
+	"github.com/mlange-42/som/conv"
+	"github.com/mlange-42/som/distance"
+	"github.com/mlange-42/som/norm"
+	"github.com/mlange-42/som/table"
+)
+
+// Score is the metric used to score a SOM configuration in cross-validation.
+type Score uint8
+
+const (
+	ScoreQuantization Score = iota // Quantization error (MSE) of the test data. Lower is better
+	ScoreTopographic               // Topographic error of the test data. Lower is better
+	ScoreAccuracy                  // Prediction accuracy of a categorical target layer for the test data. Higher is better
+)
+
+var scores = map[string]Score{
+	"qe":       ScoreQuantization,
+	"te":       ScoreTopographic,
+	"accuracy": ScoreAccuracy,
+}
+
+// GetScore returns the score with the given name.
+// Options are qe, te and accuracy.
+func GetScore(name string) (Score, bool) {
+	s, ok := scores[name]
+	return s, ok
+}
+
+// String returns the name of the score.
+func (s Score) String() string {
+	for name, sc := range scores {
+		if sc == s {
+			return name
+		}
+	}
+	return "qe"
+}
+
+// HigherIsBetter returns whether higher values of the score indicate a better SOM.
+func (s Score) HigherIsBetter() bool {
+	return s == ScoreAccuracy
+}
+
+// CrossValidation holds the parameters for k-fold cross-validation of a SOM configuration.
+type CrossValidation struct {
+	Folds  int    // Number of folds
+	Score  Score  // Score to evaluate
+	Target string // Name of the categorical target layer. Required for ScoreAccuracy
+}
+
+// CrossValidate runs k-fold cross-validation of the given SOM and training configuration.
+// Rows are randomly partitioned into folds. For each fold, a new SOM is trained on the remaining rows,
+// and scored on the rows of the fold. Returns the mean and the standard deviation of the scores over folds.
+//
+// Tables are expected to be raw, i.e. not normalized, e.g. as the raw tables from [SomConfig.PrepareTables].
+// For each fold, copies of the normalizers of the configuration are fitted to the training rows only,
+// and used to normalize the training and test rows, so that test data does not leak into the normalization.
+// Likewise, the covariance matrices of [distance.Mahalanobis] metrics are estimated from the training rows only.
+// For ScoreAccuracy, the target layer is not used for finding the BMUs of the test data.
+// The SOM configuration is not modified, so that multiple cross-validations can run concurrently.
+//
+// Row weights, class balancing and the validation fraction of the training configuration are not supported,
+// and an error is returned if they are set.
+func CrossValidate(config *SomConfig, params *TrainingConfig, tables []*table.Table, cv *CrossValidation, rng *rand.Rand) (mean, stdDev float64, err error) {
+	rows := tables[0].Rows()
+	if cv.Folds < 2 {
+		return 0, 0, fmt.Errorf("cross-validation requires at least 2 folds, got %d", cv.Folds)
+	}
+	if cv.Folds > rows {
+		return 0, 0, fmt.Errorf("number of folds (%d) is larger than the number of data rows (%d)", cv.Folds, rows)
+	}
+	if params.WeightColumn != "" {
+		return 0, 0, fmt.Errorf("cross-validation does not support row weights (weight column %s)", params.WeightColumn)
+	}
+	if params.Balance != nil && params.Balance.Mode != NoBalance {
+		return 0, 0, fmt.Errorf("cross-validation does not support class balancing (balance column %s)", params.Balance.Column)
+	}
+	if params.ValidationFraction > 0 {
+		return 0, 0, fmt.Errorf("cross-validation does not support a validation fraction, as folds are held out instead")
+	}
+
+	target := -1
+	if cv.Score == ScoreAccuracy {
+		target = slices.IndexFunc(config.Layers, func(l *LayerDef) bool { return l.Name == cv.Target })
+		if target < 0 {
+			return 0, 0, fmt.Errorf("target layer %s not found", cv.Target)
+		}
+		if !config.Layers[target].Categorical {
+			return 0, 0, fmt.Errorf("accuracy requires a categorical target layer, but %s is not categorical", cv.Target)
+		}
+	}
+
+	perm := rng.Perm(rows)
+	seeds := make([]int64, cv.Folds)
+	for i := range seeds {
+		seeds[i] = rng.Int63()
+	}
+
+	foldScores := make([]float64, cv.Folds)
+	for fold := 0; fold < cv.Folds; fold++ {
+		trainRows, testRows := foldRows(perm, fold, cv.Folds)
+		trainTables := make([]*table.Table, len(tables))
+		testTables := make([]*table.Table, len(tables))
+		for i, tab := range tables {
+			trainTables[i] = tab.SelectRows(trainRows)
+			testTables[i] = tab.SelectRows(testRows)
+		}
+
+		foldConfig, err := normalizeFold(config, trainTables, testTables)
+		if err != nil {
+			return 0, 0, err
+		}
+		s, err := New(foldConfig)
+		if err != nil {
+			return 0, 0, err
+		}
+		trainer, err := NewTrainer(s, trainTables, params, rand.New(rand.NewSource(seeds[fold])))
+		if err != nil {
+			return 0, 0, err
+		}
+		progress := make(chan TrainingProgress)
+		go trainer.Train(progress)
+		for range progress {
+		}
+
+		foldScores[fold], err = score(s, testTables, cv.Score, target)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	for _, sc := range foldScores {
+		mean += sc
+	}
+	mean /= float64(cv.Folds)
+	for _, sc := range foldScores {
+		stdDev += (sc - mean) * (sc - mean)
+	}
+	stdDev = math.Sqrt(stdDev / float64(cv.Folds-1))
+
+	return mean, stdDev, nil
+}
+
+// normalizeFold returns a copy of the configuration with copies of its normalizers and metrics.
+// Normalizers, and the covariance matrices of Mahalanobis metrics, are fitted to the training tables.
+// Training and test tables are normalized in place.
+func normalizeFold(config *SomConfig, train, test []*table.Table) (*SomConfig, error) {
+	c := *config
+	c.Layers = make([]*LayerDef, len(config.Layers))
+	for i, l := range config.Layers {
+		lay := *l
+		c.Layers[i] = &lay
+		if lay.Metric != nil {
+			var err error
+			lay.Metric, err = distance.Clone(l.Metric)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if lay.Categorical || len(lay.Norm) == 0 {
+			continue
+		}
+		lay.Norm = make([]norm.Normalizer, len(l.Norm))
+		for j, n := range l.Norm {
+			var err error
+			lay.Norm[j], err = norm.FromString(norm.ToString(n))
+			if err != nil {
+				return nil, err
+			}
+		}
+		normalizeTable(train[i], &lay, true)
+		normalizeTable(test[i], &lay, false)
+		if m, ok := lay.Metric.(*distance.Mahalanobis); ok {
+			if err := estimateCovariance(train[i], m); err != nil {
+				return nil, fmt.Errorf("can't use mahalanobis metric for layer %s: %s", lay.Name, err.Error())
+			}
+		}
+	}
+	return &c, nil
+}
+
+// foldRows returns the training and test rows of a fold, in ascending order.
+func foldRows(perm []int, fold, folds int) (train, test []int) {
+	start := fold * len(perm) / folds
+	end := (fold + 1) * len(perm) / folds
+	test = slices.Clone(perm[start:end])
+	train = append(slices.Clone(perm[:start]), perm[end:]...)
+	slices.Sort(test)
+	slices.Sort(train)
+	return train, test
+}
+
+// score evaluates a trained SOM on test data.
+func score(s *Som, tables []*table.Table, sc Score, target int) (float64, error) {
+	if sc != ScoreAccuracy {
+		pred, err := NewPredictor(s, tables)
+		if err != nil {
+			return 0, err
+		}
+		eval := NewEvaluator(pred)
+		if sc == ScoreTopographic {
+			return eval.TopographicError(s.MapMetric()), nil
+		}
+		_, mse, _ := eval.Error()
+		return mse, nil
+	}
+
+	_, truth := conv.TableToClasses(tables[target])
+	_, nodeClasses := conv.LayerToClasses(s.layers[target])
+
+	bmuTables := slices.Clone(tables)
+	bmuTables[target] = nil
+	pred, err := NewPredictor(s, bmuTables)
+	if err != nil {
+		return 0, err
+	}
+	bmus := pred.GetBMU()
+
+	correct, total := 0, 0
+	for i, bmu := range bmus {
+		if truth[i] < 0 {
+			continue
+		}
+		total++
+		if nodeClasses[bmu] == truth[i] {
+			correct++
+		}
+	}
+	if total == 0 {
+		return math.NaN(), nil
+	}
+	return float64(correct) / float64(total), nil
+}
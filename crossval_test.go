@@ -0,0 +1,160 @@
+package som
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/mlange-42/som/conv"
+	"github.com/mlange-42/som/decay"
+	"github.com/mlange-42/som/distance"
+	"github.com/mlange-42/som/layer"
+	"github.com/mlange-42/som/neighborhood"
+	"github.com/mlange-42/som/norm"
+	"github.com/mlange-42/som/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func createCrossValidationData(t *testing.T) (*SomConfig, *TrainingConfig, []*table.Table) {
+	rng := rand.New(rand.NewSource(0))
+	rows := 60
+	x := table.New([]string{"x"}, rows)
+	classes := make([]string, rows)
+	for i := 0; i < rows; i++ {
+		if i%2 == 0 {
+			x.Set(i, 0, rng.Float64()*0.2)
+			classes[i] = "a"
+		} else {
+			x.Set(i, 0, 0.8+rng.Float64()*0.2)
+			classes[i] = "b"
+		}
+	}
+	cls, err := conv.ClassesToTable(classes, nil, "")
+	assert.NoError(t, err)
+
+	config := SomConfig{
+		Size: layer.Size{Width: 4, Height: 1},
+		Layers: []*LayerDef{
+			{
+				Name:    "x",
+				Columns: []string{"x"},
+				Norm:    []norm.Normalizer{&norm.Identity{}},
+				Metric:  &distance.Euclidean{},
+			},
+			{
+				Name:        "cls",
+				Columns:     cls.ColumnNames(),
+				Categorical: true,
+			},
+		},
+		Neighborhood: &neighborhood.Gaussian{},
+		MapMetric:    &neighborhood.EuclideanMetric{},
+	}
+	params := TrainingConfig{
+		Epochs:             20,
+		LearningRate:       &decay.Linear{Start: 0.5, End: 0.01},
+		NeighborhoodRadius: &decay.Linear{Start: 2, End: 0.5},
+	}
+	return &config, &params, []*table.Table{x, cls}
+}
+
+func TestFoldRows(t *testing.T) {
+	perm := []int{4, 0, 3, 1, 2}
+	train, test := foldRows(perm, 0, 2)
+	assert.Equal(t, []int{1, 2, 3}, train)
+	assert.Equal(t, []int{0, 4}, test)
+
+	train, test = foldRows(perm, 1, 2)
+	assert.Equal(t, []int{0, 4}, train)
+	assert.Equal(t, []int{1, 2, 3}, test)
+}
+
+func TestNormalizeFold(t *testing.T) {
+	gauss := &norm.Gaussian{}
+	config := &SomConfig{
+		Layers: []*LayerDef{
+			{
+				Name:    "x",
+				Columns: []string{"x"},
+				Norm:    []norm.Normalizer{gauss},
+			},
+		},
+	}
+	train, err := table.NewWithData([]string{"x"}, []float64{0, 2})
+	assert.NoError(t, err)
+	test, err := table.NewWithData([]string{"x"}, []float64{1, 100})
+	assert.NoError(t, err)
+
+	foldConfig, err := normalizeFold(config, []*table.Table{train}, []*table.Table{test})
+	assert.NoError(t, err)
+
+	assert.Equal(t, &norm.Gaussian{}, gauss, "normalizers of the configuration should not be modified")
+	assert.NotSame(t, gauss, foldConfig.Layers[0].Norm[0])
+	mean, _ := train.MeanStdDev(0)
+	assert.InDelta(t, 0.0, mean, 1e-12, "normalizers should be fitted to the training rows only")
+	assert.Equal(t, 0.0, test.Get(0, 0))
+	assert.Greater(t, test.Get(1, 0), 10.0)
+}
+
+func TestCrossValidate(t *testing.T) {
+	config, params, tables := createCrossValidationData(t)
+
+	mean, std, err := CrossValidate(config, params, tables, &CrossValidation{Folds: 3, Score: ScoreAccuracy, Target: "cls"}, rand.New(rand.NewSource(1)))
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, mean)
+	assert.Equal(t, 0.0, std)
+
+	mean, _, err = CrossValidate(config, params, tables, &CrossValidation{Folds: 3, Score: ScoreQuantization}, rand.New(rand.NewSource(1)))
+	assert.NoError(t, err)
+	assert.Greater(t, mean, 0.0)
+	assert.Less(t, mean, 0.1)
+
+	mean2, _, err := CrossValidate(config, params, tables, &CrossValidation{Folds: 3, Score: ScoreQuantization}, rand.New(rand.NewSource(1)))
+	assert.NoError(t, err)
+	assert.Equal(t, mean, mean2, "cross-validation should be reproducible")
+
+	_, _, err = CrossValidate(config, params, tables, &CrossValidation{Folds: 1}, rand.New(rand.NewSource(1)))
+	assert.Error(t, err)
+	_, _, err = CrossValidate(config, params, tables, &CrossValidation{Folds: 3, Score: ScoreAccuracy, Target: "x"}, rand.New(rand.NewSource(1)))
+	assert.Error(t, err)
+	_, _, err = CrossValidate(config, params, tables, &CrossValidation{Folds: 3, Score: ScoreAccuracy, Target: "y"}, rand.New(rand.NewSource(1)))
+	assert.Error(t, err)
+
+	unsupported := []func(p *TrainingConfig){
+		func(p *TrainingConfig) { p.WeightColumn = "w" },
+		func(p *TrainingConfig) { p.Balance = &BalanceConfig{Column: "cls", Mode: Balanced} },
+		func(p *TrainingConfig) { p.ValidationFraction = 0.2 },
+	}
+	for _, set := range unsupported {
+		p := *params
+		set(&p)
+		_, _, err = CrossValidate(config, &p, tables, &CrossValidation{Folds: 3, Score: ScoreQuantization}, rand.New(rand.NewSource(1)))
+		assert.Error(t, err)
+	}
+}
+
+func TestNormalizeFoldMahalanobis(t *testing.T) {
+	metric := &distance.Mahalanobis{}
+	assert.NoError(t, metric.SetArgs(1, 0, 0, 1))
+	config := &SomConfig{
+		Layers: []*LayerDef{
+			{
+				Name:    "xy",
+				Columns: []string{"x", "y"},
+				Norm:    []norm.Normalizer{&norm.Identity{}, &norm.Identity{}},
+				Metric:  metric,
+			},
+		},
+	}
+	train, err := table.NewWithData([]string{"x", "y"}, []float64{1, 2, 2, 4, 3, 5, 4, 9})
+	assert.NoError(t, err)
+	test, err := table.NewWithData([]string{"x", "y"}, []float64{100, -100})
+	assert.NoError(t, err)
+
+	foldConfig, err := normalizeFold(config, []*table.Table{train}, []*table.Table{test})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []float64{1, 0, 0, 1}, metric.GetArgs(), "metrics of the configuration should not be modified")
+	assert.NotSame(t, metric, foldConfig.Layers[0].Metric)
+	assert.InDeltaSlice(t, []float64{5.0 / 3, 11.0 / 3, 11.0 / 3, 26.0 / 3}, foldConfig.Layers[0].Metric.GetArgs(), 1e-12,
+		"covariance should be estimated from the training rows only")
+}
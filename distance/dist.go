@@ -1,90 +1,236 @@
 package distance
 
 import (
+	"fmt"
 	"math"
+	"slices"
+	"strconv"
+	"strings"
 )
 
-var metrics = map[string]Distance{}
+var metrics = map[string]func() Distance{}
 
 func init() {
-	m := []Distance{
-		&SumOfSquares{},
-		&Euclidean{},
-		&Manhattan{},
-		&Hamming{},
+	m := []func() Distance{
+		func() Distance { return &SumOfSquares{} },
+		func() Distance { return &Euclidean{} },
+		func() Distance { return &Manhattan{} },
+		func() Distance { return &Hamming{} },
+		func() Distance { return &Minkowski{} },
+		func() Distance { return &Chebyshev{} },
+		func() Distance { return &Canberra{} },
+		func() Distance { return &Cosine{} },
+		func() Distance { return &Correlation{} },
+		func() Distance { return &Mahalanobis{} },
 	}
 	for _, v := range m {
-		if _, ok := metrics[v.Name()]; ok {
-			panic("duplicate metric name: " + v.Name())
+		vv := v()
+		if _, ok := metrics[vv.Name()]; ok {
+			panic("duplicate metric name: " + vv.Name())
 		}
-		metrics[v.Name()] = v
+		metrics[vv.Name()] = v
 	}
 }
 
+// GetMetric returns a new instance of the metric with the given name, without arguments.
+// For metrics with arguments, use [FromString].
 func GetMetric(name string) (Distance, bool) {
-	d, ok := metrics[name]
-	return d, ok
+	d, err := FromString(name)
+	if err != nil {
+		return nil, false
+	}
+	return d, true
+}
+
+// FromString creates a metric from its name, followed by space-separated arguments.
+// Example: "minkowski 3".
+func FromString(nameAndArgs string) (Distance, error) {
+	parts := strings.Split(nameAndArgs, " ")
+
+	dFunc, ok := metrics[parts[0]]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric: %s", parts[0])
+	}
+	d := dFunc()
+	args := make([]float64, len(parts)-1)
+	for i := 1; i < len(parts); i++ {
+		v, err := strconv.ParseFloat(parts[i], 64)
+		if err != nil {
+			return nil, err
+		}
+		args[i-1] = v
+	}
+	err := d.SetArgs(args...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid arguments for metric %s: %s", parts[0], err.Error())
+	}
+	return d, nil
 }
 
+// ToString returns the name of the metric, followed by its space-separated arguments.
+// It is the inverse of [FromString].
+func ToString(d Distance) string {
+	args := d.GetArgs()
+	if len(args) == 0 {
+		return d.Name()
+	}
+	s := d.Name() + " "
+	for i, v := range args {
+		s += strconv.FormatFloat(v, 'f', -1, 64)
+		if i < len(args)-1 {
+			s += " "
+		}
+	}
+	return s
+}
+
+// Clone returns an independent copy of the metric, with the same arguments and column weights.
+func Clone(d Distance) (Distance, error) {
+	if r, ok := d.(*Rescaled); ok {
+		m, err := Clone(r.Metric)
+		if err != nil {
+			return nil, err
+		}
+		return &Rescaled{Metric: m.(Rescalable)}, nil
+	}
+	dFunc, ok := metrics[d.Name()]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric: %s", d.Name())
+	}
+	c := dFunc()
+	if err := c.SetArgs(d.GetArgs()...); err != nil {
+		return nil, fmt.Errorf("invalid arguments for metric %s: %s", d.Name(), err.Error())
+	}
+	c.SetColumnWeights(slices.Clone(d.ColumnWeights()))
+	return c, nil
+}
+
+// Distance is a distance metric in the data space.
+// Columns with missing data (NaN) are skipped.
+// Columns are weighted by the column weights, if set.
 type Distance interface {
 	Name() string
 	Distance(node, data []float64) float64
+	SetArgs(args ...float64) error
+	GetArgs() []float64
+	SetColumnWeights(weights []float64)
+	ColumnWeights() []float64
 }
 
-type SumOfSquares struct{}
+// noArgs is embedded by metrics without arguments.
+type noArgs struct{}
+
+func (n noArgs) SetArgs(args ...float64) error {
+	if len(args) != 0 {
+		return fmt.Errorf("expected 0 args, got %d", len(args))
+	}
+	return nil
+}
+
+func (n noArgs) GetArgs() []float64 {
+	return nil
+}
+
+// columnWeights is embedded by all metrics, to store per-column weights.
+// Without weights, all columns have a weight of 1.
+type columnWeights struct {
+	weights []float64
+}
+
+func (c *columnWeights) SetColumnWeights(weights []float64) {
+	c.weights = weights
+}
+
+func (c *columnWeights) ColumnWeights() []float64 {
+	return c.weights
+}
+
+// weight returns the weight of the given column.
+func (c *columnWeights) weight(col int) float64 {
+	if c.weights == nil {
+		return 1
+	}
+	return c.weights[col]
+}
+
+type SumOfSquares struct {
+	noArgs
+	columnWeights
+}
 
 func (d *SumOfSquares) Name() string {
 	return "sumofsquares"
 }
 
+func (d *SumOfSquares) Rescale(dist, ratio float64) float64 {
+	return dist * ratio
+}
+
 func (d *SumOfSquares) Distance(node, data []float64) float64 {
 	var sum float64
 	for i := range node {
 		if math.IsNaN(data[i]) {
 			continue
 		}
-		d := node[i] - data[i]
-		sum += d * d
+		diff := node[i] - data[i]
+		sum += d.weight(i) * diff * diff
 	}
 	return sum
 }
 
-type Euclidean struct{}
+type Euclidean struct {
+	noArgs
+	columnWeights
+}
 
 func (d *Euclidean) Name() string {
 	return "euclidean"
 }
 
+func (d *Euclidean) Rescale(dist, ratio float64) float64 {
+	return dist * math.Sqrt(ratio)
+}
+
 func (d *Euclidean) Distance(node, data []float64) float64 {
 	var sum float64
 	for i := range node {
 		if math.IsNaN(data[i]) {
 			continue
 		}
-		d := node[i] - data[i]
-		sum += d * d
+		diff := node[i] - data[i]
+		sum += d.weight(i) * diff * diff
 	}
 	return math.Sqrt(sum)
 }
 
-type Manhattan struct{}
+type Manhattan struct {
+	noArgs
+	columnWeights
+}
 
 func (d *Manhattan) Name() string {
 	return "manhattan"
 }
 
+func (d *Manhattan) Rescale(dist, ratio float64) float64 {
+	return dist * ratio
+}
+
 func (d *Manhattan) Distance(node, data []float64) float64 {
 	var sum float64
 	for i := range node {
 		if math.IsNaN(data[i]) {
 			continue
 		}
-		sum += math.Abs(node[i] - data[i])
+		sum += d.weight(i) * math.Abs(node[i]-data[i])
 	}
 	return sum
 }
 
-type Hamming struct{}
+type Hamming struct {
+	noArgs
+	columnWeights
+}
 
 func (d *Hamming) Name() string {
 	return "hamming"
@@ -97,8 +243,451 @@ func (d *Hamming) Distance(node, data []float64) float64 {
 			continue
 		}
 		if (node[i] < 0.5) != (data[i] < 0.5) {
-			sum++
+			sum += d.weight(i)
+		}
+	}
+	if d.weights == nil {
+		return sum / float64(len(node))
+	}
+	var total float64
+	for _, w := range d.weights {
+		total += w
+	}
+	if total == 0 {
+		return 0
+	}
+	return sum / total
+}
+
+// Minkowski distance with exponent p, i.e. the p-th root of the sum of absolute differences to the power of p.
+// The exponent is set via [Minkowski.SetArgs]. The zero value has an exponent of 2, i.e. the Euclidean distance.
+type Minkowski struct {
+	columnWeights
+	p float64
+}
+
+func (d *Minkowski) Name() string {
+	return "minkowski"
+}
+
+func (d *Minkowski) Rescale(dist, ratio float64) float64 {
+	return dist * math.Pow(ratio, 1/d.exponent())
+}
+
+func (d *Minkowski) Distance(node, data []float64) float64 {
+	var sum float64
+	for i := range node {
+		if math.IsNaN(data[i]) {
+			continue
+		}
+		sum += d.weight(i) * math.Pow(math.Abs(node[i]-data[i]), d.exponent())
+	}
+	return math.Pow(sum, 1/d.exponent())
+}
+
+// exponent returns the exponent of the metric, with a default of 2.
+func (d *Minkowski) exponent() float64 {
+	if d.p == 0 {
+		return 2
+	}
+	return d.p
+}
+
+func (d *Minkowski) SetArgs(args ...float64) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected 1 arg, got %d", len(args))
+	}
+	if args[0] <= 0 {
+		return fmt.Errorf("exponent must be positive, got %f", args[0])
+	}
+	d.p = args[0]
+	return nil
+}
+
+func (d *Minkowski) GetArgs() []float64 {
+	return []float64{d.exponent()}
+}
+
+// Chebyshev distance, i.e. the maximum absolute difference.
+type Chebyshev struct {
+	noArgs
+	columnWeights
+}
+
+func (d *Chebyshev) Name() string {
+	return "chebyshev"
+}
+
+func (d *Chebyshev) Distance(node, data []float64) float64 {
+	var maxDiff float64
+	for i := range node {
+		if math.IsNaN(data[i]) {
+			continue
+		}
+		maxDiff = math.Max(maxDiff, d.weight(i)*math.Abs(node[i]-data[i]))
+	}
+	return maxDiff
+}
+
+// Canberra distance, i.e. the sum of absolute differences, each divided by the sum of absolute values.
+// Columns where both values are zero are skipped.
+type Canberra struct {
+	noArgs
+	columnWeights
+}
+
+func (d *Canberra) Name() string {
+	return "canberra"
+}
+
+func (d *Canberra) Rescale(dist, ratio float64) float64 {
+	return dist * ratio
+}
+
+func (d *Canberra) Distance(node, data []float64) float64 {
+	var sum float64
+	for i := range node {
+		if math.IsNaN(data[i]) {
+			continue
+		}
+		denom := math.Abs(node[i]) + math.Abs(data[i])
+		if denom == 0 {
+			continue
+		}
+		sum += d.weight(i) * math.Abs(node[i]-data[i]) / denom
+	}
+	return sum
+}
+
+// Cosine distance, i.e. one minus the cosine of the angle between the vectors.
+// If one of the vectors is zero, the distance is 1, or 0 if both are zero.
+type Cosine struct {
+	noArgs
+	columnWeights
+}
+
+func (d *Cosine) Name() string {
+	return "cosine"
+}
+
+func (d *Cosine) Distance(node, data []float64) float64 {
+	var dot, normNode, normData float64
+	for i := range node {
+		if math.IsNaN(data[i]) {
+			continue
+		}
+		w := d.weight(i)
+		dot += w * node[i] * data[i]
+		normNode += w * node[i] * node[i]
+		normData += w * data[i] * data[i]
+	}
+	if normNode == 0 || normData == 0 {
+		if normNode == normData {
+			return 0
+		}
+		return 1
+	}
+	return 1 - dot/math.Sqrt(normNode*normData)
+}
+
+// Correlation distance, i.e. one minus the Pearson correlation coefficient of the vectors.
+// If one of the vectors has zero variance, the distance is 1, or 0 if both have zero variance.
+type Correlation struct {
+	noArgs
+	columnWeights
+}
+
+func (d *Correlation) Name() string {
+	return "correlation"
+}
+
+func (d *Correlation) Distance(node, data []float64) float64 {
+	var meanNode, meanData, total float64
+	for i := range node {
+		if math.IsNaN(data[i]) {
+			continue
+		}
+		w := d.weight(i)
+		meanNode += w * node[i]
+		meanData += w * data[i]
+		total += w
+	}
+	if total == 0 {
+		return 0
+	}
+	meanNode /= total
+	meanData /= total
+
+	var cov, varNode, varData float64
+	for i := range node {
+		if math.IsNaN(data[i]) {
+			continue
+		}
+		w := d.weight(i)
+		dn, dd := node[i]-meanNode, data[i]-meanData
+		cov += w * dn * dd
+		varNode += w * dn * dn
+		varData += w * dd * dd
+	}
+	if varNode == 0 || varData == 0 {
+		if varNode == varData {
+			return 0
+		}
+		return 1
+	}
+	return 1 - cov/math.Sqrt(varNode*varData)
+}
+
+// Mahalanobis distance, i.e. the Euclidean distance after decorrelating the columns
+// with the inverse of their covariance matrix.
+// Without a covariance matrix, it is equivalent to the Euclidean distance.
+//
+// The arguments are the elements of the covariance matrix, in row-major order.
+// Columns with missing data are dropped from the calculation,
+// i.e. the distance is calculated from the covariance matrix of the observed columns only.
+// This is slower than for complete rows, as it requires inverting that matrix.
+// Use [Rescaled] to compensate for the missing columns.
+type Mahalanobis struct {
+	columnWeights
+	covariance []float64
+	precision  []float64
+	dims       int
+}
+
+func (d *Mahalanobis) Name() string {
+	return "mahalanobis"
+}
+
+func (d *Mahalanobis) Rescale(dist, ratio float64) float64 {
+	return dist * math.Sqrt(ratio)
+}
+
+// SetCovariance sets the covariance matrix, and calculates its inverse.
+// Returns an error if the matrix is not square, or singular.
+func (d *Mahalanobis) SetCovariance(cov [][]float64) error {
+	flat := make([]float64, 0, len(cov)*len(cov))
+	for _, row := range cov {
+		if len(row) != len(cov) {
+			return fmt.Errorf("covariance matrix is not square")
+		}
+		flat = append(flat, row...)
+	}
+	return d.SetArgs(flat...)
+}
+
+// Dims returns the number of columns of the covariance matrix, or 0 if there is none.
+func (d *Mahalanobis) Dims() int {
+	return d.dims
+}
+
+func (d *Mahalanobis) SetArgs(args ...float64) error {
+	if len(args) == 0 {
+		d.covariance, d.precision, d.dims = nil, nil, 0
+		return nil
+	}
+	dims := int(math.Round(math.Sqrt(float64(len(args)))))
+	if dims*dims != len(args) {
+		return fmt.Errorf("expected a square number of args, got %d", len(args))
+	}
+	precision, err := invert(args, dims)
+	if err != nil {
+		return err
+	}
+	d.covariance = append([]float64{}, args...)
+	d.precision = precision
+	d.dims = dims
+	return nil
+}
+
+func (d *Mahalanobis) GetArgs() []float64 {
+	return d.covariance
+}
+
+func (d *Mahalanobis) Distance(node, data []float64) float64 {
+	if d.precision == nil {
+		var sum float64
+		for i := range node {
+			if math.IsNaN(data[i]) {
+				continue
+			}
+			diff := node[i] - data[i]
+			sum += d.weight(i) * diff * diff
+		}
+		return math.Sqrt(sum)
+	}
+	for _, v := range data {
+		if math.IsNaN(v) {
+			return d.partialDistance(node, data)
+		}
+	}
+
+	var sum float64
+	for i := range node {
+		a := math.Sqrt(d.weight(i)) * (node[i] - data[i])
+		if a == 0 {
+			continue
+		}
+		row := d.precision[i*d.dims : (i+1)*d.dims]
+		for j := range node {
+			sum += a * row[j] * math.Sqrt(d.weight(j)) * (node[j] - data[j])
+		}
+	}
+	return math.Sqrt(math.Max(sum, 0))
+}
+
+// partialDistance calculates the distance for a row with missing data,
+// using the inverse of the covariance matrix of the observed columns only.
+func (d *Mahalanobis) partialDistance(node, data []float64) float64 {
+	observed := make([]int, 0, len(data))
+	for i, v := range data {
+		if !math.IsNaN(v) {
+			observed = append(observed, i)
+		}
+	}
+	n := len(observed)
+	if n == 0 {
+		return 0
+	}
+	cov := make([]float64, n*n)
+	diff := make([]float64, n)
+	for k, i := range observed {
+		diff[k] = math.Sqrt(d.weight(i)) * (node[i] - data[i])
+		for l, j := range observed {
+			cov[k*n+l] = d.covariance[i*d.dims+j]
+		}
+	}
+	precision, err := invert(cov, n)
+	if err != nil {
+		// Can only happen due to numerical issues, as the covariance matrix itself is invertible.
+		precision = cov
+		for k, i := range observed {
+			for l, j := range observed {
+				precision[k*n+l] = d.precision[i*d.dims+j]
+			}
+		}
+	}
+
+	var sum float64
+	for k, a := range diff {
+		for l, b := range diff {
+			sum += a * precision[k*n+l] * b
+		}
+	}
+	return math.Sqrt(math.Max(sum, 0))
+}
+
+// invert calculates the inverse of a square matrix in row-major order,
+// using Gauss-Jordan elimination with partial pivoting.
+func invert(matrix []float64, dims int) ([]float64, error) {
+	a := append([]float64{}, matrix...)
+	inv := make([]float64, dims*dims)
+	scale := 0.0
+	for i := 0; i < dims; i++ {
+		inv[i*dims+i] = 1
+		scale = math.Max(scale, math.Abs(a[i*dims+i]))
+	}
+	for col := 0; col < dims; col++ {
+		pivot := col
+		for row := col + 1; row < dims; row++ {
+			if math.Abs(a[row*dims+col]) > math.Abs(a[pivot*dims+col]) {
+				pivot = row
+			}
+		}
+		if scale == 0 || math.Abs(a[pivot*dims+col]) < 1e-12*scale {
+			return nil, fmt.Errorf("matrix is singular")
+		}
+		if pivot != col {
+			for k := 0; k < dims; k++ {
+				a[col*dims+k], a[pivot*dims+k] = a[pivot*dims+k], a[col*dims+k]
+				inv[col*dims+k], inv[pivot*dims+k] = inv[pivot*dims+k], inv[col*dims+k]
+			}
+		}
+		p := a[col*dims+col]
+		for k := 0; k < dims; k++ {
+			a[col*dims+k] /= p
+			inv[col*dims+k] /= p
+		}
+		for row := 0; row < dims; row++ {
+			f := a[row*dims+col]
+			if row == col || f == 0 {
+				continue
+			}
+			for k := 0; k < dims; k++ {
+				a[row*dims+k] -= f * a[col*dims+k]
+				inv[row*dims+k] -= f * inv[col*dims+k]
+			}
+		}
+	}
+	return inv, nil
+}
+
+// Rescalable is a metric that can be rescaled for missing data, see [Rescaled].
+// Metrics that are scale-free or that take a maximum or mean over columns are not rescalable.
+type Rescalable interface {
+	Distance
+	// Rescale scales up a distance calculated from the observed columns only,
+	// given the ratio of the (weighted) number of all columns to the number of observed columns.
+	Rescale(dist, ratio float64) float64
+}
+
+// Rescaled wraps a metric to compensate for missing data (NaN).
+// The distance of the wrapped metric is scaled up according to the (weighted) fraction of observed columns,
+// so that rows with missing values are not artificially close to all nodes.
+// For additive metrics, the distance is divided by the fraction, and for root-based metrics
+// by the respective root of the fraction. See [Rescalable].
+// For rows without any observed column, the distance is 0.
+//
+// Name and arguments are those of the wrapped metric.
+type Rescaled struct {
+	Metric Rescalable
+}
+
+func (d *Rescaled) Name() string {
+	return d.Metric.Name()
+}
+
+func (d *Rescaled) SetArgs(args ...float64) error {
+	return d.Metric.SetArgs(args...)
+}
+
+func (d *Rescaled) GetArgs() []float64 {
+	return d.Metric.GetArgs()
+}
+
+func (d *Rescaled) SetColumnWeights(weights []float64) {
+	d.Metric.SetColumnWeights(weights)
+}
+
+func (d *Rescaled) ColumnWeights() []float64 {
+	return d.Metric.ColumnWeights()
+}
+
+func (d *Rescaled) Distance(node, data []float64) float64 {
+	weights := d.Metric.ColumnWeights()
+	var total, observed float64
+	for i, v := range data {
+		w := 1.0
+		if weights != nil {
+			w = weights[i]
+		}
+		total += w
+		if !math.IsNaN(v) {
+			observed += w
+		}
+	}
+	if observed == 0 {
+		return 0
+	}
+	return d.Metric.Rescale(d.Metric.Distance(node, data), total/observed)
+}
+
+// Observed returns the number of columns with data, i.e. that are not NaN.
+func Observed(data []float64) int {
+	count := 0
+	for _, v := range data {
+		if !math.IsNaN(v) {
+			count++
 		}
 	}
-	return sum / float64(len(node))
+	return count
 }
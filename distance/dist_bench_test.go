@@ -58,3 +58,36 @@ func BenchmarkHammingDistance3(b *testing.B) {
 func BenchmarkHammingDistance10(b *testing.B) {
 	benchmarkDistanceMetric(b, &distance.Hamming{}, 10)
 }
+
+func BenchmarkMinkowskiDistance10(b *testing.B) {
+	benchmarkDistanceMetric(b, minkowski(3), 10)
+}
+
+func BenchmarkChebyshevDistance10(b *testing.B) {
+	benchmarkDistanceMetric(b, &distance.Chebyshev{}, 10)
+}
+
+func BenchmarkCanberraDistance10(b *testing.B) {
+	benchmarkDistanceMetric(b, &distance.Canberra{}, 10)
+}
+
+func BenchmarkCosineDistance10(b *testing.B) {
+	benchmarkDistanceMetric(b, &distance.Cosine{}, 10)
+}
+
+func BenchmarkCorrelationDistance10(b *testing.B) {
+	benchmarkDistanceMetric(b, &distance.Correlation{}, 10)
+}
+
+func BenchmarkMahalanobisDistance10(b *testing.B) {
+	cov := make([][]float64, 10)
+	for i := range cov {
+		cov[i] = make([]float64, 10)
+		cov[i][i] = 1
+	}
+	d := distance.Mahalanobis{}
+	if err := d.SetCovariance(cov); err != nil {
+		b.Fatal(err)
+	}
+	benchmarkDistanceMetric(b, &d, 10)
+}
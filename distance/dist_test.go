@@ -95,3 +95,296 @@ func TestHammingDistance(t *testing.T) {
 		})
 	}
 }
+
+// minkowski creates a Minkowski metric with the given exponent.
+func minkowski(p float64) *distance.Minkowski {
+	d := &distance.Minkowski{}
+	if err := d.SetArgs(p); err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestMinkowskiDistance(t *testing.T) {
+	tests := []struct {
+		name     string
+		p        float64
+		x        []float64
+		y        []float64
+		expected float64
+	}{
+		{"Zero vectors", 3, []float64{0, 0, 0}, []float64{0, 0, 0}, 0},
+		{"Manhattan", 1, []float64{1, 1, 1}, []float64{4, 5, 6}, 3 + 4 + 5},
+		{"Euclidean", 2, []float64{0, 0}, []float64{3, 4}, 5},
+		{"Cubic", 3, []float64{0, 0}, []float64{1, 2}, math.Cbrt(9)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := minkowski(tt.p)
+			result := d.Distance(tt.x, tt.y)
+			assert.InDelta(t, tt.expected, result, 1e-12)
+		})
+	}
+	d := distance.Minkowski{}
+	assert.Equal(t, []float64{2}, d.GetArgs())
+	assert.InDelta(t, 5.0, d.Distance([]float64{0, 0}, []float64{3, 4}), 1e-12)
+	assert.Error(t, d.SetArgs(0))
+	assert.Error(t, d.SetArgs(-1))
+}
+
+func TestChebyshevDistance(t *testing.T) {
+	d := &distance.Chebyshev{}
+	assert.Equal(t, 0.0, d.Distance([]float64{0, 0, 0}, []float64{0, 0, 0}))
+	assert.Equal(t, 5.0, d.Distance([]float64{1, 1, 1}, []float64{4, 6, 5}))
+	assert.Equal(t, 6.0, d.Distance([]float64{-1, 2, -3}, []float64{1, -2, 3}))
+}
+
+func TestCanberraDistance(t *testing.T) {
+	d := &distance.Canberra{}
+	assert.Equal(t, 0.0, d.Distance([]float64{0, 0, 0}, []float64{0, 0, 0}))
+	assert.InDelta(t, 1.0/3.0+1, d.Distance([]float64{1, 0, 0}, []float64{2, 0, 3}), 1e-12)
+	assert.InDelta(t, 3.0, d.Distance([]float64{-1, 2, -3}, []float64{1, -2, 3}), 1e-12)
+}
+
+func TestCosineDistance(t *testing.T) {
+	d := &distance.Cosine{}
+	assert.Equal(t, 0.0, d.Distance([]float64{0, 0}, []float64{0, 0}))
+	assert.Equal(t, 1.0, d.Distance([]float64{0, 0}, []float64{1, 0}))
+	assert.InDelta(t, 0.0, d.Distance([]float64{1, 2}, []float64{2, 4}), 1e-12)
+	assert.InDelta(t, 1.0, d.Distance([]float64{1, 0}, []float64{0, 3}), 1e-12)
+	assert.InDelta(t, 2.0, d.Distance([]float64{1, 2}, []float64{-1, -2}), 1e-12)
+}
+
+func TestCorrelationDistance(t *testing.T) {
+	d := &distance.Correlation{}
+	assert.Equal(t, 0.0, d.Distance([]float64{1, 1}, []float64{2, 2}))
+	assert.Equal(t, 1.0, d.Distance([]float64{1, 1, 1}, []float64{1, 2, 3}))
+	assert.InDelta(t, 0.0, d.Distance([]float64{1, 2, 3}, []float64{10, 20, 30}), 1e-12)
+	assert.InDelta(t, 2.0, d.Distance([]float64{1, 2, 3}, []float64{3, 2, 1}), 1e-12)
+	assert.InDelta(t, 1.0, d.Distance([]float64{1, 2, 1}, []float64{1, 2, 3}), 1e-12)
+}
+
+func TestDistanceMissing(t *testing.T) {
+	nan := math.NaN()
+	node := []float64{1, 2, 3}
+	data := []float64{2, nan, 6}
+	reduced := minkowski(3)
+
+	tests := []struct {
+		d        distance.Distance
+		expected float64
+	}{
+		{&distance.SumOfSquares{}, 1 + 9},
+		{&distance.Euclidean{}, math.Sqrt(1 + 9)},
+		{&distance.Manhattan{}, 1 + 3},
+		{reduced, reduced.Distance([]float64{1, 3}, []float64{2, 6})},
+		{&distance.Chebyshev{}, 3},
+		{&distance.Canberra{}, 1.0/3.0 + 3.0/9.0},
+		{&distance.Cosine{}, (&distance.Cosine{}).Distance([]float64{1, 3}, []float64{2, 6})},
+		{&distance.Correlation{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.d.Name(), func(t *testing.T) {
+			assert.InDelta(t, tt.expected, tt.d.Distance(node, data), 1e-12)
+		})
+	}
+}
+
+func TestFromString(t *testing.T) {
+	for _, name := range []string{"sumofsquares", "euclidean", "manhattan", "hamming", "chebyshev", "canberra", "cosine", "correlation"} {
+		d, err := distance.FromString(name)
+		assert.NoError(t, err)
+		assert.Equal(t, name, d.Name())
+		assert.Equal(t, name, distance.ToString(d))
+
+		_, err = distance.FromString(name + " 1")
+		assert.Error(t, err)
+	}
+
+	d, err := distance.FromString("minkowski 3")
+	assert.NoError(t, err)
+	assert.Equal(t, minkowski(3), d)
+	assert.Equal(t, "minkowski 3", distance.ToString(d))
+
+	d, ok := distance.GetMetric("euclidean")
+	assert.True(t, ok)
+	assert.Equal(t, &distance.Euclidean{}, d)
+
+	_, ok = distance.GetMetric("minkowski")
+	assert.False(t, ok)
+
+	_, err = distance.FromString("minkowski")
+	assert.Error(t, err)
+	_, err = distance.FromString("minkowski 0")
+	assert.Error(t, err)
+	_, err = distance.FromString("minkowski x")
+	assert.Error(t, err)
+	_, err = distance.FromString("unknown")
+	assert.Error(t, err)
+}
+
+func TestRescaledDistance(t *testing.T) {
+	nan := math.NaN()
+	d := distance.Rescaled{Metric: &distance.Manhattan{}}
+
+	assert.Equal(t, "manhattan", d.Name())
+	assert.Equal(t, "manhattan", distance.ToString(&d))
+
+	assert.Equal(t, 6.0, d.Distance([]float64{1, 2, 3, 4}, []float64{2, 3, 4, 7}))
+	assert.Equal(t, 8.0, d.Distance([]float64{1, 2, 3, 4}, []float64{2, nan, nan, 7}))
+	assert.Equal(t, 0.0, d.Distance([]float64{1, 2, 3, 4}, []float64{nan, nan, nan, nan}))
+
+	m := distance.Rescaled{Metric: &distance.Minkowski{}}
+	assert.NoError(t, m.SetArgs(3))
+	assert.Equal(t, []float64{3}, m.GetArgs())
+	assert.Equal(t, "minkowski 3", distance.ToString(&m))
+
+	assert.Equal(t, 2, distance.Observed([]float64{1, nan, 3}))
+}
+
+func TestRescaledDistanceMetrics(t *testing.T) {
+	nan := math.NaN()
+	node := []float64{0, 0, 0, 0}
+	full := []float64{1, 1, 1, 1}
+	partial := []float64{1, nan, 1, nan}
+
+	tests := []distance.Rescalable{
+		&distance.SumOfSquares{},
+		&distance.Euclidean{},
+		&distance.Manhattan{},
+		minkowski(3),
+		&distance.Canberra{},
+		&distance.Mahalanobis{},
+	}
+
+	for _, m := range tests {
+		d := distance.Rescaled{Metric: m}
+		assert.InDelta(t, m.Distance(node, full), d.Distance(node, partial), 1e-12, m.Name())
+	}
+
+	d := distance.Rescaled{Metric: &distance.Euclidean{}}
+	assert.InDelta(t, math.Sqrt(2)*math.Sqrt(2), d.Distance(node, partial), 1e-12)
+
+	d = distance.Rescaled{Metric: minkowski(3)}
+	assert.InDelta(t, math.Cbrt(2), d.Distance([]float64{0, 0}, []float64{1, nan}), 1e-12)
+
+	for _, m := range []distance.Distance{
+		&distance.Hamming{},
+		&distance.Chebyshev{},
+		&distance.Cosine{},
+		&distance.Correlation{},
+	} {
+		_, ok := m.(distance.Rescalable)
+		assert.False(t, ok, m.Name())
+	}
+}
+
+func TestClone(t *testing.T) {
+	m := &distance.Mahalanobis{}
+	assert.NoError(t, m.SetArgs(2, 1, 1, 2))
+	m.SetColumnWeights([]float64{1, 2})
+
+	c, err := distance.Clone(m)
+	assert.NoError(t, err)
+	assert.NotSame(t, m, c)
+	assert.Equal(t, m, c)
+
+	c.SetColumnWeights(nil)
+	assert.Equal(t, []float64{1, 2}, m.ColumnWeights())
+
+	r := &distance.Rescaled{Metric: minkowski(3)}
+	c, err = distance.Clone(r)
+	assert.NoError(t, err)
+	assert.NotSame(t, r.Metric, c.(*distance.Rescaled).Metric)
+	assert.Equal(t, r, c)
+}
+
+func TestDistanceColumnWeights(t *testing.T) {
+	nan := math.NaN()
+	node := []float64{1, 2, 3}
+	data := []float64{2, 4, 6}
+	weights := []float64{4, 0, 1}
+
+	tests := []struct {
+		d        distance.Distance
+		expected float64
+	}{
+		{&distance.SumOfSquares{}, 4*1 + 9},
+		{&distance.Euclidean{}, math.Sqrt(4*1 + 9)},
+		{&distance.Manhattan{}, 4*1 + 3},
+		{&distance.Hamming{}, 0},
+		{minkowski(1), 4*1 + 3},
+		{&distance.Chebyshev{}, 4},
+		{&distance.Canberra{}, 4.0/3.0 + 3.0/9.0},
+		{&distance.Cosine{}, 0},
+		{&distance.Correlation{}, 0},
+		{&distance.Rescaled{Metric: &distance.Manhattan{}}, 4*1 + 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.d.Name(), func(t *testing.T) {
+			assert.Nil(t, tt.d.ColumnWeights())
+			tt.d.SetColumnWeights(weights)
+			assert.Equal(t, weights, tt.d.ColumnWeights())
+			assert.InDelta(t, tt.expected, tt.d.Distance(node, data), 1e-12)
+		})
+	}
+
+	h := distance.Hamming{}
+	h.SetColumnWeights([]float64{3, 1})
+	assert.Equal(t, 0.75, h.Distance([]float64{1, 0}, []float64{0, 0}))
+	assert.Equal(t, 0.25, h.Distance([]float64{1, 1}, []float64{1, 0}))
+
+	c := distance.Correlation{}
+	c.SetColumnWeights([]float64{1, 1, 0})
+	assert.InDelta(t, 0.0, c.Distance([]float64{1, 2, 3}, []float64{1, 2, -5}), 1e-12)
+
+	r := distance.Rescaled{Metric: &distance.Manhattan{}}
+	r.SetColumnWeights([]float64{3, 1})
+	assert.Equal(t, 4.0, r.Distance([]float64{0, 0}, []float64{1, 1}))
+	assert.Equal(t, 4.0, r.Distance([]float64{0, 0}, []float64{1, nan}))
+	assert.Equal(t, 4.0, r.Distance([]float64{0, 0}, []float64{nan, 1}))
+}
+
+func TestMahalanobisDistance(t *testing.T) {
+	nan := math.NaN()
+	d := distance.Mahalanobis{}
+	assert.Equal(t, 0, d.Dims())
+	assert.InDelta(t, 5.0, d.Distance([]float64{0, 0}, []float64{3, 4}), 1e-12)
+
+	assert.NoError(t, d.SetCovariance([][]float64{{4, 0}, {0, 1}}))
+	assert.Equal(t, 2, d.Dims())
+	assert.InDelta(t, math.Sqrt(9.0/4+16), d.Distance([]float64{0, 0}, []float64{3, 4}), 1e-12)
+	assert.InDelta(t, 4.0, d.Distance([]float64{0, 0}, []float64{nan, 4}), 1e-12)
+
+	assert.NoError(t, d.SetCovariance([][]float64{{1, 0.5}, {0.5, 1}}))
+	assert.InDelta(t, math.Sqrt(2.0/1.5), d.Distance([]float64{0, 0}, []float64{1, 1}), 1e-12)
+	assert.InDelta(t, math.Sqrt(2.0/0.5), d.Distance([]float64{0, 0}, []float64{1, -1}), 1e-12)
+	assert.InDelta(t, 2.0, d.Distance([]float64{0, 0}, []float64{nan, 2}), 1e-12)
+	assert.Equal(t, 0.0, d.Distance([]float64{0, 0}, []float64{nan, nan}))
+
+	allocs := testing.AllocsPerRun(10, func() {
+		d.Distance([]float64{0, 0}, []float64{1, -1})
+	})
+	assert.Equal(t, 0.0, allocs)
+
+	d.SetColumnWeights([]float64{1, 0})
+	assert.InDelta(t, math.Sqrt(4.0/3.0), d.Distance([]float64{0, 0}, []float64{1, 1}), 1e-12)
+	d.SetColumnWeights([]float64{1, 4})
+	assert.InDelta(t, 4.0, d.Distance([]float64{0, 0}, []float64{nan, 2}), 1e-12)
+
+	assert.Error(t, d.SetCovariance([][]float64{{1, 2}, {2, 4}}))
+	assert.Error(t, d.SetCovariance([][]float64{{1, 2}, {2}}))
+	assert.Error(t, d.SetArgs(1, 0, 0))
+
+	m, err := distance.FromString("mahalanobis 1 0.5 0.5 1")
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{1, 0.5, 0.5, 1}, m.GetArgs())
+	assert.Equal(t, "mahalanobis 1 0.5 0.5 1", distance.ToString(m))
+
+	m, err = distance.FromString("mahalanobis")
+	assert.NoError(t, err)
+	assert.Equal(t, "mahalanobis", distance.ToString(m))
+}
@@ -0,0 +1,160 @@
+package som
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/mlange-42/som/conv"
+	"github.com/mlange-42/som/table"
+)
+
+// ClassificationMetrics holds metrics for the prediction of a categorical layer.
+// Per-class slices are in the order of Classes.
+type ClassificationMetrics struct {
+	Layer     string    // Name of the layer
+	Classes   []string  // Class names
+	Accuracy  float64   // Fraction of correctly predicted rows
+	Precision []float64 // Precision per class. Zero for classes that are never predicted
+	Recall    []float64 // Recall per class. Zero for classes that never occur
+	F1        []float64 // F1 score per class
+	Support   []int     // Number of rows per true class
+	Confusion [][]int   // Confusion matrix, indexed by true and predicted class
+	Count     int       // Number of evaluated rows
+}
+
+// RegressionMetrics holds metrics for the prediction of a single column of a continuous layer.
+type RegressionMetrics struct {
+	Layer  string  // Name of the layer
+	Column string  // Name of the column
+	RMSE   float64 // Root mean squared error
+	MAE    float64 // Mean absolute error
+	R2     float64 // Coefficient of determination. NaN if the true values have no variance
+	Count  int     // Number of evaluated rows
+}
+
+// EvaluateClassification compares predicted classes to the true classes of a categorical layer.
+// Classes are derived from the tables using [conv.TableToClasses].
+// Rows with an unknown true or predicted class are skipped.
+//
+// Both tables must have the same columns and rows.
+func EvaluateClassification(layer string, truth, predicted *table.Table) (*ClassificationMetrics, error) {
+	if err := checkEvaluationTables(truth, predicted); err != nil {
+		return nil, err
+	}
+	classes, trueClasses := conv.TableToClasses(truth)
+	_, predClasses := conv.TableToClasses(predicted)
+
+	n := len(classes)
+	m := ClassificationMetrics{
+		Layer:     layer,
+		Classes:   classes,
+		Precision: make([]float64, n),
+		Recall:    make([]float64, n),
+		F1:        make([]float64, n),
+		Support:   make([]int, n),
+		Confusion: make([][]int, n),
+	}
+	for i := range m.Confusion {
+		m.Confusion[i] = make([]int, n)
+	}
+
+	correct := 0
+	for i, t := range trueClasses {
+		p := predClasses[i]
+		if t < 0 || p < 0 {
+			continue
+		}
+		m.Confusion[t][p]++
+		m.Support[t]++
+		m.Count++
+		if t == p {
+			correct++
+		}
+	}
+	if m.Count == 0 {
+		return nil, fmt.Errorf("no rows with known classes in layer %s", layer)
+	}
+	m.Accuracy = float64(correct) / float64(m.Count)
+
+	for c := 0; c < n; c++ {
+		predictedCount := 0
+		for t := 0; t < n; t++ {
+			predictedCount += m.Confusion[t][c]
+		}
+		hits := float64(m.Confusion[c][c])
+		if predictedCount > 0 {
+			m.Precision[c] = hits / float64(predictedCount)
+		}
+		if m.Support[c] > 0 {
+			m.Recall[c] = hits / float64(m.Support[c])
+		}
+		if m.Precision[c]+m.Recall[c] > 0 {
+			m.F1[c] = 2 * m.Precision[c] * m.Recall[c] / (m.Precision[c] + m.Recall[c])
+		}
+	}
+
+	return &m, nil
+}
+
+// EvaluateRegression compares predicted values to the true values of a continuous layer, per column.
+// Rows with missing true or predicted values are skipped.
+//
+// Both tables must have the same columns and rows.
+func EvaluateRegression(layer string, truth, predicted *table.Table) ([]RegressionMetrics, error) {
+	if err := checkEvaluationTables(truth, predicted); err != nil {
+		return nil, err
+	}
+
+	metrics := make([]RegressionMetrics, truth.Columns())
+	for col, name := range truth.ColumnNames() {
+		m := RegressionMetrics{Layer: layer, Column: name}
+
+		mean := 0.0
+		for row := 0; row < truth.Rows(); row++ {
+			t, p := truth.Get(row, col), predicted.Get(row, col)
+			if math.IsNaN(t) || math.IsNaN(p) {
+				continue
+			}
+			mean += t
+			m.Count++
+		}
+		if m.Count == 0 {
+			return nil, fmt.Errorf("no rows with values in column %s of layer %s", name, layer)
+		}
+		mean /= float64(m.Count)
+
+		sse, sst := 0.0, 0.0
+		for row := 0; row < truth.Rows(); row++ {
+			t, p := truth.Get(row, col), predicted.Get(row, col)
+			if math.IsNaN(t) || math.IsNaN(p) {
+				continue
+			}
+			sse += (t - p) * (t - p)
+			sst += (t - mean) * (t - mean)
+			m.MAE += math.Abs(t - p)
+		}
+		m.RMSE = math.Sqrt(sse / float64(m.Count))
+		m.MAE /= float64(m.Count)
+		if sst > 0 {
+			m.R2 = 1 - sse/sst
+		} else {
+			m.R2 = math.NaN()
+		}
+
+		metrics[col] = m
+	}
+
+	return metrics, nil
+}
+
+// checkEvaluationTables checks that the tables of true and predicted values have the same shape.
+func checkEvaluationTables(truth, predicted *table.Table) error {
+	if truth.Rows() != predicted.Rows() {
+		return fmt.Errorf("number of rows of true values (%d) does not match number of rows of predictions (%d)", truth.Rows(), predicted.Rows())
+	}
+	if !slices.Equal(truth.ColumnNames(), predicted.ColumnNames()) {
+		return fmt.Errorf("columns of true values do not match columns of predictions")
+	}
+	return nil
+}
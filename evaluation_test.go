@@ -0,0 +1,91 @@
+package som
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mlange-42/som/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateClassification(t *testing.T) {
+	nan := math.NaN()
+	truth, err := table.NewWithData([]string{"a", "b", "c"}, []float64{
+		1, 0, 0,
+		1, 0, 0,
+		0, 1, 0,
+		0, 1, 0,
+		0, 0, 1,
+		nan, nan, nan,
+	})
+	assert.NoError(t, err)
+	predicted, err := table.NewWithData([]string{"a", "b", "c"}, []float64{
+		0.9, 0.1, 0,
+		0.2, 0.7, 0.1,
+		0.1, 0.8, 0.1,
+		0, 1, 0,
+		0, 0.9, 0.1,
+		1, 0, 0,
+	})
+	assert.NoError(t, err)
+
+	m, err := EvaluateClassification("cls", truth, predicted)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "cls", m.Layer)
+	assert.Equal(t, []string{"a", "b", "c"}, m.Classes)
+	assert.Equal(t, 5, m.Count)
+	assert.InDelta(t, 0.6, m.Accuracy, 1e-12)
+	assert.Equal(t, [][]int{{1, 1, 0}, {0, 2, 0}, {0, 1, 0}}, m.Confusion)
+	assert.Equal(t, []int{2, 2, 1}, m.Support)
+	assert.InDeltaSlice(t, []float64{1, 0.5, 0}, m.Precision, 1e-12)
+	assert.InDeltaSlice(t, []float64{0.5, 1, 0}, m.Recall, 1e-12)
+	assert.InDeltaSlice(t, []float64{2.0 / 3.0, 2.0 / 3.0, 0}, m.F1, 1e-12)
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := EvaluateClassification("cls", truth, table.New([]string{"a", "b", "c"}, 5))
+		assert.Error(t, err)
+		_, err = EvaluateClassification("cls", truth, table.New([]string{"a", "b", "x"}, 6))
+		assert.Error(t, err)
+
+		empty, err := table.NewWithData([]string{"a"}, []float64{nan})
+		assert.NoError(t, err)
+		_, err = EvaluateClassification("cls", empty, table.New([]string{"a"}, 1))
+		assert.Error(t, err)
+	})
+}
+
+func TestEvaluateRegression(t *testing.T) {
+	nan := math.NaN()
+	truth, err := table.NewWithData([]string{"x", "y"}, []float64{
+		1, 2,
+		2, 2,
+		3, 2,
+		nan, 2,
+	})
+	assert.NoError(t, err)
+	predicted, err := table.NewWithData([]string{"x", "y"}, []float64{
+		1, 1,
+		3, 2,
+		3, 3,
+		5, nan,
+	})
+	assert.NoError(t, err)
+
+	m, err := EvaluateRegression("lay", truth, predicted)
+	assert.NoError(t, err)
+	assert.Len(t, m, 2)
+
+	assert.Equal(t, "lay", m[0].Layer)
+	assert.Equal(t, "x", m[0].Column)
+	assert.Equal(t, 3, m[0].Count)
+	assert.InDelta(t, math.Sqrt(1.0/3.0), m[0].RMSE, 1e-12)
+	assert.InDelta(t, 1.0/3.0, m[0].MAE, 1e-12)
+	assert.InDelta(t, 0.5, m[0].R2, 1e-12)
+
+	assert.Equal(t, "y", m[1].Column)
+	assert.Equal(t, 3, m[1].Count)
+	assert.InDelta(t, math.Sqrt(2.0/3.0), m[1].RMSE, 1e-12)
+	assert.InDelta(t, 2.0/3.0, m[1].MAE, 1e-12)
+	assert.True(t, math.IsNaN(m[1].R2))
+}
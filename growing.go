@@ -0,0 +1,196 @@
+package som
+
+import (
+	"context"
+
+	"github.com/mlange-42/som/layer"
+	"github.com/mlange-42/som/neighborhood"
+)
+
+// GrowingConfig holds the parameters for growing the map during training, following Fritzke's Growing Grid.
+//
+// Training starts with the size of the SOM's configuration. After every Epochs epochs,
+// a row or column is inserted next to the node with the highest accumulated quantization error,
+// towards its most distant direct neighbor. Growing stops when the quantization error (MSE) falls
+// below ErrorThreshold, or when an insertion would exceed MaxNodes.
+//
+// During growth, the learning rate is kept at the start value of the learning rate decay function,
+// and the neighborhood radius at the end value of the radius decay function.
+// After growth, the SOM is trained for the regular number of epochs.
+type GrowingConfig struct {
+	MaxNodes       int     // Maximum number of nodes
+	ErrorThreshold float64 // Quantization error (MSE) to stop growing at. Optional
+	Epochs         int     // Number of training epochs between insertions
+}
+
+// grow runs the growth phase of the Growing Grid.
+// Growth stops early when the context is cancelled.
+func (t *Trainer) grow(ctx context.Context) {
+	g := t.params.Growing
+	alpha := 0.0
+	if t.params.LearningRate != nil {
+		alpha = t.params.LearningRate.Decay(0, 1)
+	}
+	radius := t.params.NeighborhoodRadius.Decay(1, 1)
+
+	for ctx.Err() == nil {
+		for epoch := 0; epoch < g.Epochs; epoch++ {
+			if t.params.Algorithm == Batch {
+				t.batchEpoch(t.sampleRows(epoch), radius)
+			} else {
+				t.epoch(t.sampleRows(epoch), alpha, radius)
+			}
+		}
+
+		errors, qError := t.nodeErrors()
+		if qError < g.ErrorThreshold {
+			return
+		}
+		if !t.som.growAt(errors, g.MaxNodes) {
+			return
+		}
+	}
+}
+
+// nodeErrors returns the accumulated, weighted squared distance of the data to each node as BMU,
+// as well as the overall quantization error (MSE).
+func (t *Trainer) nodeErrors() ([]float64, float64) {
+	data := make([][]float64, len(t.tables))
+	rows := t.tables[0].Rows()
+
+	errors := make([]float64, t.som.size.Nodes())
+	qError := 0.0
+	sumWeights := 0.0
+	for i := 0; i < rows; i++ {
+		for j := 0; j < len(t.tables); j++ {
+			data[j] = t.tables[j].GetRow(i)
+		}
+		bmu, dist := t.som.GetBMU(data)
+		w := t.weight(i)
+		errors[bmu] += w * dist * dist
+		qError += w * dist * dist
+		sumWeights += w
+	}
+	return errors, qError / sumWeights
+}
+
+// growAt inserts a row or column next to the node with the highest error,
+// between that node and its direct neighbor with the largest distance in data space.
+// Returns false if the insertion would exceed maxNodes, or if the node has no neighbors.
+func (s *Som) growAt(errors []float64, maxNodes int) bool {
+	q := 0
+	for i, e := range errors {
+		if e > errors[q] {
+			q = i
+		}
+	}
+
+	xq, yq := s.size.Coords(q)
+	f, maxDist := -1, -1.0
+	var fx, fy int
+	for _, n := range s.topology.Neighbors(xq, yq) {
+		x, y := xq+n[0], yq+n[1]
+		if !s.wrap.X && (x < 0 || x >= s.size.Width) || !s.wrap.Y && (y < 0 || y >= s.size.Height) {
+			continue
+		}
+		x, y = wrapIndex(x, s.size.Width), wrapIndex(y, s.size.Height)
+		idx := s.size.Index(x, y)
+		if idx == q {
+			continue
+		}
+		if d := s.nodeDistance(q, idx); d > maxDist {
+			f, maxDist = idx, d
+			fx, fy = n[0], n[1]
+		}
+	}
+	if f < 0 {
+		return false
+	}
+
+	if fy == 0 {
+		if (s.size.Width+1)*s.size.Height > maxNodes {
+			return false
+		}
+		s.insertColumns(insertAfter(xq, fx, s.size.Width), 1)
+		return true
+	}
+
+	// Hexagonal maps grow by two rows, to preserve the offset of subsequent rows.
+	count := 1
+	if _, ok := s.topology.(*neighborhood.Hexagonal); ok {
+		count = 2
+	}
+	if s.size.Width*(s.size.Height+count) > maxNodes {
+		return false
+	}
+	s.insertRows(insertAfter(yq, fy, s.size.Height), count)
+	return true
+}
+
+// insertAfter returns the index after which to insert, given a coordinate and the offset towards the neighbor.
+func insertAfter(pos, offset, size int) int {
+	if offset > 0 {
+		return pos
+	}
+	return wrapIndex(pos-1, size)
+}
+
+// insertColumns inserts count columns after column after.
+// New nodes are interpolated linearly between the adjacent columns.
+// For after == width-1, the map must wrap in x direction, and nodes are interpolated
+// between the last and the first column.
+func (s *Som) insertColumns(after, count int) {
+	size := layer.Size{Width: s.size.Width + count, Height: s.size.Height}
+	s.resize(size, func(x, y int) (int, int, float64) {
+		a, b, frac := insertSource(x, after, count, s.size.Width)
+		return s.size.Index(a, y), s.size.Index(b, y), frac
+	})
+}
+
+// insertRows inserts count rows after row after.
+// New nodes are interpolated linearly between the adjacent rows.
+// For after == height-1, the map must wrap in y direction, and nodes are interpolated
+// between the last and the first row.
+func (s *Som) insertRows(after, count int) {
+	size := layer.Size{Width: s.size.Width, Height: s.size.Height + count}
+	s.resize(size, func(x, y int) (int, int, float64) {
+		a, b, frac := insertSource(y, after, count, s.size.Height)
+		return s.size.Index(x, a), s.size.Index(x, b), frac
+	})
+}
+
+// insertSource returns the coordinates of the two source nodes along an axis,
+// and the interpolation fraction, for a coordinate after insertion.
+func insertSource(pos, after, count, size int) (int, int, float64) {
+	if pos <= after {
+		return pos, pos, 0
+	}
+	if pos > after+count {
+		return pos - count, pos - count, 0
+	}
+	return after, (after + 1) % size, float64(pos-after) / float64(count+1)
+}
+
+// resize reallocates the weights of all layers for a new size.
+// For each new node, source returns the indices of two nodes of the current map,
+// and the fraction for linear interpolation between them.
+func (s *Som) resize(size layer.Size, source func(x, y int) (int, int, float64)) {
+	for _, lay := range s.layers {
+		cols := lay.Columns()
+		data := make([]float64, size.Nodes()*cols)
+		for x := 0; x < size.Width; x++ {
+			for y := 0; y < size.Height; y++ {
+				a, b, frac := source(x, y)
+				nodeA, nodeB := lay.GetNodeAt(a), lay.GetNodeAt(b)
+				idx := size.Index(x, y) * cols
+				for i := 0; i < cols; i++ {
+					data[idx+i] = nodeA[i] + frac*(nodeB[i]-nodeA[i])
+				}
+			}
+		}
+		if err := lay.Resize(size, data); err != nil {
+			panic(err)
+		}
+	}
+	s.size = size
+}
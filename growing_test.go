@@ -0,0 +1,122 @@
+package som
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/mlange-42/som/decay"
+	"github.com/mlange-42/som/distance"
+	"github.com/mlange-42/som/layer"
+	"github.com/mlange-42/som/neighborhood"
+	"github.com/mlange-42/som/norm"
+	"github.com/mlange-42/som/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func createGrowingSom(t *testing.T, size layer.Size, topology neighborhood.Topology, wrap neighborhood.Wrap) *Som {
+	params := SomConfig{
+		Size: size,
+		Layers: []*LayerDef{
+			{
+				Columns: []string{"x"},
+				Norm:    []norm.Normalizer{&norm.Identity{}},
+				Weight:  1.0,
+				Metric:  &distance.Euclidean{},
+			},
+		},
+		Neighborhood: &neighborhood.Gaussian{},
+		MapMetric:    &neighborhood.EuclideanMetric{},
+		Topology:     topology,
+		Wrap:         wrap,
+	}
+	s, err := New(&params)
+	assert.NoError(t, err)
+	return s
+}
+
+func TestSomInsert(t *testing.T) {
+	t.Run("Columns", func(t *testing.T) {
+		s := createGrowingSom(t, layer.Size{Width: 2, Height: 2}, nil, neighborhood.Wrap{})
+		copy(s.layers[0].Weights(), []float64{0, 1, 4, 5})
+
+		s.insertColumns(0, 1)
+		assert.Equal(t, layer.Size{Width: 3, Height: 2}, *s.Size())
+		assert.Equal(t, []float64{0, 1, 2, 3, 4, 5}, s.layers[0].Weights())
+	})
+
+	t.Run("Rows", func(t *testing.T) {
+		s := createGrowingSom(t, layer.Size{Width: 2, Height: 2}, nil, neighborhood.Wrap{})
+		copy(s.layers[0].Weights(), []float64{0, 3, 10, 13})
+
+		s.insertRows(0, 2)
+		assert.Equal(t, layer.Size{Width: 2, Height: 4}, *s.Size())
+		assert.Equal(t, []float64{0, 1, 2, 3, 10, 11, 12, 13}, s.layers[0].Weights())
+	})
+
+	t.Run("Columns wrapped", func(t *testing.T) {
+		s := createGrowingSom(t, layer.Size{Width: 2, Height: 1}, nil, neighborhood.Wrap{X: true})
+		copy(s.layers[0].Weights(), []float64{0, 2})
+
+		s.insertColumns(1, 1)
+		assert.Equal(t, []float64{0, 2, 1}, s.layers[0].Weights())
+	})
+}
+
+func TestSomGrowAt(t *testing.T) {
+	s := createGrowingSom(t, layer.Size{Width: 2, Height: 2}, nil, neighborhood.Wrap{})
+	copy(s.layers[0].Weights(), []float64{0, 1, 4, 5})
+
+	// Node (0, 0) has the highest error, and node (1, 0) is its most distant neighbor.
+	assert.True(t, s.growAt([]float64{3, 1, 0, 0}, 6))
+	assert.Equal(t, layer.Size{Width: 3, Height: 2}, *s.Size())
+	assert.Equal(t, []float64{0, 1, 2, 3, 4, 5}, s.layers[0].Weights())
+
+	assert.False(t, s.growAt([]float64{3, 1, 0, 0, 0, 0}, 7))
+	assert.Equal(t, layer.Size{Width: 3, Height: 2}, *s.Size())
+
+	s = createGrowingSom(t, layer.Size{Width: 2, Height: 2}, &neighborhood.Hexagonal{}, neighborhood.Wrap{})
+	copy(s.layers[0].Weights(), []float64{0, 5, 0, 5})
+
+	// Hexagonal maps grow by two rows.
+	assert.True(t, s.growAt([]float64{1, 0, 0, 0}, 8))
+	assert.Equal(t, layer.Size{Width: 2, Height: 4}, *s.Size())
+}
+
+func TestTrainerGrow(t *testing.T) {
+	s := createGrowingSom(t, layer.Size{Width: 2, Height: 2}, nil, neighborhood.Wrap{})
+
+	rng := rand.New(rand.NewSource(0))
+	tab := table.New([]string{"x"}, 100)
+	for i := 0; i < tab.Rows(); i++ {
+		tab.Set(i, 0, rng.Float64()*10)
+	}
+
+	params := TrainingConfig{
+		Epochs:             5,
+		LearningRate:       &decay.Linear{Start: 0.5, End: 0.01},
+		NeighborhoodRadius: &decay.Linear{Start: 2, End: 0.7},
+		Growing: &GrowingConfig{
+			MaxNodes: 12,
+			Epochs:   2,
+		},
+	}
+
+	trainer, err := NewTrainer(s, []*table.Table{tab}, &params, rng)
+	assert.NoError(t, err)
+
+	progress := make(chan TrainingProgress)
+	go trainer.Train(progress)
+	epochs := 0
+	for range progress {
+		epochs++
+	}
+
+	assert.Equal(t, params.Epochs, epochs)
+	assert.LessOrEqual(t, s.Size().Nodes(), 12)
+	assert.Greater(t, s.Size().Nodes(), 8)
+	assert.Equal(t, s.Size().Nodes(), len(s.layers[0].Weights()))
+
+	params.Growing.MaxNodes = 2
+	_, err = NewTrainer(s, []*table.Table{tab}, &params, rng)
+	assert.Error(t, err)
+}
@@ -0,0 +1,417 @@
+package som
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+
+	"github.com/mlange-42/som/layer"
+	"github.com/mlange-42/som/table"
+)
+
+// HierarchyConfig holds the parameters for expanding a trained SOM into a Growing Hierarchical SOM (GHSOM).
+//
+// A node is expanded into a child map if its mean quantization error is larger than Tau times the
+// mean quantization error of the entire data, measured as the mean distance of all rows to the data's mean.
+type HierarchyConfig struct {
+	Size     layer.Size // Size of child maps
+	Tau      float64    // Fraction of the data's mean quantization error a node may have before it is expanded
+	MaxDepth int        // Maximum depth of child maps. The root map has depth 0
+	MinRows  int        // Minimum number of rows mapped to a node to expand it. Optional, defaults to the number of nodes of child maps
+}
+
+// Hierarchy is a tree of Self-Organizing Maps (SOMs).
+// Nodes of each map can be expanded into child maps, trained only on the data rows mapped to the node.
+type Hierarchy struct {
+	som      *Som
+	children map[int]*Hierarchy
+	rows     rowData
+}
+
+// rowData holds optional row weights and classes, for training child maps.
+type rowData struct {
+	weights    []float64
+	classNames []string
+	classes    []int
+}
+
+// selectRows returns the row data of the given rows.
+func (r *rowData) selectRows(rows []int) rowData {
+	sel := rowData{classNames: r.classNames}
+	if r.weights != nil {
+		sel.weights = make([]float64, len(rows))
+		for i, row := range rows {
+			sel.weights[i] = r.weights[row]
+		}
+	}
+	if r.classes != nil {
+		sel.classes = make([]int, len(rows))
+		for i, row := range rows {
+			sel.classes[i] = r.classes[row]
+		}
+	}
+	return sel
+}
+
+// hasWeight returns whether any of the rows has a non-zero weight.
+func (r *rowData) hasWeight() bool {
+	if r.weights == nil {
+		return true
+	}
+	return slices.ContainsFunc(r.weights, func(w float64) bool { return w > 0 })
+}
+
+// PathNode identifies a node on a level of a [Hierarchy].
+type PathNode struct {
+	Level int // Depth of the map, 0 for the root map
+	Node  int // Index of the node in the map
+}
+
+// NewHierarchy creates a new Hierarchy with the given SOM as root map, without any child maps.
+func NewHierarchy(som *Som) *Hierarchy {
+	return &Hierarchy{
+		som:      som,
+		children: map[int]*Hierarchy{},
+	}
+}
+
+// Som returns the map at the root of the Hierarchy.
+func (h *Hierarchy) Som() *Som {
+	return h.som
+}
+
+// Child returns the child map of the given node, and whether there is one.
+func (h *Hierarchy) Child(node int) (*Hierarchy, bool) {
+	c, ok := h.children[node]
+	return c, ok
+}
+
+// SetChild sets the child map of the given node.
+// Child maps must have the same layers as the parent map.
+func (h *Hierarchy) SetChild(node int, child *Hierarchy) error {
+	if node < 0 || node >= h.som.Size().Nodes() {
+		return fmt.Errorf("node index %d out of range for map with %d nodes", node, h.som.Size().Nodes())
+	}
+	if err := checkLayers(h.som, child.som); err != nil {
+		return err
+	}
+	h.children[node] = child
+	return nil
+}
+
+// ChildNodes returns the indices of all nodes that have a child map, in ascending order.
+func (h *Hierarchy) ChildNodes() []int {
+	nodes := make([]int, 0, len(h.children))
+	for n := range h.children {
+		nodes = append(nodes, n)
+	}
+	slices.Sort(nodes)
+	return nodes
+}
+
+// Depth returns the depth of the deepest child map. It is 0 for a Hierarchy without child maps.
+func (h *Hierarchy) Depth() int {
+	depth := 0
+	for _, c := range h.children {
+		depth = max(depth, c.Depth()+1)
+	}
+	return depth
+}
+
+// GetBMU finds the path of Best Matching Units (BMU) for the given input data,
+// from the root map down to the deepest child map the data is mapped to.
+// It returns the path, as well as the distance between the input data and the BMU of the deepest map.
+func (h *Hierarchy) GetBMU(data [][]float64) ([]PathNode, float64) {
+	var path []PathNode
+	current := h
+	for level := 0; ; level++ {
+		bmu, dist := current.som.GetBMU(data)
+		path = append(path, PathNode{Level: level, Node: bmu})
+		child, ok := current.children[bmu]
+		if !ok {
+			return path, dist
+		}
+		current = child
+	}
+}
+
+// getLeaf finds the deepest map and the BMU in it for the given input data.
+func (h *Hierarchy) getLeaf(data [][]float64) (*Som, int) {
+	current := h
+	for {
+		bmu, _ := current.som.GetBMU(data)
+		child, ok := current.children[bmu]
+		if !ok {
+			return current.som, bmu
+		}
+		current = child
+	}
+}
+
+// SetWeights sets weights for the data rows, used for training child maps in [Hierarchy.Expand].
+// See [Trainer.SetWeights]. A nil slice removes the weights.
+func (h *Hierarchy) SetWeights(weights []float64) {
+	h.rows.weights = weights
+}
+
+// SetClasses sets the class of each data row, for class balancing when training child maps in [Hierarchy.Expand].
+// See [Trainer.SetClasses]. Nil indices remove the classes.
+func (h *Hierarchy) SetClasses(names []string, indices []int) {
+	h.rows.classNames = names
+	h.rows.classes = indices
+}
+
+// Expand recursively expands nodes of the Hierarchy's leaf maps into child maps,
+// following the Growing Hierarchical SOM (GHSOM).
+// The root map is expected to be trained on the given tables already.
+//
+// Child maps are created from config, with the size taken from hParams, and trained with params.
+// If params contains a growing configuration, child maps are grown from that size.
+// Child maps are trained without validation data, so the validation plateau criterion
+// of the stopping configuration is not used for them.
+// Tables are assumed to be normalized.
+//
+// Row weights and classes (see [Hierarchy.SetWeights] and [Hierarchy.SetClasses]) are passed on
+// to the trainers of child maps, for the rows mapped to the respective node.
+// Nodes where all rows have a weight of zero are not expanded.
+func (h *Hierarchy) Expand(config *SomConfig, params *TrainingConfig, hParams *HierarchyConfig, tables []*table.Table, rng *rand.Rand) error {
+	if err := checkTables(h.som, tables); err != nil {
+		return err
+	}
+	if h.rows.weights != nil {
+		if err := checkWeights(h.rows.weights, tables[0].Rows()); err != nil {
+			return err
+		}
+	}
+	if h.rows.classes != nil && len(h.rows.classes) != tables[0].Rows() {
+		return fmt.Errorf("length of class indices (%d) does not match number of data rows (%d)", len(h.rows.classes), tables[0].Rows())
+	}
+	if hParams.Size.Nodes() == 0 {
+		return fmt.Errorf("size of child maps must not be zero")
+	}
+	minRows := hParams.MinRows
+	if minRows <= 0 {
+		minRows = hParams.Size.Nodes()
+	}
+
+	threshold := hParams.Tau * meanDistanceToCenter(h.som, tables)
+
+	childConfig := *config
+	childConfig.Size = hParams.Size
+	childConfig.Layers = make([]*LayerDef, len(config.Layers))
+	for i, l := range config.Layers {
+		lay := *l
+		lay.Weights = nil
+		childConfig.Layers[i] = &lay
+	}
+
+	// Child maps are always trained from scratch.
+	childParams := *params
+	childParams.Continue = false
+	// Child maps have no validation data, so they can't stop on a validation plateau.
+	if params.Stopping != nil && params.Stopping.Patience > 0 {
+		stopping := *params.Stopping
+		stopping.Patience = 0
+		childParams.Stopping = &stopping
+	}
+
+	return h.expand(&childConfig, &childParams, hParams, tables, &h.rows, rng, threshold, minRows, 0)
+}
+
+func (h *Hierarchy) expand(config *SomConfig, params *TrainingConfig, hParams *HierarchyConfig,
+	tables []*table.Table, data *rowData, rng *rand.Rand, threshold float64, minRows int, depth int) error {
+
+	if depth >= hParams.MaxDepth {
+		return nil
+	}
+
+	pred, err := NewPredictor(h.som, tables)
+	if err != nil {
+		return err
+	}
+	bmu, dist := pred.GetBMUWithDistance()
+
+	nodes := h.som.Size().Nodes()
+	rows := make([][]int, nodes)
+	sumDist := make([]float64, nodes)
+	for i, b := range bmu {
+		rows[b] = append(rows[b], i)
+		sumDist[b] += dist[i]
+	}
+
+	for node := 0; node < nodes; node++ {
+		child, ok := h.children[node]
+		if !ok {
+			if len(rows[node]) < minRows || sumDist[node]/float64(len(rows[node])) <= threshold {
+				continue
+			}
+		}
+
+		childData := data.selectRows(rows[node])
+		if !ok && !childData.hasWeight() {
+			continue
+		}
+		childTables := make([]*table.Table, len(tables))
+		for i, t := range tables {
+			if t != nil {
+				childTables[i] = t.SelectRows(rows[node])
+			}
+		}
+
+		if !ok {
+			s, err := trainSom(config, params, childTables, &childData, rng)
+			if err != nil {
+				return err
+			}
+			child = NewHierarchy(s)
+			h.children[node] = child
+		}
+
+		if err := child.expand(config, params, hParams, childTables, &childData, rng, threshold, minRows, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trainSom creates a new SOM from the given configuration and trains it, discarding progress information.
+// Row weights and classes are optional.
+func trainSom(config *SomConfig, params *TrainingConfig, tables []*table.Table, data *rowData, rng *rand.Rand) (*Som, error) {
+	s, err := New(config)
+	if err != nil {
+		return nil, err
+	}
+	trainer, err := NewTrainer(s, tables, params, rng)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil && data.weights != nil {
+		if err := trainer.SetWeights(data.weights); err != nil {
+			return nil, err
+		}
+	}
+	if data != nil && data.classes != nil {
+		if err := trainer.SetClasses(data.classNames, data.classes); err != nil {
+			return nil, err
+		}
+	}
+	progress := make(chan TrainingProgress)
+	go trainer.Train(progress)
+	for range progress {
+	}
+	return s, nil
+}
+
+// meanDistanceToCenter returns the mean distance of the data rows to the mean of the data,
+// using the layer metrics and weights of the SOM.
+func meanDistanceToCenter(s *Som, tables []*table.Table) float64 {
+	rows := tables[0].Rows()
+	center := columnMeans(tables)
+
+	sum := 0.0
+	for row := 0; row < rows; row++ {
+		for i, t := range tables {
+			if t == nil {
+				continue
+			}
+			lay := s.layers[i]
+			if lay.Weight() == 0 {
+				continue
+			}
+			sum += lay.Weight() * lay.Metric().Distance(center[i], t.GetRow(row))
+		}
+	}
+	return sum / float64(rows)
+}
+
+// checkLayers checks whether two SOMs have the same layers and columns.
+func checkLayers(s1, s2 *Som) error {
+	if len(s1.layers) != len(s2.layers) {
+		return fmt.Errorf("number of layers (%d) does not match number of layers in parent map (%d)", len(s2.layers), len(s1.layers))
+	}
+	for i, l := range s1.layers {
+		if l.Name() != s2.layers[i].Name() || !slices.Equal(l.ColumnNames(), s2.layers[i].ColumnNames()) {
+			return fmt.Errorf("layer %s does not match layer %s in parent map", s2.layers[i].Name(), l.Name())
+		}
+	}
+	return nil
+}
+
+// HierarchyPredictor makes predictions using a [Hierarchy] of SOMs.
+// For each data row, the BMU of the deepest map the row is mapped to is used.
+type HierarchyPredictor struct {
+	hierarchy *Hierarchy
+	predictor *Predictor
+}
+
+// NewHierarchyPredictor creates a new HierarchyPredictor with the given Hierarchy and tables.
+// Tables are assumed to be normalized.
+// An error is returned if the tables do not match the Hierarchy's root SOM.
+func NewHierarchyPredictor(hierarchy *Hierarchy, tables []*table.Table) (*HierarchyPredictor, error) {
+	pred, err := NewPredictor(hierarchy.som, tables)
+	if err != nil {
+		return nil, err
+	}
+	return &HierarchyPredictor{
+		hierarchy: hierarchy,
+		predictor: pred,
+	}, nil
+}
+
+// SetThreads sets the number of worker goroutines used for BMU search over data rows.
+// See [Predictor.SetThreads].
+func (p *HierarchyPredictor) SetThreads(threads int) {
+	p.predictor.SetThreads(threads)
+}
+
+// GetBMU returns the path of best matching units (BMUs) through the hierarchy for each row
+// in the associated tables, as well as the distance to the BMU of the deepest map.
+func (p *HierarchyPredictor) GetBMU() ([][]PathNode, []float64) {
+	rows := p.predictor.rows()
+	paths := make([][]PathNode, rows)
+	dist := make([]float64, rows)
+
+	p.predictor.forEachRow(rows, func(i int, data [][]float64) {
+		paths[i], dist[i] = p.hierarchy.GetBMU(data)
+	})
+
+	return paths, dist
+}
+
+// GetBMUTable returns a table with the paths of best matching units (BMUs) through the hierarchy
+// for each row in the associated tables. The table contains the following columns:
+//
+// - level_<L>: the index of the BMU node in the map at depth L, for each level of the hierarchy.
+// NaN if the row is not mapped to a map at that depth.
+// - node_dist: the distance between the input data and the BMU node of the deepest map
+func (p *HierarchyPredictor) GetBMUTable() *table.Table {
+	paths, dist := p.GetBMU()
+	levels := p.hierarchy.Depth() + 1
+
+	columns := make([]string, levels+1)
+	for i := 0; i < levels; i++ {
+		columns[i] = fmt.Sprintf("level_%d", i)
+	}
+	columns[levels] = "node_dist"
+
+	t := table.New(columns, len(paths))
+	for i, path := range paths {
+		for l := 0; l < levels; l++ {
+			if l < len(path) {
+				t.Set(i, l, float64(path[l].Node))
+			} else {
+				t.Set(i, l, math.NaN())
+			}
+		}
+		t.Set(i, levels, dist[i])
+	}
+	return t
+}
+
+// Predict generates predictions for the specified layers in the input tables,
+// using the BMU of the deepest map each row is mapped to.
+// See [Predictor.Predict] for details.
+func (p *HierarchyPredictor) Predict(tables []*table.Table, layers []string) error {
+	return p.predictor.predict(tables, layers, p.hierarchy.getLeaf)
+}
@@ -0,0 +1,211 @@
+package som
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/mlange-42/som/decay"
+	"github.com/mlange-42/som/distance"
+	"github.com/mlange-42/som/layer"
+	"github.com/mlange-42/som/neighborhood"
+	"github.com/mlange-42/som/norm"
+	"github.com/mlange-42/som/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func createHierarchyTable(rng *rand.Rand, rows int) *table.Table {
+	tab := table.New([]string{"x"}, rows)
+	for i := 0; i < rows; i++ {
+		// Two well-separated clusters.
+		v := rng.Float64()
+		if i%2 == 0 {
+			v += 10
+		}
+		tab.Set(i, 0, v)
+	}
+	return tab
+}
+
+func TestHierarchySetChild(t *testing.T) {
+	root := createGrowingSom(t, layer.Size{Width: 2, Height: 1}, nil, neighborhood.Wrap{})
+	child := createGrowingSom(t, layer.Size{Width: 2, Height: 2}, nil, neighborhood.Wrap{})
+
+	h := NewHierarchy(root)
+	assert.Equal(t, 0, h.Depth())
+
+	assert.NoError(t, h.SetChild(1, NewHierarchy(child)))
+	assert.Error(t, h.SetChild(2, NewHierarchy(child)))
+
+	other := createSom()
+	assert.Error(t, h.SetChild(0, NewHierarchy(other)))
+
+	assert.Equal(t, []int{1}, h.ChildNodes())
+	assert.Equal(t, 1, h.Depth())
+
+	c, ok := h.Child(1)
+	assert.True(t, ok)
+	assert.Equal(t, child, c.Som())
+	_, ok = h.Child(0)
+	assert.False(t, ok)
+}
+
+func TestHierarchyGetBMU(t *testing.T) {
+	root := createGrowingSom(t, layer.Size{Width: 2, Height: 1}, nil, neighborhood.Wrap{})
+	copy(root.layers[0].Weights(), []float64{0, 10})
+	child := createGrowingSom(t, layer.Size{Width: 3, Height: 1}, nil, neighborhood.Wrap{})
+	copy(child.layers[0].Weights(), []float64{9, 10, 11})
+
+	h := NewHierarchy(root)
+	assert.NoError(t, h.SetChild(1, NewHierarchy(child)))
+
+	path, dist := h.GetBMU([][]float64{{0.5}})
+	assert.Equal(t, []PathNode{{Level: 0, Node: 0}}, path)
+	assert.Equal(t, 0.5, dist)
+
+	path, dist = h.GetBMU([][]float64{{11.25}})
+	assert.Equal(t, []PathNode{{Level: 0, Node: 1}, {Level: 1, Node: 2}}, path)
+	assert.Equal(t, 0.25, dist)
+
+	tab, err := table.NewWithData([]string{"x"}, []float64{0.5, 11.25})
+	assert.NoError(t, err)
+
+	pred, err := NewHierarchyPredictor(h, []*table.Table{tab})
+	assert.NoError(t, err)
+
+	bmu := pred.GetBMUTable()
+	assert.Equal(t, []string{"level_0", "level_1", "node_dist"}, bmu.ColumnNames())
+	assert.Equal(t, 0.0, bmu.Get(0, 0))
+	assert.True(t, math.IsNaN(bmu.Get(0, 1)))
+	assert.Equal(t, []float64{1, 2, 0.25}, bmu.GetRow(1))
+
+	t.Run("Ignore", func(t *testing.T) {
+		createSom := func(size layer.Size, weights []float64) *Som {
+			s, err := New(&SomConfig{
+				Size: size,
+				Layers: []*LayerDef{
+					{Name: "a", Columns: []string{"a"}, Norm: []norm.Normalizer{&norm.Identity{}}},
+					{Name: "x", Columns: []string{"x"}, Norm: []norm.Normalizer{&norm.Identity{}}},
+				},
+				Neighborhood: &neighborhood.Gaussian{},
+				MapMetric:    &neighborhood.EuclideanMetric{},
+			})
+			assert.NoError(t, err)
+			copy(s.layers[1].Weights(), weights)
+			return s
+		}
+		h := NewHierarchy(createSom(layer.Size{Width: 2, Height: 1}, []float64{0, 10}))
+		assert.NoError(t, h.SetChild(1, NewHierarchy(createSom(layer.Size{Width: 3, Height: 1}, []float64{9, 10, 11}))))
+
+		pred, err := NewHierarchyPredictor(h, []*table.Table{nil, tab})
+		assert.NoError(t, err)
+
+		paths, dist := pred.GetBMU()
+		assert.Equal(t, [][]PathNode{{{Level: 0, Node: 0}}, {{Level: 0, Node: 1}, {Level: 1, Node: 2}}}, paths)
+		assert.Equal(t, []float64{0.5, 0.25}, dist)
+	})
+}
+
+func TestHierarchyExpand(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	tab := createHierarchyTable(rng, 200)
+	tables := []*table.Table{tab}
+
+	root := createGrowingSom(t, layer.Size{Width: 2, Height: 1}, nil, neighborhood.Wrap{})
+	params := TrainingConfig{
+		Epochs:             20,
+		LearningRate:       &decay.Linear{Start: 0.5, End: 0.01},
+		NeighborhoodRadius: &decay.Linear{Start: 1, End: 0.5},
+	}
+	_, err := trainSom(&SomConfig{}, &params, tables, nil, rng)
+	assert.Error(t, err)
+
+	trainer, err := NewTrainer(root, tables, &params, rng)
+	assert.NoError(t, err)
+	progress := make(chan TrainingProgress)
+	go trainer.Train(progress)
+	for range progress {
+	}
+
+	config := SomConfig{
+		Layers: []*LayerDef{
+			{
+				Columns: []string{"x"},
+				Norm:    []norm.Normalizer{&norm.Identity{}},
+				Weight:  1.0,
+				Metric:  &distance.Euclidean{},
+			},
+		},
+		Neighborhood: &neighborhood.Gaussian{},
+		MapMetric:    &neighborhood.EuclideanMetric{},
+	}
+
+	h := NewHierarchy(root)
+	hParams := HierarchyConfig{
+		Size:     layer.Size{Width: 2, Height: 1},
+		Tau:      0.001,
+		MaxDepth: 2,
+	}
+	err = h.Expand(&config, &params, &hParams, tables, rng)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []int{0, 1}, h.ChildNodes())
+	assert.Equal(t, 2, h.Depth())
+	for _, node := range h.ChildNodes() {
+		c, _ := h.Child(node)
+		assert.Equal(t, hParams.Size, *c.Som().Size())
+	}
+
+	pred, err := NewHierarchyPredictor(h, tables)
+	assert.NoError(t, err)
+	paths, dist := pred.GetBMU()
+	assert.Len(t, paths, tab.Rows())
+	assert.Len(t, dist, tab.Rows())
+	for _, p := range paths {
+		assert.Len(t, p, 3)
+	}
+
+	h = NewHierarchy(root)
+	hParams.Tau = 1000
+	err = h.Expand(&config, &params, &hParams, tables, rng)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, h.Depth())
+
+	hParams.Size = layer.Size{}
+	err = h.Expand(&config, &params, &hParams, tables, rng)
+	assert.Error(t, err)
+
+	t.Run("weights and classes", func(t *testing.T) {
+		hParams := HierarchyConfig{
+			Size:     layer.Size{Width: 2, Height: 1},
+			Tau:      0.001,
+			MaxDepth: 1,
+		}
+		bmu := (&Predictor{som: root, tables: tables, threads: 1}).GetBMU()
+		weights := make([]float64, tab.Rows())
+		classes := make([]int, tab.Rows())
+		for i, b := range bmu {
+			if b == 1 {
+				weights[i] = 1
+			}
+			classes[i] = i % 2
+		}
+
+		h := NewHierarchy(root)
+		h.SetWeights(weights)
+		h.SetClasses([]string{"a", "b"}, classes)
+		p := params
+		p.Balance = &BalanceConfig{Mode: Balanced}
+		err := h.Expand(&config, &p, &hParams, tables, rng)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1}, h.ChildNodes())
+
+		h = NewHierarchy(root)
+		h.SetWeights(weights[1:])
+		assert.Error(t, h.Expand(&config, &params, &hParams, tables, rng))
+
+		h = NewHierarchy(root)
+		h.SetClasses([]string{"a", "b"}, classes[1:])
+		assert.Error(t, h.Expand(&config, &params, &hParams, tables, rng))
+	})
+}
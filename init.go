@@ -0,0 +1,329 @@
+package som
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+
+	"github.com/mlange-42/som/table"
+)
+
+// Initialization is the strategy used for initializing the weights of a Self-Organizing Map (SOM) before training.
+type Initialization uint8
+
+const (
+	InitRandom    Initialization = iota // Uniform random weights in [0, 0.25]
+	InitPCA                             // Linear initialization spanning the first two principal components of the data
+	InitSamples                         // Weights copied from random data rows
+	InitDataRange                       // Uniform random weights within each column's range in the data
+)
+
+var initializations = map[string]Initialization{
+	"random":     InitRandom,
+	"pca":        InitPCA,
+	"samples":    InitSamples,
+	"data-range": InitDataRange,
+}
+
+// GetInitialization returns the initialization strategy with the given name.
+// Options are random, pca, samples and data-range.
+func GetInitialization(name string) (Initialization, bool) {
+	i, ok := initializations[name]
+	return i, ok
+}
+
+// String returns the name of the initialization strategy.
+func (i Initialization) String() string {
+	for name, in := range initializations {
+		if in == i {
+			return name
+		}
+	}
+	return "random"
+}
+
+// initialize initializes the weights of the SOM using the given strategy and data.
+// Tables are assumed to be normalized. Layers without a table are initialized randomly.
+func (s *Som) initialize(initialization Initialization, tables []*table.Table, rng *rand.Rand) {
+	s.Randomize(rng)
+
+	switch initialization {
+	case InitPCA:
+		s.initPCA(tables)
+	case InitSamples:
+		s.initSamples(tables, rng)
+	case InitDataRange:
+		s.initDataRange(tables, rng)
+	}
+}
+
+// initSamples sets each node to a randomly selected data row.
+// Missing values are replaced by the column mean.
+func (s *Som) initSamples(tables []*table.Table, rng *rand.Rand) {
+	rows := tables[0].Rows()
+	if rows == 0 {
+		return
+	}
+	means := columnMeans(tables)
+	nodes := s.size.Nodes()
+	for node := 0; node < nodes; node++ {
+		row := rng.Intn(rows)
+		for i, t := range tables {
+			if t == nil {
+				continue
+			}
+			weights := s.layers[i].GetNodeAt(node)
+			for j, v := range t.GetRow(row) {
+				if math.IsNaN(v) {
+					v = means[i][j]
+				}
+				weights[j] = v
+			}
+		}
+	}
+}
+
+// initDataRange sets each weight to a uniform random value within the range of its column in the data.
+func (s *Som) initDataRange(tables []*table.Table, rng *rand.Rand) {
+	for i, t := range tables {
+		if t == nil {
+			continue
+		}
+		cols := t.Columns()
+		mins, maxs := make([]float64, cols), make([]float64, cols)
+		for j := 0; j < cols; j++ {
+			mins[j], maxs[j] = t.Range(j)
+			if math.IsInf(mins[j], 0) {
+				mins[j], maxs[j] = 0, 0
+			}
+		}
+		weights := s.layers[i].Weights()
+		for k := range weights {
+			j := k % cols
+			weights[k] = mins[j] + rng.Float64()*(maxs[j]-mins[j])
+		}
+	}
+}
+
+// initPCA initializes the SOM linearly, spanning the map plane along the first two principal components
+// of the data, centered at the data mean. The first component is aligned with the longer map axis.
+// Node positions are scaled to [-1, 1] along each axis, multiplied by the standard deviation along
+// the respective component.
+func (s *Som) initPCA(tables []*table.Table) {
+	means := columnMeans(tables)
+	center := slices.Concat(means...)
+	dims := len(center)
+	if dims == 0 {
+		return
+	}
+
+	cov := covariance(tables, center)
+	values, vectors := symmetricEigen(cov)
+
+	order := make([]int, dims)
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		switch {
+		case values[a] > values[b]:
+			return -1
+		case values[a] < values[b]:
+			return 1
+		}
+		return 0
+	})
+
+	components := [2][]float64{make([]float64, dims), make([]float64, dims)}
+	for c := 0; c < 2 && c < dims; c++ {
+		idx := order[c]
+		std := math.Sqrt(math.Max(values[idx], 0))
+		// Fix the sign of the eigenvector for reproducible results.
+		sign, largest := 1.0, 0.0
+		for k := 0; k < dims; k++ {
+			if v := vectors[k][idx]; math.Abs(v) > math.Abs(largest) {
+				largest = v
+			}
+		}
+		if largest < 0 {
+			sign = -1
+		}
+		for k := 0; k < dims; k++ {
+			components[c][k] = sign * std * vectors[k][idx]
+		}
+	}
+
+	posX, posY := s.normalizedPositions()
+
+	nodes := s.size.Nodes()
+	for node := 0; node < nodes; node++ {
+		offset := 0
+		for i, t := range tables {
+			if t == nil {
+				continue
+			}
+			weights := s.layers[i].GetNodeAt(node)
+			for j := range weights {
+				k := offset + j
+				weights[j] = center[k] + posX[node]*components[0][k] + posY[node]*components[1][k]
+			}
+			offset += len(weights)
+		}
+	}
+}
+
+// normalizedPositions returns the positions of all nodes in map space, scaled to [-1, 1] along each axis.
+// The first returned axis is the longer axis of the map.
+func (s *Som) normalizedPositions() ([]float64, []float64) {
+	nodes := s.size.Nodes()
+	posX, posY := make([]float64, nodes), make([]float64, nodes)
+	for node := 0; node < nodes; node++ {
+		x, y := s.size.Coords(node)
+		posX[node], posY[node] = s.topology.Position(x, y)
+	}
+	if slices.Max(posY)-slices.Min(posY) > slices.Max(posX)-slices.Min(posX) {
+		posX, posY = posY, posX
+	}
+	scaleToUnit(posX)
+	scaleToUnit(posY)
+	return posX, posY
+}
+
+// scaleToUnit linearly scales values to [-1, 1]. If all values are equal, they are set to 0.
+func scaleToUnit(values []float64) {
+	lo, hi := slices.Min(values), slices.Max(values)
+	for i, v := range values {
+		if hi == lo {
+			values[i] = 0
+			continue
+		}
+		values[i] = 2*(v-lo)/(hi-lo) - 1
+	}
+}
+
+// columnMeans returns the mean of each column of the tables, ignoring missing values.
+// Columns without any values get a mean of 0. Nil tables result in nil entries.
+func columnMeans(tables []*table.Table) [][]float64 {
+	means := make([][]float64, len(tables))
+	for i, t := range tables {
+		if t == nil {
+			continue
+		}
+		means[i] = make([]float64, t.Columns())
+		for j := range means[i] {
+			means[i][j], _ = t.MeanStdDev(j)
+			if math.IsNaN(means[i][j]) {
+				means[i][j] = 0
+			}
+		}
+	}
+	return means
+}
+
+// covariance calculates the covariance matrix of all columns of the tables,
+// with the given column means. Missing values are ignored pairwise.
+func covariance(tables []*table.Table, center []float64) [][]float64 {
+	dims := len(center)
+	cov := make([][]float64, dims)
+	counts := make([][]int, dims)
+	for i := range cov {
+		cov[i] = make([]float64, dims)
+		counts[i] = make([]int, dims)
+	}
+
+	rows := tables[0].Rows()
+	row := make([]float64, 0, dims)
+	for r := 0; r < rows; r++ {
+		row = row[:0]
+		for _, t := range tables {
+			if t != nil {
+				row = append(row, t.GetRow(r)...)
+			}
+		}
+		for a := 0; a < dims; a++ {
+			if math.IsNaN(row[a]) {
+				continue
+			}
+			for b := a; b < dims; b++ {
+				if math.IsNaN(row[b]) {
+					continue
+				}
+				cov[a][b] += (row[a] - center[a]) * (row[b] - center[b])
+				counts[a][b]++
+			}
+		}
+	}
+
+	for a := 0; a < dims; a++ {
+		for b := a; b < dims; b++ {
+			if counts[a][b] > 1 {
+				cov[a][b] /= float64(counts[a][b] - 1)
+			} else {
+				cov[a][b] = 0
+			}
+			cov[b][a] = cov[a][b]
+		}
+	}
+	return cov
+}
+
+// symmetricEigen calculates the eigenvalues and eigenvectors of a symmetric matrix,
+// using the cyclic Jacobi eigenvalue algorithm. Eigenvectors are returned as columns.
+// The input matrix is modified.
+func symmetricEigen(a [][]float64) ([]float64, [][]float64) {
+	n := len(a)
+	v := make([][]float64, n)
+	for i := range v {
+		v[i] = make([]float64, n)
+		v[i][i] = 1
+	}
+
+	for sweep := 0; sweep < 100; sweep++ {
+		off := 0.0
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				off += a[p][q] * a[p][q]
+			}
+		}
+		if off < 1e-22 {
+			break
+		}
+
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if a[p][q] == 0 {
+					continue
+				}
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+
+				for k := 0; k < n; k++ {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p] = c*akp - s*akq
+					a[k][q] = s*akp + c*akq
+				}
+				for k := 0; k < n; k++ {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k] = c*apk - s*aqk
+					a[q][k] = s*apk + c*aqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p] = c*vkp - s*vkq
+					v[k][q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = a[i][i]
+	}
+	return values, v
+}
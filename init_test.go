@@ -0,0 +1,97 @@
+package som
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/mlange-42/som/layer"
+	"github.com/mlange-42/som/neighborhood"
+	"github.com/mlange-42/som/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetInitialization(t *testing.T) {
+	for _, name := range []string{"random", "pca", "samples", "data-range"} {
+		init, ok := GetInitialization(name)
+		assert.True(t, ok)
+		assert.Equal(t, name, init.String())
+	}
+	_, ok := GetInitialization("foo")
+	assert.False(t, ok)
+}
+
+func TestSymmetricEigen(t *testing.T) {
+	values, vectors := symmetricEigen([][]float64{
+		{2, 1},
+		{1, 2},
+	})
+	assert.InDeltaSlice(t, []float64{1, 3}, values, 1e-9)
+
+	s := 1 / math.Sqrt(2)
+	assert.InDelta(t, s, math.Abs(vectors[0][1]), 1e-9)
+	assert.InDelta(t, vectors[0][1], vectors[1][1], 1e-9)
+	assert.InDelta(t, -vectors[0][0], vectors[1][0], 1e-9)
+}
+
+func TestSomInitialize(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	tab := table.New([]string{"x"}, 100)
+	for i := 0; i < tab.Rows(); i++ {
+		tab.Set(i, 0, 10+float64(i%11))
+	}
+	tab.Set(3, 0, math.NaN())
+	tables := []*table.Table{tab}
+
+	t.Run("Samples", func(t *testing.T) {
+		s := createGrowingSom(t, layer.Size{Width: 4, Height: 3}, nil, neighborhood.Wrap{})
+		s.initialize(InitSamples, tables, rng)
+		for _, w := range s.layers[0].Weights() {
+			assert.GreaterOrEqual(t, w, 10.0)
+			assert.LessOrEqual(t, w, 20.0)
+			assert.Equal(t, math.Round(w), w)
+		}
+	})
+
+	t.Run("DataRange", func(t *testing.T) {
+		s := createGrowingSom(t, layer.Size{Width: 4, Height: 3}, nil, neighborhood.Wrap{})
+		s.initialize(InitDataRange, tables, rng)
+		for _, w := range s.layers[0].Weights() {
+			assert.GreaterOrEqual(t, w, 10.0)
+			assert.LessOrEqual(t, w, 20.0)
+		}
+	})
+
+	t.Run("PCA", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(1))
+		tab := table.New([]string{"x", "y"}, 200)
+		for i := 0; i < tab.Rows(); i++ {
+			v := rng.NormFloat64()
+			tab.Set(i, 0, 5+v)
+			tab.Set(i, 1, 5-v+0.01*rng.NormFloat64())
+		}
+
+		s := createSom()
+		s.layers = s.layers[:1]
+		s.initialize(InitPCA, []*table.Table{tab}, rng)
+		w1 := append([]float64{}, s.layers[0].Weights()...)
+
+		s.initialize(InitPCA, []*table.Table{tab}, rand.New(rand.NewSource(2)))
+		assert.Equal(t, w1, s.layers[0].Weights())
+
+		// Nodes lie along the main diagonal of the data, centered at the mean.
+		lay := s.layers[0]
+		sumX, sumY := 0.0, 0.0
+		for i := 0; i < s.Size().Nodes(); i++ {
+			node := lay.GetNodeAt(i)
+			assert.InDelta(t, 10, node[0]+node[1], 0.1)
+			sumX += node[0]
+			sumY += node[1]
+		}
+		meanX, _ := tab.MeanStdDev(0)
+		assert.InDelta(t, meanX, sumX/float64(s.Size().Nodes()), 1e-9)
+
+		first, last := lay.GetNodeAt(0), lay.GetNodeAt(s.Size().Nodes()-1)
+		assert.Greater(t, math.Abs(first[0]-last[0]), 1.0)
+	})
+}
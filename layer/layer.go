@@ -137,6 +137,17 @@ func (l *Layer) Normalizers() []norm.Normalizer {
 	return l.norm
 }
 
+// Resize replaces the layer's size and weight values.
+// The length of data must match the new size.
+func (l *Layer) Resize(size Size, data []float64) error {
+	if len(data) != size.Width*size.Height*len(l.columns) {
+		return fmt.Errorf("data length (%d) does not match layer size (%d)", len(data), size.Width*size.Height*len(l.columns))
+	}
+	l.size = size
+	l.weights = data
+	return nil
+}
+
 // Get returns the value at the specified column and coordinate in the Layer.
 func (l *Layer) Get(x, y, col int) float64 {
 	return l.weights[l.index(x, y, col)]
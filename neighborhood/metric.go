@@ -25,10 +25,11 @@ func GetMetric(name string) (Metric, bool) {
 
 // Metric is an interface that defines a distance metric in map space, i.e. between SOM nodes.
 // The Name method returns the name of the metric.
-// The Distance method calculates the distance between two points (x1, y1) and (x2, y2).
+// The Distance method calculates the distance between two points (x1, y1) and (x2, y2) in map space,
+// as given by [Topology.Position].
 type Metric interface {
 	Name() string
-	Distance(x1, y1, x2, y2 int) float64
+	Distance(x1, y1, x2, y2 float64) float64
 }
 
 // EuclideanMetric implements [Metric] for the Euclidean distance.
@@ -38,9 +39,9 @@ func (e *EuclideanMetric) Name() string {
 	return "euclidean"
 }
 
-func (e *EuclideanMetric) Distance(x1, y1, x2, y2 int) float64 {
-	dx := float64(x1 - x2)
-	dy := float64(y1 - y2)
+func (e *EuclideanMetric) Distance(x1, y1, x2, y2 float64) float64 {
+	dx := x1 - x2
+	dy := y1 - y2
 	return math.Sqrt(dx*dx + dy*dy)
 }
 
@@ -51,9 +52,9 @@ func (m *ManhattanMetric) Name() string {
 	return "manhattan"
 }
 
-func (m *ManhattanMetric) Distance(x1, y1, x2, y2 int) float64 {
-	dx := float64(x1 - x2)
-	dy := float64(y1 - y2)
+func (m *ManhattanMetric) Distance(x1, y1, x2, y2 float64) float64 {
+	dx := x1 - x2
+	dy := y1 - y2
 	return math.Abs(dx) + math.Abs(dy)
 }
 
@@ -64,8 +65,8 @@ func (c *ChebyshevMetric) Name() string {
 	return "chebyshev"
 }
 
-func (c *ChebyshevMetric) Distance(x1, y1, x2, y2 int) float64 {
-	dx := float64(x1 - x2)
-	dy := float64(y1 - y2)
+func (c *ChebyshevMetric) Distance(x1, y1, x2, y2 float64) float64 {
+	dx := x1 - x2
+	dy := y1 - y2
 	return math.Max(math.Abs(dx), math.Abs(dy))
 }
@@ -0,0 +1,103 @@
+package neighborhood
+
+import "math"
+
+var topologies = map[string]Topology{}
+
+func init() {
+	t := []Topology{
+		&Rectangular{},
+		&Hexagonal{},
+	}
+	for _, v := range t {
+		if _, ok := topologies[v.Name()]; ok {
+			panic("duplicate topology name: " + v.Name())
+		}
+		topologies[v.Name()] = v
+	}
+}
+
+func GetTopology(name string) (Topology, bool) {
+	t, ok := topologies[name]
+	return t, ok
+}
+
+// Topology is an interface that defines the arrangement of nodes in map space.
+// The Name method returns the name of the topology.
+// The Position method returns the position of the node at grid coordinates (x, y) in map space.
+// The Neighbors method returns the grid offsets (dx, dy) of the direct neighbors of the node at (x, y).
+// The Adjacent method reports whether two nodes are considered neighbors for the given metric.
+// The Extent method returns the number of columns and rows to each side of a node that cover the given map-space radius.
+type Topology interface {
+	Name() string
+	Position(x, y int) (float64, float64)
+	Neighbors(x, y int) [][2]int
+	Adjacent(metric Metric, x1, y1, x2, y2 int) bool
+	Extent(radius int) (int, int)
+}
+
+var rectangularNeighbors = [][2]int{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+
+// Rectangular implements [Topology] for a grid of square cells.
+type Rectangular struct{}
+
+func (r *Rectangular) Name() string {
+	return "rectangular"
+}
+
+func (r *Rectangular) Position(x, y int) (float64, float64) {
+	return float64(x), float64(y)
+}
+
+func (r *Rectangular) Neighbors(x, y int) [][2]int {
+	return rectangularNeighbors
+}
+
+// Adjacent reports whether the nodes are not further apart than 1 under the given metric.
+// This allows to select between 4-neighborhoods (Manhattan) and 8-neighborhoods (Chebyshev).
+func (r *Rectangular) Adjacent(metric Metric, x1, y1, x2, y2 int) bool {
+	return metric.Distance(float64(x1), float64(y1), float64(x2), float64(y2)) <= 1
+}
+
+func (r *Rectangular) Extent(radius int) (int, int) {
+	return radius, radius
+}
+
+// rowHeight is the distance between rows of a hexagonal grid.
+var rowHeight = math.Sqrt(3) / 2
+
+var hexagonalNeighbors = [2][][2]int{
+	{{1, 0}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}}, // even rows
+	{{1, 0}, {1, 1}, {0, 1}, {-1, 0}, {0, -1}, {1, -1}},   // odd rows
+}
+
+// Hexagonal implements [Topology] for a grid of hexagonal cells.
+// Odd rows are shifted by half a cell to the right.
+// All six neighbors of a node are at a Euclidean distance of 1.
+type Hexagonal struct{}
+
+func (h *Hexagonal) Name() string {
+	return "hexagonal"
+}
+
+func (h *Hexagonal) Position(x, y int) (float64, float64) {
+	return float64(x) + 0.5*float64(y&1), float64(y) * rowHeight
+}
+
+func (h *Hexagonal) Neighbors(x, y int) [][2]int {
+	return hexagonalNeighbors[y&1]
+}
+
+// Adjacent reports whether the nodes share a cell edge. The metric is ignored.
+func (h *Hexagonal) Adjacent(metric Metric, x1, y1, x2, y2 int) bool {
+	for _, n := range h.Neighbors(x1, y1) {
+		if x1+n[0] == x2 && y1+n[1] == y2 {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Hexagonal) Extent(radius int) (int, int) {
+	return radius + 1, int(math.Ceil(float64(radius) / rowHeight))
+}
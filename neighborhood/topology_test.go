@@ -0,0 +1,57 @@
+package neighborhood_test
+
+import (
+	"testing"
+
+	"github.com/mlange-42/som/neighborhood"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTopology(t *testing.T) {
+	topo, ok := neighborhood.GetTopology("hexagonal")
+	assert.True(t, ok)
+	assert.IsType(t, &neighborhood.Hexagonal{}, topo)
+
+	_, ok = neighborhood.GetTopology("unknown")
+	assert.False(t, ok)
+}
+
+func TestRectangular(t *testing.T) {
+	topo := &neighborhood.Rectangular{}
+	metric := &neighborhood.ManhattanMetric{}
+
+	x, y := topo.Position(2, 3)
+	assert.Equal(t, 2.0, x)
+	assert.Equal(t, 3.0, y)
+
+	assert.Len(t, topo.Neighbors(2, 3), 4)
+	assert.True(t, topo.Adjacent(metric, 2, 3, 2, 4))
+	assert.False(t, topo.Adjacent(metric, 2, 3, 3, 4))
+	assert.True(t, topo.Adjacent(&neighborhood.ChebyshevMetric{}, 2, 3, 3, 4))
+}
+
+func TestHexagonal(t *testing.T) {
+	topo := &neighborhood.Hexagonal{}
+	metric := &neighborhood.EuclideanMetric{}
+
+	x, y := topo.Position(2, 1)
+	assert.Equal(t, 2.5, x)
+	assert.InDelta(t, 0.866, y, 0.001)
+
+	for _, yy := range []int{2, 3} {
+		neighbors := topo.Neighbors(2, yy)
+		assert.Len(t, neighbors, 6)
+		for _, n := range neighbors {
+			x2, y2 := 2+n[0], yy+n[1]
+			assert.True(t, topo.Adjacent(metric, 2, yy, x2, y2))
+			assert.True(t, topo.Adjacent(metric, x2, y2, 2, yy))
+
+			px1, py1 := topo.Position(2, yy)
+			px2, py2 := topo.Position(x2, y2)
+			assert.InDelta(t, 1.0, metric.Distance(px1, py1, px2, py2), 0.000001)
+		}
+	}
+
+	assert.False(t, topo.Adjacent(metric, 2, 2, 3, 3))
+	assert.False(t, topo.Adjacent(metric, 2, 2, 4, 2))
+}
@@ -0,0 +1,50 @@
+package neighborhood
+
+var wraps = map[string]Wrap{}
+
+func init() {
+	w := []Wrap{
+		{},
+		{X: true, Y: true},
+		{X: true},
+		{Y: true},
+	}
+	for _, v := range w {
+		if _, ok := wraps[v.Name()]; ok {
+			panic("duplicate wrap name: " + v.Name())
+		}
+		wraps[v.Name()] = v
+	}
+}
+
+func GetWrap(name string) (Wrap, bool) {
+	w, ok := wraps[name]
+	return w, ok
+}
+
+// Wrap defines the boundary conditions of the map, i.e. along which axes the map is periodic.
+// The zero value is a map with hard boundaries.
+type Wrap struct {
+	X bool // Whether the left and right edges of the map are connected
+	Y bool // Whether the top and bottom edges of the map are connected
+}
+
+// Name returns the name of the boundary conditions.
+// Options are none, torus, cylinder-x and cylinder-y.
+func (w Wrap) Name() string {
+	switch {
+	case w.X && w.Y:
+		return "torus"
+	case w.X:
+		return "cylinder-x"
+	case w.Y:
+		return "cylinder-y"
+	default:
+		return "none"
+	}
+}
+
+// Any returns whether the map wraps around along any axis.
+func (w Wrap) Any() bool {
+	return w.X || w.Y
+}
@@ -0,0 +1,24 @@
+package neighborhood_test
+
+import (
+	"testing"
+
+	"github.com/mlange-42/som/neighborhood"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetWrap(t *testing.T) {
+	for _, name := range []string{"none", "torus", "cylinder-x", "cylinder-y"} {
+		w, ok := neighborhood.GetWrap(name)
+		assert.True(t, ok)
+		assert.Equal(t, name, w.Name())
+	}
+
+	w, _ := neighborhood.GetWrap("torus")
+	assert.Equal(t, neighborhood.Wrap{X: true, Y: true}, w)
+	assert.True(t, w.Any())
+	assert.False(t, neighborhood.Wrap{}.Any())
+
+	_, ok := neighborhood.GetWrap("unknown")
+	assert.False(t, ok)
+}
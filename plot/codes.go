@@ -6,6 +6,7 @@ import (
 
 	"github.com/mlange-42/som"
 	"github.com/mlange-42/som/conv"
+	"github.com/mlange-42/som/neighborhood"
 	"github.com/mlange-42/som/norm"
 	"github.com/mlange-42/som/plot/plotter"
 	"gonum.org/v1/plot"
@@ -67,13 +68,28 @@ func Codes(s *som.Som, columns [][2]int,
 	legendHeight := (legendFontSize + 2) * int(math.Ceil(float64(len(thumbs))/float64(l.Columns)))
 	hPad, vPad := 2, 4
 
+	// For hexagonal maps, odd rows are shifted by half a code plot.
+	_, hexagonal := s.Topology().(*neighborhood.Hexagonal)
+	hexShift := hexagonal && h > 1
+
 	codeHeight := (size.Y - legendHeight) / s.Size().Height
 	codeWidth := size.X / s.Size().Width
+	if hexShift {
+		codeWidth = int(float64(size.X) / (float64(w) + 0.5))
+	}
+	totalWidth := w * codeWidth
+	if hexShift {
+		totalWidth += codeWidth / 2
+	}
 
 	for i, p := range plots {
 		x, y := s.Size().Coords(i)
+		left := x * codeWidth
+		if hexShift && y%2 == 1 {
+			left += codeWidth / 2
+		}
 		c := draw.Crop(dc,
-			font.Length(x*codeWidth+hPad), font.Length((x+1-w)*codeWidth-hPad),
+			font.Length(left+hPad), font.Length(left+codeWidth-totalWidth-hPad),
 			font.Length(y*codeHeight+legendHeight+vPad), font.Length((y+1-h)*codeHeight-vPad))
 
 		p.Draw(c)
@@ -92,7 +108,13 @@ func Codes(s *som.Som, columns [][2]int,
 
 		_, classIndices := conv.LayerToClasses(s.Layers()[boundariesLayer])
 		bounds := &IntGrid{Size: *s.Size(), Values: classIndices}
-		bound, err := plotter.NewGridBoundaries(bounds)
+		var bound plot.Plotter
+		var err error
+		if hexagonal {
+			bound, err = plotter.NewHexBoundaries(bounds)
+		} else {
+			bound, err = plotter.NewGridBoundaries(bounds)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -3,6 +3,7 @@ package plot
 import (
 	"github.com/mlange-42/som"
 	"github.com/mlange-42/som/layer"
+	"gonum.org/v1/plot/plotter"
 )
 
 type SomLayerGrid struct {
@@ -92,3 +93,61 @@ func (g *UMatrixGrid) X(c int) float64 {
 func (g *UMatrixGrid) Y(r int) float64 {
 	return float64(r) / 2
 }
+
+// UMatrixNodeGrid is a grid of the node cells of a U-Matrix,
+// i.e. the average distance of each node to its neighbors.
+type UMatrixNodeGrid struct {
+	UMatrix [][]float64
+}
+
+func (g *UMatrixNodeGrid) Dims() (c, r int) {
+	return (len(g.UMatrix[0]) + 1) / 2, (len(g.UMatrix) + 1) / 2
+}
+
+func (g *UMatrixNodeGrid) Z(c, r int) float64 {
+	return g.UMatrix[r*2][c*2]
+}
+
+func (g *UMatrixNodeGrid) X(c int) float64 {
+	return float64(c)
+}
+
+func (g *UMatrixNodeGrid) Y(r int) float64 {
+	return float64(r)
+}
+
+// TiledGrid repeats a grid along both axes.
+// Used to show periodic maps in a tiled view.
+type TiledGrid struct {
+	Grid   plotter.GridXYZ
+	TilesX int
+	TilesY int
+}
+
+func (g *TiledGrid) Dims() (c, r int) {
+	c, r = g.Grid.Dims()
+	return c * g.TilesX, r * g.TilesY
+}
+
+func (g *TiledGrid) Z(c, r int) float64 {
+	cols, rows := g.Grid.Dims()
+	return g.Grid.Z(c%cols, r%rows)
+}
+
+func (g *TiledGrid) X(c int) float64 {
+	cols, _ := g.Grid.Dims()
+	return g.Grid.X(c%cols) + float64(c/cols)*gridPeriod(cols, g.Grid.X)
+}
+
+func (g *TiledGrid) Y(r int) float64 {
+	_, rows := g.Grid.Dims()
+	return g.Grid.Y(r%rows) + float64(r/rows)*gridPeriod(rows, g.Grid.Y)
+}
+
+// gridPeriod returns the extent of n grid cells with positions given by pos.
+func gridPeriod(n int, pos func(int) float64) float64 {
+	if n == 1 {
+		return 1
+	}
+	return float64(n) * (pos(1) - pos(0))
+}
@@ -15,7 +15,7 @@ import (
 	"gonum.org/v1/plot/vg/vgimg"
 )
 
-func Heatmap(title string, g plotter.GridXYZ, boundaries plotter.GridXYZ, width, height int, categories []string, labels []string, positions []plotter.XY) (image.Image, error) {
+func Heatmap(title string, g plotter.GridXYZ, boundaries plotter.GridXYZ, hexagonal bool, width, height int, categories []string, labels []string, positions []plotter.XY) (image.Image, error) {
 	p := plot.New()
 	l := plot.NewLegend()
 	p.Title.TextStyle.Font.Size = 16
@@ -32,14 +32,29 @@ func Heatmap(title string, g plotter.GridXYZ, boundaries plotter.GridXYZ, width,
 	} else {
 		pal = palette.Rainbow(numColors, palette.Blue, palette.Red, 1, 1, 1)
 	}
-	h := plotter.NewHeatMap(g, pal)
 
 	p.Title.Text = title
 	p.HideAxes()
-	p.Add(h)
+
+	var zMin, zMax float64
+	if hexagonal {
+		h := som_plotter.NewHexHeatMap(g, pal)
+		zMin, zMax = h.Min, h.Max
+		p.Add(h)
+	} else {
+		h := plotter.NewHeatMap(g, pal)
+		zMin, zMax = h.Min, h.Max
+		p.Add(h)
+	}
 
 	if boundaries != nil {
-		bound, err := som_plotter.NewGridBoundaries(boundaries)
+		var bound plot.Plotter
+		var err error
+		if hexagonal {
+			bound, err = som_plotter.NewHexBoundaries(boundaries)
+		} else {
+			bound, err = som_plotter.NewGridBoundaries(boundaries)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -69,9 +84,9 @@ func Heatmap(title string, g plotter.GridXYZ, boundaries plotter.GridXYZ, width,
 		var val float64
 		switch i {
 		case 0:
-			val = h.Min
+			val = zMin
 		case len(thumbs) - 1:
-			val = h.Max
+			val = zMax
 		}
 		if val < 10000 {
 			l.Add(fmt.Sprintf("%.2f", val), t)
@@ -0,0 +1,222 @@
+package plotter
+
+import (
+	"math"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/palette"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// hexRowHeight is the vertical distance between rows of hexagons of unit width.
+var hexRowHeight = math.Sqrt(3) / 2
+
+// hexRadius is the distance from the center to the corners of a hexagon of unit width.
+var hexRadius = 1 / math.Sqrt(3)
+
+// hexCorners are the corners of a pointy-top hexagon of unit width,
+// counter-clockwise, starting at 30 degrees.
+var hexCorners = [6][2]float64{}
+
+// hexNeighbors are the grid offsets of the neighbors of a cell, counter-clockwise, starting at 0 degrees.
+// The edge shared with neighbor k is between corners k-1 and k.
+var hexNeighbors = [2][6][2]int{
+	{{1, 0}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}}, // even rows
+	{{1, 0}, {1, 1}, {0, 1}, {-1, 0}, {0, -1}, {1, -1}},   // odd rows
+}
+
+func init() {
+	for i := range hexCorners {
+		angle := math.Pi/6 + float64(i)*math.Pi/3
+		hexCorners[i] = [2]float64{hexRadius * math.Cos(angle), hexRadius * math.Sin(angle)}
+	}
+}
+
+// hexCenter returns the center of the hexagonal cell at column c and row r.
+// Odd rows are shifted by half a cell to the right.
+func hexCenter(g plotter.GridXYZ, c, r int) (float64, float64) {
+	return g.X(c) + 0.5*float64(r&1), g.Y(r) * hexRowHeight
+}
+
+// hexDataRange returns the data range of a grid of hexagonal cells.
+// The vertical extent of the outer rows is given by yExtent.
+func hexDataRange(g plotter.GridXYZ, yExtent float64) (xMin, xMax, yMin, yMax float64) {
+	c, r := g.Dims()
+	shift := 0.0
+	if r > 1 {
+		shift = 0.5
+	}
+	xMin = g.X(0) - 0.5
+	xMax = g.X(c-1) + 0.5 + shift
+	yMin = g.Y(0)*hexRowHeight - yExtent
+	yMax = g.Y(r-1)*hexRowHeight + yExtent
+	return
+}
+
+// HexHeatMap is a heat map plotter that draws the cells of a grid as hexagons.
+// Cell centers are given by the grid's X and Y values, which are expected to be unit-spaced.
+type HexHeatMap struct {
+	plotter.GridXYZ
+	Palette  palette.Palette
+	Min, Max float64
+}
+
+// NewHexHeatMap creates a new hexagonal heat map plotter for the given data,
+// using the provided palette.
+func NewHexHeatMap(g plotter.GridXYZ, p palette.Palette) *HexHeatMap {
+	min, max := math.Inf(1), math.Inf(-1)
+	c, r := g.Dims()
+	for i := 0; i < c; i++ {
+		for j := 0; j < r; j++ {
+			v := g.Z(i, j)
+			if math.IsNaN(v) {
+				continue
+			}
+			min = math.Min(min, v)
+			max = math.Max(max, v)
+		}
+	}
+
+	return &HexHeatMap{
+		GridXYZ: g,
+		Palette: p,
+		Min:     min,
+		Max:     max,
+	}
+}
+
+// Plot implements the Plot method of the plot.Plotter interface.
+func (h *HexHeatMap) Plot(c draw.Canvas, plt *plot.Plot) {
+	pal := h.Palette.Colors()
+	ps := float64(len(pal)-1) / (h.Max - h.Min)
+
+	trX, trY := plt.Transforms(&c)
+
+	var pa vg.Path
+	cols, rows := h.GridXYZ.Dims()
+	for i := 0; i < cols; i++ {
+		for j := 0; j < rows; j++ {
+			v := h.GridXYZ.Z(i, j)
+			if math.IsNaN(v) || math.IsInf(ps, 0) {
+				continue
+			}
+			col := pal[int((v-h.Min)*ps+0.5)]
+
+			x, y := hexCenter(h.GridXYZ, i, j)
+			pa = pa[:0]
+			for k, corner := range hexCorners {
+				p := vg.Point{X: trX(x + corner[0]), Y: trY(y + corner[1])}
+				if k == 0 {
+					pa.Move(p)
+				} else {
+					pa.Line(p)
+				}
+			}
+			pa.Close()
+
+			c.SetColor(col)
+			c.Fill(pa)
+		}
+	}
+}
+
+// DataRange implements the DataRange method
+// of the plot.DataRanger interface.
+func (h *HexHeatMap) DataRange() (xMin, xMax, yMin, yMax float64) {
+	return hexDataRange(h.GridXYZ, hexRadius)
+}
+
+// GlyphBoxes implements the GlyphBoxes method
+// of the plot.GlyphBoxer interface.
+func (h *HexHeatMap) GlyphBoxes(plt *plot.Plot) []plot.GlyphBox {
+	return hexGlyphBoxes(h.GridXYZ, plt)
+}
+
+// HexBoundaries draws boundaries between hexagonal cells with different values.
+type HexBoundaries struct {
+	plotter.GridXYZ
+	draw.LineStyle
+}
+
+func NewHexBoundaries(grid plotter.GridXYZ) (*HexBoundaries, error) {
+	style := plotter.DefaultLineStyle
+	style.Width = 3
+	return &HexBoundaries{
+		GridXYZ:   grid,
+		LineStyle: style,
+	}, nil
+}
+
+func (h *HexBoundaries) Plot(c draw.Canvas, plt *plot.Plot) {
+	trX, trY := plt.Transforms(&c)
+
+	cols, rows := h.GridXYZ.Dims()
+	var pa vg.Path
+	for i := 0; i < cols; i++ {
+		for j := 0; j < rows; j++ {
+			vHere := h.GridXYZ.Z(i, j)
+			x, y := hexCenter(h.GridXYZ, i, j)
+			for k, n := range hexNeighbors[j&1] {
+				i2 := i + n[0]
+				j2 := j + n[1]
+				if i2 < 0 || i2 >= cols || j2 < 0 || j2 >= rows {
+					continue
+				}
+				vThere := h.GridXYZ.Z(i2, j2)
+				if vHere == vThere {
+					continue
+				}
+				c1, c2 := hexCorners[(k+5)%6], hexCorners[k]
+				p1 := vg.Point{X: trX(x + c1[0]), Y: trY(y + c1[1])}
+				p2 := vg.Point{X: trX(x + c2[0]), Y: trY(y + c2[1])}
+
+				if !c.Contains(p1) || !c.Contains(p2) {
+					continue
+				}
+
+				pa = pa[:0]
+				pa.Move(p1)
+				pa.Line(p2)
+
+				c.SetLineStyle(h.LineStyle)
+				c.Stroke(pa)
+			}
+		}
+	}
+}
+
+// DataRange implements the DataRange method
+// of the plot.DataRanger interface.
+//
+// The vertical range covers half a row around the outer rows,
+// so that boundaries can be aligned with equally spaced plots.
+func (h *HexBoundaries) DataRange() (xMin, xMax, yMin, yMax float64) {
+	return hexDataRange(h.GridXYZ, hexRowHeight/2)
+}
+
+// GlyphBoxes implements the GlyphBoxes method
+// of the plot.GlyphBoxer interface.
+func (h *HexBoundaries) GlyphBoxes(plt *plot.Plot) []plot.GlyphBox {
+	return hexGlyphBoxes(h.GridXYZ, plt)
+}
+
+func hexGlyphBoxes(g plotter.GridXYZ, plt *plot.Plot) []plot.GlyphBox {
+	c, r := g.Dims()
+	b := make([]plot.GlyphBox, 0, r*c)
+	for i := 0; i < c; i++ {
+		for j := 0; j < r; j++ {
+			x, y := hexCenter(g, i, j)
+			b = append(b, plot.GlyphBox{
+				X: plt.X.Norm(x),
+				Y: plt.Y.Norm(y),
+				Rectangle: vg.Rectangle{
+					Min: vg.Point{X: -5, Y: -5},
+					Max: vg.Point{X: +5, Y: +5},
+				},
+			})
+		}
+	}
+	return b
+}
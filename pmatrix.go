@@ -0,0 +1,91 @@
+package som
+
+import (
+	"slices"
+)
+
+// paretoPercentile is the percentile of pairwise data distances used as Pareto radius.
+const paretoPercentile = 0.18
+
+// paretoMaxRows is the maximum number of data rows used to determine the Pareto radius.
+// For larger datasets, rows are sub-sampled with a regular stride.
+const paretoMaxRows = 1000
+
+// ParetoRadius returns the Pareto radius of the Predictor's data, for the P-matrix.
+// Following Ultsch (2005), it is approximated by the 18th percentile of the
+// pairwise distances between data rows, calculated like for BMU search.
+// Returns zero for less than two data rows.
+func (p *Predictor) ParetoRadius() float64 {
+	data := p.collectRows()
+	if len(data) > paretoMaxRows {
+		stride := float64(len(data)) / paretoMaxRows
+		sample := make([][][]float64, paretoMaxRows)
+		for i := range sample {
+			sample[i] = data[int(float64(i)*stride)]
+		}
+		data = sample
+	}
+	if len(data) < 2 {
+		return 0
+	}
+
+	dist := make([]float64, 0, len(data)*(len(data)-1)/2)
+	for i := range data {
+		for j := 0; j < i; j++ {
+			dist = append(dist, p.som.dataDistance(data[i], data[j]))
+		}
+	}
+	slices.Sort(dist)
+	return dist[int(paretoPercentile*float64(len(dist)-1))]
+}
+
+// PMatrix calculates Ultsch's P-matrix, the data density around each node.
+// The density of a node is the number of data rows within the given radius of the node's vector,
+// with distances calculated like for BMU search. With weights (see [Predictor.SetWeights]),
+// the sum of the weights of these rows is used.
+//
+// The returned slice has one element per node. See also [Predictor.ParetoRadius].
+func (p *Predictor) PMatrix(radius float64) []float64 {
+	data := p.collectRows()
+	nodes := p.som.nodeVectors()
+
+	density := make([]float64, len(nodes))
+	for n, node := range nodes {
+		for i, row := range data {
+			if p.som.dataDistance(node, row) <= radius {
+				density[n] += p.weight(i)
+			}
+		}
+	}
+	return density
+}
+
+// UStarMatrix calculates Ultsch's U*-matrix, the u-matrix scaled by the data density of the P-matrix.
+// For each node, the mean distance to its neighbors (see [Som.UMatrix]) is multiplied
+// by a factor that decreases with density, so that distances inside dense regions are reduced.
+// The factor is 1 for nodes with the mean density, and 0 for nodes with the maximum density.
+//
+// The returned slice has one element per node. See also [Predictor.PMatrix].
+func (p *Predictor) UStarMatrix(radius float64) []float64 {
+	density := p.PMatrix(radius)
+	uMatrix := p.som.UMatrix(true)
+
+	mean, maxDensity := 0.0, 0.0
+	for _, d := range density {
+		mean += d
+		maxDensity = max(maxDensity, d)
+	}
+	mean /= float64(len(density))
+
+	size := p.som.Size()
+	uStar := make([]float64, len(density))
+	for i, d := range density {
+		x, y := size.Coords(i)
+		scale := 1.0
+		if maxDensity > mean {
+			scale = (d-mean)/(mean-maxDensity) + 1
+		}
+		uStar[i] = uMatrix[y*2][x*2] * scale
+	}
+	return uStar
+}
@@ -0,0 +1,37 @@
+package som
+
+import (
+	"testing"
+
+	"github.com/mlange-42/som/layer"
+	"github.com/mlange-42/som/neighborhood"
+	"github.com/mlange-42/som/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPredictorPMatrix(t *testing.T) {
+	s := createGrowingSom(t, layer.Size{Width: 3, Height: 1}, nil, neighborhood.Wrap{})
+	copy(s.layers[0].Weights(), []float64{0, 1, 10})
+
+	tab, err := table.NewWithData([]string{"x"}, []float64{0, 0.1, 0.2, 1, 10})
+	assert.NoError(t, err)
+	p, err := NewPredictor(s, []*table.Table{tab})
+	assert.NoError(t, err)
+
+	assert.InDelta(t, 0.1, p.ParetoRadius(), 1e-12)
+
+	assert.Equal(t, []float64{3, 1, 1}, p.PMatrix(0.5))
+	assert.Equal(t, []float64{4, 4, 1}, p.PMatrix(1))
+	assert.InDeltaSlice(t, []float64{0, 7.5, 13.5}, p.UStarMatrix(0.5), 1e-12)
+
+	assert.NoError(t, p.SetWeights([]float64{1, 1, 1, 2, 1}))
+	assert.Equal(t, []float64{3, 2, 1}, p.PMatrix(0.5))
+
+	t.Run("uniform", func(t *testing.T) {
+		tab, err := table.NewWithData([]string{"x"}, []float64{0, 1, 10})
+		assert.NoError(t, err)
+		p, err := NewPredictor(s, []*table.Table{tab})
+		assert.NoError(t, err)
+		assert.InDeltaSlice(t, []float64{1, 5, 9}, p.UStarMatrix(0.5), 1e-12)
+	})
+}
@@ -3,7 +3,9 @@ package som
 import (
 	"fmt"
 	"math"
+	"runtime"
 	"slices"
+	"sync"
 
 	"github.com/mlange-42/som/neighborhood"
 	"github.com/mlange-42/som/table"
@@ -11,8 +13,10 @@ import (
 
 // Predictor is a struct that holds an SOM and a set of tables for making predictions.
 type Predictor struct {
-	som    *Som
-	tables []*table.Table
+	som     *Som
+	tables  []*table.Table
+	weights []float64
+	threads int
 }
 
 // NewPredictor creates a new Predictor instance with the given SOM and tables.
@@ -24,11 +28,63 @@ func NewPredictor(som *Som, tables []*table.Table) (*Predictor, error) {
 		return nil, err
 	}
 	return &Predictor{
-		som:    som,
-		tables: tables,
+		som:     som,
+		tables:  tables,
+		threads: 1,
 	}, nil
 }
 
+// SetThreads sets the number of worker goroutines used for BMU search over data rows.
+// Values smaller than 1 use the number of available CPUs.
+// Results do not depend on the number of threads.
+func (p *Predictor) SetThreads(threads int) {
+	if threads < 1 {
+		threads = runtime.NumCPU()
+	}
+	p.threads = threads
+}
+
+// SetWeights sets weights for the data rows, e.g. sampling weights of survey data.
+// Weights scale the contribution of rows to density, errors and quality metrics.
+// A nil slice removes the weights.
+//
+// An error is returned if the number of weights does not match the number of rows,
+// if any weight is negative or not finite, or if all weights are zero.
+func (p *Predictor) SetWeights(weights []float64) error {
+	if weights == nil {
+		p.weights = nil
+		return nil
+	}
+	if err := checkWeights(weights, p.rows()); err != nil {
+		return err
+	}
+	p.weights = weights
+	return nil
+}
+
+// weight returns the weight of the given data row.
+func (p *Predictor) weight(row int) float64 {
+	if p.weights == nil {
+		return 1
+	}
+	return p.weights[row]
+}
+
+// rows returns the number of data rows, skipping tables of ignored layers.
+func (p *Predictor) rows() int {
+	for _, t := range p.tables {
+		if t != nil {
+			return t.Rows()
+		}
+	}
+	return 0
+}
+
+// Threads returns the number of worker goroutines used for BMU search.
+func (p *Predictor) Threads() int {
+	return p.threads
+}
+
 // Som returns the SOM associated with this Predictor.
 func (p *Predictor) Som() *Som {
 	return p.som
@@ -47,22 +103,19 @@ func (p *Predictor) Tables() []*table.Table {
 // - node_y: the y-coordinate of the BMU node
 // - node_dist: the distance between the input data and the BMU node
 func (p *Predictor) GetBMUTable() *table.Table {
-	data := make([][]float64, len(p.tables))
-	rows := p.tables[0].Rows()
+	rows := p.rows()
 
 	cols := 4
 	bmu := make([]float64, rows*cols)
 
-	for i := 0; i < rows; i++ {
-		p.collectData(i, data)
-
+	p.forEachRow(rows, func(i int, data [][]float64) {
 		idx, dist := p.som.GetBMU(data)
 		x, y := p.som.Size().Coords(idx)
 		bmu[i*cols] = float64(idx)
 		bmu[i*cols+1] = float64(x)
 		bmu[i*cols+2] = float64(y)
 		bmu[i*cols+3] = dist
-	}
+	})
 
 	t, err := table.NewWithData([]string{"node_id", "node_x", "node_y", "node_dist"}, bmu)
 	if err != nil {
@@ -83,19 +136,17 @@ func (p *Predictor) FillMissing(tables []*table.Table) error {
 	}
 
 	rows := tables[0].Rows()
-	if rows != p.tables[0].Rows() {
+	if rows != p.rows() {
 		return fmt.Errorf("number of rows in tables does not match number of rows in predictor tables")
 	}
 
 	hasMissing := findRowsWithMissing(tables)
 
-	data := make([][]float64, len(tables))
-	for i := 0; i < rows; i++ {
+	p.forEachRow(rows, func(i int, data [][]float64) {
 		if !hasMissing[i] {
-			continue
+			return
 		}
 
-		p.collectData(i, data)
 		bmu, _ := p.som.GetBMU(data)
 		for j, t := range tables {
 			if t == nil {
@@ -111,7 +162,7 @@ func (p *Predictor) FillMissing(tables []*table.Table) error {
 				}
 			}
 		}
-	}
+	})
 
 	return nil
 }
@@ -125,13 +176,24 @@ func (p *Predictor) FillMissing(tables []*table.Table) error {
 // will be returned. The number of rows in the input tables must match the number of
 // rows in the Predictor's tables.
 func (p *Predictor) Predict(tables []*table.Table, layers []string) error {
+	return p.predict(tables, layers, func(data [][]float64) (*Som, int) {
+		bmu, _ := p.som.GetBMU(data)
+		return p.som, bmu
+	})
+}
+
+// predict generates predictions from the nodes returned by bmu, which may belong to different SOMs
+// with the same layers as the Predictor's SOM.
+func (p *Predictor) predict(tables []*table.Table, layers []string, bmu func(data [][]float64) (*Som, int)) error {
 	if err := checkTables(p.som, tables); err != nil {
 		return err
 	}
 
-	rows := tables[0].Rows()
-	if rows != p.tables[0].Rows() {
-		return fmt.Errorf("number of rows in tables does not match number of rows in predictor tables")
+	rows := p.rows()
+	for _, t := range tables {
+		if t != nil && t.Rows() != rows {
+			return fmt.Errorf("number of rows in tables does not match number of rows in predictor tables")
+		}
 	}
 
 	toPredict := make([]bool, len(p.som.layers))
@@ -146,17 +208,15 @@ func (p *Predictor) Predict(tables []*table.Table, layers []string) error {
 		tables[i] = table.New(l.ColumnNames(), rows)
 	}
 
-	data := make([][]float64, len(tables))
-	for i := 0; i < rows; i++ {
-		p.collectData(i, data)
-		bmu, _ := p.som.GetBMU(data)
+	p.forEachRow(rows, func(i int, data [][]float64) {
+		s, idx := bmu(data)
 
-		for j, lay := range p.som.layers {
+		for j, lay := range s.layers {
 			if !toPredict[j] {
 				continue
 			}
 			tab := tables[j]
-			node := lay.GetNodeAt(bmu)
+			node := lay.GetNodeAt(idx)
 			outRow := tab.GetRow(i)
 
 			for k := range tab.Columns() {
@@ -164,7 +224,7 @@ func (p *Predictor) Predict(tables []*table.Table, layers []string) error {
 				outRow[k] = norm.DeNormalize(node[k])
 			}
 		}
-	}
+	})
 
 	return nil
 }
@@ -205,6 +265,51 @@ func (p *Predictor) collectData(row int, data [][]float64) {
 	}
 }
 
+// collectRows returns the data of all rows of the Predictor's tables.
+func (p *Predictor) collectRows() [][][]float64 {
+	rows := make([][][]float64, p.rows())
+	for i := range rows {
+		rows[i] = make([][]float64, len(p.tables))
+		p.collectData(i, rows[i])
+	}
+	return rows
+}
+
+// forEachRow calls fn for each row index, with the row's data collected from the Predictor's tables.
+// Rows are split into contiguous blocks, which are processed by the Predictor's worker goroutines.
+// The data slice is re-used between calls, and fn must only write to outputs specific to the row.
+func (p *Predictor) forEachRow(rows int, fn func(row int, data [][]float64)) {
+	forEachBlock(rows, p.threads, func(start, end int) {
+		data := make([][]float64, len(p.tables))
+		for i := start; i < end; i++ {
+			p.collectData(i, data)
+			fn(i, data)
+		}
+	})
+}
+
+// forEachBlock splits the indices [0, n) into contiguous blocks, one per worker goroutine,
+// and calls fn for each block. With a single thread, fn is called once in the calling goroutine.
+func forEachBlock(n, threads int, fn func(start, end int)) {
+	threads = min(threads, n)
+	if threads <= 1 {
+		fn(0, n)
+		return
+	}
+
+	blockSize := (n + threads - 1) / threads
+	var wg sync.WaitGroup
+	for start := 0; start < n; start += blockSize {
+		end := min(start+blockSize, n)
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			fn(start, end)
+		}(start, end)
+	}
+	wg.Wait()
+}
+
 // GetRowBMU returns the best matching unit (BMU) index and the distance between the
 // input data and the BMU for the given row in the associated tables.
 func (p *Predictor) GetRowBMU(row int) (int, float64) {
@@ -216,33 +321,27 @@ func (p *Predictor) GetRowBMU(row int) (int, float64) {
 // GetBMU returns a slice of the best matching unit (BMU) indices for each row in the
 // associated tables.
 func (p *Predictor) GetBMU() []int {
-	data := make([][]float64, len(p.tables))
-	rows := p.tables[0].Rows()
+	rows := p.rows()
 
 	bmu := make([]int, rows)
 
-	for i := 0; i < rows; i++ {
-		p.collectData(i, data)
-
+	p.forEachRow(rows, func(i int, data [][]float64) {
 		idx, _ := p.som.GetBMU(data)
 		bmu[i] = idx
-	}
+	})
 
 	return bmu
 }
 
 func (p *Predictor) getBMU2() []bmu2 {
-	data := make([][]float64, len(p.tables))
-	rows := p.tables[0].Rows()
+	rows := p.rows()
 
 	bmu := make([]bmu2, rows)
 
-	for i := 0; i < rows; i++ {
-		p.collectData(i, data)
-
+	p.forEachRow(rows, func(i int, data [][]float64) {
 		idx, d, idx2, d2 := p.som.GetBMU2(data)
 		bmu[i] = bmu2{idx, d, idx2, d2}
-	}
+	})
 
 	return bmu
 }
@@ -250,17 +349,14 @@ func (p *Predictor) getBMU2() []bmu2 {
 // GetBMUWithDistance returns the best matching unit (BMU) indices and the distances
 // between the input data and the BMU for each row in the associated tables.
 func (p *Predictor) GetBMUWithDistance() ([]int, []float64) {
-	data := make([][]float64, len(p.tables))
-	rows := p.tables[0].Rows()
+	rows := p.rows()
 
 	bmu := make([]int, rows)
 	distance := make([]float64, rows)
 
-	for i := 0; i < rows; i++ {
-		p.collectData(i, data)
-
+	p.forEachRow(rows, func(i int, data [][]float64) {
 		bmu[i], distance[i] = p.som.GetBMU(data)
-	}
+	})
 
 	return bmu, distance
 }
@@ -278,11 +374,23 @@ func (p *Predictor) GetDensity() []int {
 	return counter
 }
 
-// GetError returns the error for each node in the SOM, either as the raw sum of squared
-// distances between the input data and the BMU, or as the root mean squared error
+// GetWeightedDensity returns the density of the SOM like [Predictor.GetDensity],
+// but as the sum of the weights of the data points that map to each node.
+// Without weights (see [Predictor.SetWeights]), the result is equal to the density.
+func (p *Predictor) GetWeightedDensity() []float64 {
+	bmu := p.GetBMU()
+	density := make([]float64, p.Som().Size().Nodes())
+	for i, idx := range bmu {
+		density[idx] += p.weight(i)
+	}
+	return density
+}
+
+// GetError returns the error for each node in the SOM, either as the mean squared
+// distance between the input data and the BMU (MSE), or as the root mean squared error
 // (RMSE). The returned slice has one element for each
 // node in the SOM, where the value at index i represents the error for the node
-// at index i.
+// at index i. With weights (see [Predictor.SetWeights]), weighted means are used.
 //
 // If rmse is true, the returned values will be the RMSE .
 // Otherwise, the returned values will be the MSE.
@@ -290,17 +398,18 @@ func (p *Predictor) GetError(rmse bool) []float64 {
 	bmu, dist := p.GetBMUWithDistance()
 
 	errors := make([]float64, p.som.size.Nodes())
-	counter := make([]int, p.som.size.Nodes())
+	counter := make([]float64, p.som.size.Nodes())
 	for i, b := range bmu {
 		d := dist[i]
-		errors[b] = d * d
-		counter[b]++
+		w := p.weight(i)
+		errors[b] += w * d * d
+		counter[b] += w
 	}
 	for i := range errors {
 		if counter[i] == 0 {
 			continue
 		}
-		errors[i] /= float64(counter[i])
+		errors[i] /= counter[i]
 		if rmse {
 			errors[i] = math.Sqrt(errors[i])
 		}
@@ -327,31 +436,44 @@ func NewEvaluator(predictor *Predictor) *Evaluator {
 	}
 }
 
+// Error returns the quantization error (mean distance of data rows to their BMU),
+// the mean square error and the root mean square error.
+// With weights set on the Predictor (see [Predictor.SetWeights]), weighted means are used.
 func (e *Evaluator) Error() (qe, mse, rmse float64) {
 	sumDist := 0.0
 	errorSum := 0.0
+	sumWeights := 0.0
 
-	for _, b := range e.bmu {
-		sumDist += b.Dist1
-		errorSum += b.Dist1 * b.Dist1
+	for i, b := range e.bmu {
+		w := e.predictor.weight(i)
+		sumDist += w * b.Dist1
+		errorSum += w * b.Dist1 * b.Dist1
+		sumWeights += w
 	}
 
-	return sumDist / float64(len(e.bmu)),
-		errorSum / float64(len(e.bmu)),
-		math.Sqrt(errorSum / float64(len(e.bmu)))
+	return sumDist / sumWeights,
+		errorSum / sumWeights,
+		math.Sqrt(errorSum / sumWeights)
 }
 
+// TopographicError returns the fraction of data rows for which the best and the second-best matching unit
+// are not adjacent on the map. Adjacency is determined by the SOM's topology.
+// For a rectangular topology, nodes are adjacent if their distance under the given metric is not larger than 1.
+// For a hexagonal topology, the six direct neighbors of a node are adjacent, irrespective of the metric.
+// For periodic maps, nodes across the edges of the map can be adjacent.
+// With weights set on the Predictor (see [Predictor.SetWeights]), the weighted fraction is returned.
 func (e *Evaluator) TopographicError(dist neighborhood.Metric) float64 {
-	failed := len(e.bmu)
-	for _, b := range e.bmu {
-		x1, y1 := e.predictor.som.Size().Coords(b.Idx1)
-		x2, y2 := e.predictor.som.Size().Coords(b.Idx2)
-
-		if dist.Distance(x1, y1, x2, y2) > 1 {
+	som := e.predictor.som
+	failed := 0.0
+	sumWeights := 0.0
+	for i, b := range e.bmu {
+		w := e.predictor.weight(i)
+		sumWeights += w
+		if som.adjacent(dist, b.Idx1, b.Idx2) {
 			continue
 		}
-		failed--
+		failed += w
 	}
 
-	return float64(failed) / float64(len(e.bmu))
+	return failed / sumWeights
 }
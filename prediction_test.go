@@ -1,6 +1,8 @@
 package som
 
 import (
+	"math"
+	"math/rand"
 	"testing"
 
 	"github.com/mlange-42/som/distance"
@@ -71,3 +73,122 @@ func TestPredictorGetBMU(t *testing.T) {
 		5, 2, 1, 1,
 	}, bmu.Data())
 }
+
+func TestPredictorThreads(t *testing.T) {
+	som := createSom()
+	rng := rand.New(rand.NewSource(0))
+	som.Randomize(rng)
+
+	rows := 101
+	t1 := table.New([]string{"x", "y"}, rows)
+	t2 := table.New([]string{"a", "b"}, rows)
+	for i := 0; i < rows; i++ {
+		t1.Set(i, 0, rng.Float64())
+		t1.Set(i, 1, rng.Float64())
+		t2.Set(i, 0, rng.Float64())
+		t2.Set(i, 1, math.NaN())
+	}
+	tables := []*table.Table{t1, t2}
+
+	p, err := NewPredictor(som, tables)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, p.Threads())
+
+	bmuTable := p.GetBMUTable()
+	bmu, dist := p.GetBMUWithDistance()
+	bmu2 := p.getBMU2()
+
+	for _, threads := range []int{2, 7, 200, 0} {
+		p.SetThreads(threads)
+		assert.Greater(t, p.Threads(), 0)
+
+		assert.Equal(t, bmuTable.Data(), p.GetBMUTable().Data())
+		assert.Equal(t, bmu, p.GetBMU())
+		b, d := p.GetBMUWithDistance()
+		assert.Equal(t, bmu, b)
+		assert.Equal(t, dist, d)
+		assert.Equal(t, bmu2, p.getBMU2())
+	}
+
+	p.SetThreads(4)
+	filled := []*table.Table{t1, table.New([]string{"a", "b"}, rows)}
+	copy(filled[1].Data(), t2.Data())
+	err = p.FillMissing(filled)
+	assert.NoError(t, err)
+
+	for i, b := range bmu {
+		assert.Equal(t, som.layers[1].GetNodeAt(b)[1], filled[1].Get(i, 1))
+	}
+}
+
+func TestPredictorWeights(t *testing.T) {
+	s := createGrowingSom(t, layer.Size{Width: 2, Height: 1}, nil, neighborhood.Wrap{})
+	copy(s.layers[0].Weights(), []float64{0, 10})
+
+	tab, err := table.NewWithData([]string{"x"}, []float64{1, 3, 10})
+	assert.NoError(t, err)
+
+	p, err := NewPredictor(s, []*table.Table{tab})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []int{2, 1}, p.GetDensity())
+	assert.Equal(t, []float64{2, 1}, p.GetWeightedDensity())
+	assert.Equal(t, []float64{5, 0}, p.GetError(false))
+
+	assert.Error(t, p.SetWeights([]float64{1, 2}))
+	assert.Error(t, p.SetWeights([]float64{1, -1, 1}))
+	assert.Error(t, p.SetWeights([]float64{1, math.NaN(), 1}))
+	assert.Error(t, p.SetWeights([]float64{0, 0, 0}))
+
+	assert.NoError(t, p.SetWeights([]float64{3, 1, 4}))
+	assert.Equal(t, []int{2, 1}, p.GetDensity())
+	assert.Equal(t, []float64{4, 4}, p.GetWeightedDensity())
+	assert.Equal(t, []float64{3, 0}, p.GetError(false))
+
+	qe, mse, _ := NewEvaluator(p).Error()
+	assert.InDelta(t, (3*1+1*3)/8.0, qe, 1e-12)
+	assert.InDelta(t, (3*1+1*9)/8.0, mse, 1e-12)
+
+	assert.NoError(t, p.SetWeights(nil))
+	qe, _, _ = NewEvaluator(p).Error()
+	assert.InDelta(t, 4/3.0, qe, 1e-12)
+}
+
+func TestPredictorPredictFirstLayer(t *testing.T) {
+	conf := SomConfig{
+		Size: layer.Size{Width: 3, Height: 3},
+		Layers: []*LayerDef{
+			{
+				Name:    "L1",
+				Columns: []string{"x", "y"},
+				Norm:    []norm.Normalizer{&norm.Identity{}, &norm.Identity{}},
+				Metric:  &distance.Euclidean{},
+			},
+			{
+				Name:    "L2",
+				Columns: []string{"a", "b"},
+				Norm:    []norm.Normalizer{&norm.Identity{}, &norm.Identity{}},
+				Metric:  &distance.Euclidean{},
+			},
+		},
+		Neighborhood: &neighborhood.Linear{},
+	}
+	som, err := New(&conf)
+	assert.NoError(t, err)
+	som.Randomize(rand.New(rand.NewSource(0)))
+
+	rows := 5
+	t2 := table.New([]string{"a", "b"}, rows)
+	p, err := NewPredictor(som, []*table.Table{nil, t2})
+	assert.NoError(t, err)
+
+	tables := []*table.Table{nil, nil}
+	err = p.Predict(tables, []string{"L1"})
+	assert.NoError(t, err)
+	assert.Nil(t, tables[1])
+	assert.Equal(t, rows, tables[0].Rows())
+
+	for i, b := range p.GetBMU() {
+		assert.Equal(t, som.layers[0].GetNodeAt(b), tables[0].GetRow(i))
+	}
+}
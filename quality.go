@@ -0,0 +1,349 @@
+package som
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Neighborhoods holds measures of the preservation of data neighborhoods by the SOM,
+// as returned by [Evaluator.Neighborhoods].
+type Neighborhoods struct {
+	Trustworthiness float64 // Whether rows close on the map are close in data space. Between 0 and 1, higher is better
+	Continuity      float64 // Whether rows close in data space are close on the map. Between 0 and 1, higher is better
+	Preservation    float64 // Mean fraction of a row's nearest neighbors in data space that are also nearest on the map
+}
+
+// LayerErrors returns the contribution of each layer to the quantization error,
+// i.e. the mean weighted distance of data rows to their BMU in each layer.
+// The contributions of all layers sum up to the quantization error, unless layers with rescaling
+// for missing data (see [LayerDef]) are completely missing in some rows. For these rows,
+// the quantization error is scaled up by the weight fraction of the observed layers, while layer errors are not.
+// For layers ignored in the Predictor's tables, NaN is returned.
+// With weights set on the Predictor (see [Predictor.SetWeights]), weighted means are used.
+func (e *Evaluator) LayerErrors() []float64 {
+	s := e.predictor.som
+	errors := make([]float64, len(s.layers))
+	data := make([][]float64, len(e.predictor.tables))
+	sumWeights := 0.0
+	for i, b := range e.bmu {
+		e.predictor.collectData(i, data)
+		w := e.predictor.weight(i)
+		sumWeights += w
+		for l, lay := range s.layers {
+			if data[l] == nil || lay.Weight() == 0 {
+				continue
+			}
+			errors[l] += w * lay.Weight() * lay.Metric().Distance(lay.GetNodeAt(b.Idx1), data[l])
+		}
+	}
+	for l := range errors {
+		if e.predictor.tables[l] == nil {
+			errors[l] = math.NaN()
+			continue
+		}
+		errors[l] /= sumWeights
+	}
+	return errors
+}
+
+// TopographicProduct returns the topographic product of Bauer and Pawelzik (1992).
+// It compares, for each node, the order of its nearest neighbors on the map with the order in data space.
+// Values near zero indicate a good fit of the map size to the data. Negative values indicate that the map
+// is too small for the data's dimensionality, positive values that it is too large.
+//
+// Map distances are determined by the SOM's map metric, topology and boundaries.
+// The computation is quadratic in the number of nodes.
+func (e *Evaluator) TopographicProduct() float64 {
+	s := e.predictor.som
+	n := s.size.Nodes()
+	if n < 2 {
+		return 0
+	}
+
+	distData := make([]float64, n)
+	distMap := make([]float64, n)
+	orderData := make([]int, 0, n-1)
+	orderMap := make([]int, 0, n-1)
+
+	sumLog := 0.0
+	for j := 0; j < n; j++ {
+		orderData, orderMap = orderData[:0], orderMap[:0]
+		for i := 0; i < n; i++ {
+			if i == j {
+				continue
+			}
+			distData[i] = s.nodeDistance(j, i)
+			distMap[i] = s.nodeMapDistance(j, i)
+			orderData = append(orderData, i)
+			orderMap = append(orderMap, i)
+		}
+		sortByDistance(orderData, distData)
+		sortByDistance(orderMap, distMap)
+
+		logProduct := 0.0
+		for k := 1; k < n; k++ {
+			nMap, nData := orderMap[k-1], orderData[k-1]
+			logProduct += logRatio(distData[nMap], distData[nData]) + logRatio(distMap[nMap], distMap[nData])
+			sumLog += logProduct / float64(2*k)
+		}
+	}
+	return sumLog / float64(n*(n-1))
+}
+
+// Neighborhoods returns the trustworthiness and continuity of Venna and Kaski (2001),
+// as well as the neighborhood preservation, for the k nearest neighbors of each data row.
+// The map position of a data row is the position of its BMU. Ties in distances are broken by row order.
+// Weights set on the Predictor are not used.
+//
+// Map distances are determined by the SOM's map metric, topology and boundaries.
+// The computation is quadratic in the number of data rows.
+// An error is returned if k is not positive, or not smaller than half the number of rows.
+func (e *Evaluator) Neighborhoods(k int) (Neighborhoods, error) {
+	s := e.predictor.som
+	n := len(e.bmu)
+	if k < 1 || 2*k >= n {
+		return Neighborhoods{}, fmt.Errorf("number of neighbors must be in range [1, %d), got %d", (n+1)/2, k)
+	}
+
+	rows := e.predictor.collectRows()
+
+	distData := make([]float64, n)
+	distMap := make([]float64, n)
+	orderData := make([]int, 0, n-1)
+	orderMap := make([]int, 0, n-1)
+	rankData := make([]int, n)
+	rankMap := make([]int, n)
+
+	sumTrust, sumCont, sumPreserved := 0.0, 0.0, 0
+	for i := 0; i < n; i++ {
+		orderData, orderMap = orderData[:0], orderMap[:0]
+		for j := 0; j < n; j++ {
+			if i == j {
+				continue
+			}
+			distData[j] = s.dataDistance(rows[i], rows[j])
+			distMap[j] = s.nodeMapDistance(e.bmu[i].Idx1, e.bmu[j].Idx1)
+			orderData = append(orderData, j)
+			orderMap = append(orderMap, j)
+		}
+		sortByDistance(orderData, distData)
+		sortByDistance(orderMap, distMap)
+		for r, j := range orderData {
+			rankData[j] = r + 1
+		}
+		for r, j := range orderMap {
+			rankMap[j] = r + 1
+		}
+
+		for _, j := range orderMap[:k] {
+			if rankData[j] > k {
+				sumTrust += float64(rankData[j] - k)
+			} else {
+				sumPreserved++
+			}
+		}
+		for _, j := range orderData[:k] {
+			if rankMap[j] > k {
+				sumCont += float64(rankMap[j] - k)
+			}
+		}
+	}
+
+	norm := 2 / float64(n*k*(2*n-3*k-1))
+	return Neighborhoods{
+		Trustworthiness: 1 - norm*sumTrust,
+		Continuity:      1 - norm*sumCont,
+		Preservation:    float64(sumPreserved) / float64(n*k),
+	}, nil
+}
+
+// KaskiLagusError returns the combined error of Kaski and Lagus (1996).
+// For each data row, it is the distance to the BMU, plus the length of the shortest path
+// from the BMU to the second-best matching unit, along neighboring nodes, with node distances in data space.
+// With weights set on the Predictor (see [Predictor.SetWeights]), the weighted mean is returned.
+func (e *Evaluator) KaskiLagusError() float64 {
+	paths := map[int][]float64{}
+	sumError := 0.0
+	sumWeights := 0.0
+	for i, b := range e.bmu {
+		dist, ok := paths[b.Idx1]
+		if !ok {
+			dist = e.predictor.som.shortestPaths(b.Idx1)
+			paths[b.Idx1] = dist
+		}
+		w := e.predictor.weight(i)
+		sumError += w * (b.Dist1 + dist[b.Idx2])
+		sumWeights += w
+	}
+	return sumError / sumWeights
+}
+
+// Distortion returns the SOM distortion measure, i.e. the mean over data rows of the squared distances
+// to all nodes, weighted by the neighborhood function of the SOM with the given radius around the BMU.
+// This is the energy function that is minimized by SOM training with a fixed radius.
+// With weights set on the Predictor (see [Predictor.SetWeights]), the weighted mean is returned.
+func (e *Evaluator) Distortion(radius float64) float64 {
+	s := e.predictor.som
+	n := s.size.Nodes()
+	data := make([][]float64, len(e.predictor.tables))
+	sumDistortion := 0.0
+	sumWeights := 0.0
+	for i, b := range e.bmu {
+		e.predictor.collectData(i, data)
+		d := 0.0
+		for j := 0; j < n; j++ {
+			h := s.neighborhood.Weight(s.nodeMapDistance(b.Idx1, j), radius)
+			if h == 0 {
+				continue
+			}
+			dist := s.distance(data, j)
+			d += h * dist * dist
+		}
+		w := e.predictor.weight(i)
+		sumDistortion += w * d
+		sumWeights += w
+	}
+	return sumDistortion / sumWeights
+}
+
+// ExplainedVariance returns the fraction of the data's variance explained by the SOM,
+// i.e. one minus the ratio of the sum of squared distances to the BMUs and the sum of squared
+// distances to the data's mean. Distances are calculated like for BMU search.
+// With weights set on the Predictor (see [Predictor.SetWeights]), weighted sums are used.
+func (e *Evaluator) ExplainedVariance() float64 {
+	s := e.predictor.som
+	mean := e.dataMean()
+	data := make([][]float64, len(e.predictor.tables))
+	sumResidual := 0.0
+	sumTotal := 0.0
+	for i, b := range e.bmu {
+		e.predictor.collectData(i, data)
+		w := e.predictor.weight(i)
+		dist := s.dataDistance(mean, data)
+		sumResidual += w * b.Dist1 * b.Dist1
+		sumTotal += w * dist * dist
+	}
+	if sumTotal == 0 {
+		return math.NaN()
+	}
+	return 1 - sumResidual/sumTotal
+}
+
+// dataMean returns the (weighted) mean of the data, per layer and column, ignoring missing values.
+func (e *Evaluator) dataMean() [][]float64 {
+	mean := make([][]float64, len(e.predictor.tables))
+	for l, tab := range e.predictor.tables {
+		if tab == nil {
+			continue
+		}
+		cols := tab.Columns()
+		mean[l] = make([]float64, cols)
+		for c := 0; c < cols; c++ {
+			sum, sumWeights := 0.0, 0.0
+			for i := 0; i < tab.Rows(); i++ {
+				v := tab.Get(i, c)
+				if math.IsNaN(v) {
+					continue
+				}
+				w := e.predictor.weight(i)
+				sum += w * v
+				sumWeights += w
+			}
+			mean[l][c] = sum / sumWeights
+		}
+	}
+	return mean
+}
+
+// nodeMapDistance returns the distance between two nodes on the map,
+// according to the SOM's map metric, topology and boundaries.
+func (s *Som) nodeMapDistance(idx1, idx2 int) float64 {
+	x1, y1 := s.size.Coords(idx1)
+	x2, y2 := s.size.Coords(idx2)
+	return s.mapDistance(s.metric, x1, y1, x2, y2)
+}
+
+// dataDistance returns the distance between two data rows, calculated like for BMU search.
+// Layers without data in any of the rows are skipped.
+func (s *Som) dataDistance(a, b [][]float64) float64 {
+	totalDist := 0.0
+	for l, layer := range s.layers {
+		if layer.Weight() == 0 || a[l] == nil || b[l] == nil {
+			continue
+		}
+		totalDist += layer.Weight() * layer.Metric().Distance(a[l], b[l])
+	}
+	return totalDist
+}
+
+// shortestPaths returns the lengths of the shortest paths from the given node to all nodes,
+// along neighboring nodes, with the distances between nodes in data space as edge lengths.
+func (s *Som) shortestPaths(start int) []float64 {
+	n := s.size.Nodes()
+	dist := make([]float64, n)
+	for i := range dist {
+		dist[i] = math.Inf(1)
+	}
+	dist[start] = 0
+
+	queue := &nodeQueue{{node: start}}
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(nodeDist)
+		if item.dist > dist[item.node] {
+			continue
+		}
+		x, y := s.size.Coords(item.node)
+		s.forEachNeighbor(x, y, func(x2, y2, row, col int) {
+			other := s.size.Index(x2, y2)
+			d := item.dist + s.nodeDistance(item.node, other)
+			if d < dist[other] {
+				dist[other] = d
+				heap.Push(queue, nodeDist{node: other, dist: d})
+			}
+		})
+	}
+	return dist
+}
+
+// nodeDist is a node with its distance from the start node of a path search.
+type nodeDist struct {
+	node int
+	dist float64
+}
+
+// nodeQueue is a priority queue of nodes, implementing [heap.Interface].
+type nodeQueue []nodeDist
+
+func (q nodeQueue) Len() int           { return len(q) }
+func (q nodeQueue) Less(i, j int) bool { return q[i].dist < q[j].dist }
+func (q nodeQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *nodeQueue) Push(x any)        { *q = append(*q, x.(nodeDist)) }
+func (q *nodeQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// sortByDistance sorts indices by their distance, with ties broken by index.
+func sortByDistance(indices []int, dist []float64) {
+	slices.SortFunc(indices, func(a, b int) int {
+		if dist[a] < dist[b] {
+			return -1
+		}
+		if dist[a] > dist[b] {
+			return 1
+		}
+		return a - b
+	})
+}
+
+// logRatio returns the logarithm of a/b, or zero if any of the values is zero.
+func logRatio(a, b float64) float64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return math.Log(a / b)
+}
@@ -0,0 +1,74 @@
+package som
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mlange-42/som/layer"
+	"github.com/mlange-42/som/neighborhood"
+	"github.com/mlange-42/som/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func createQualityEvaluator(t *testing.T, nodes, data []float64) *Evaluator {
+	s := createGrowingSom(t, layer.Size{Width: len(nodes), Height: 1}, nil, neighborhood.Wrap{})
+	copy(s.layers[0].Weights(), nodes)
+	tab, err := table.NewWithData([]string{"x"}, data)
+	assert.NoError(t, err)
+	pred, err := NewPredictor(s, []*table.Table{tab})
+	assert.NoError(t, err)
+	return NewEvaluator(pred)
+}
+
+func TestEvaluatorTopographicProduct(t *testing.T) {
+	eval := createQualityEvaluator(t, []float64{0, 1, 2, 3}, []float64{0, 1, 2, 3})
+	assert.InDelta(t, 0.0, eval.TopographicProduct(), 1e-12)
+
+	eval = createQualityEvaluator(t, []float64{0, 1, 2, 2.1, 1.1, 0.1}, []float64{0, 1, 2, 3})
+	assert.NotEqual(t, 0.0, eval.TopographicProduct())
+}
+
+func TestEvaluatorNeighborhoods(t *testing.T) {
+	eval := createQualityEvaluator(t, []float64{0, 1, 2, 3, 4}, []float64{0, 1, 2, 3, 4})
+	n, err := eval.Neighborhoods(2)
+	assert.NoError(t, err)
+	assert.Equal(t, Neighborhoods{Trustworthiness: 1, Continuity: 1, Preservation: 1}, n)
+
+	eval = createQualityEvaluator(t, []float64{0, 4, 1, 3, 2}, []float64{0, 1, 2, 3, 4})
+	n, err = eval.Neighborhoods(2)
+	assert.NoError(t, err)
+	assert.Less(t, n.Trustworthiness, 1.0)
+	assert.Less(t, n.Continuity, 1.0)
+	assert.Less(t, n.Preservation, 1.0)
+
+	_, err = eval.Neighborhoods(0)
+	assert.Error(t, err)
+	_, err = eval.Neighborhoods(3)
+	assert.Error(t, err)
+}
+
+func TestEvaluatorErrors(t *testing.T) {
+	eval := createQualityEvaluator(t, []float64{0, 1, 2, 4}, []float64{0, 1, 2, 4.5})
+
+	qe, _, _ := eval.Error()
+	assert.Equal(t, []float64{qe}, eval.LayerErrors())
+
+	// Row 3 has BMU 3 at distance 0.5, and BMU 2 at path length 2.
+	assert.InDelta(t, (1+1+1+2.5)/4, eval.KaskiLagusError(), 1e-12)
+
+	mean := (0 + 1 + 2 + 4.5) / 4
+	total := mean*mean + (1-mean)*(1-mean) + (2-mean)*(2-mean) + (4.5-mean)*(4.5-mean)
+	assert.InDelta(t, 1-0.25/total, eval.ExplainedVariance(), 1e-12)
+
+	assert.InDelta(t, 0.25/4, eval.Distortion(0.01), 1e-12)
+
+	g := neighborhood.Gaussian{}
+	expected := 0.0
+	nodes := []float64{0, 1, 2, 4}
+	for i, x := range []float64{0, 1, 2, 4.5} {
+		for j, w := range nodes {
+			expected += g.Weight(math.Abs(float64(i-j)), 1) * (x - w) * (x - w)
+		}
+	}
+	assert.InDelta(t, expected/4, eval.Distortion(1), 1e-12)
+}
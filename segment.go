@@ -0,0 +1,128 @@
+package som
+
+import (
+	"math"
+	"slices"
+)
+
+// SegmentConfig holds the parameters for the watershed segmentation of the u-matrix.
+type SegmentConfig struct {
+	MinSize        int     // Minimum number of nodes per segment. Smaller segments are merged with a neighbor
+	MergeThreshold float64 // Minimum height of the boundary to a neighboring segment, above the higher of the two segments' minima. Shallower segments are merged
+}
+
+// Segment segments the SOM into regions of low inter-node distances, separated by ridges in the u-matrix.
+//
+// Heights are the node values of the u-matrix (see [Som.UMatrix]), i.e. the mean distances to neighbors.
+// The u-matrix is flooded from its local minima, where each node joins the segment of the already
+// flooded neighbor with the lowest height.
+// Subsequently, adjacent segments are merged in the order of the height of their boundary,
+// i.e. the lowest height of a pair of adjacent nodes across the boundary, where the height of a pair
+// is the higher of the two node heights. Segments are merged as long as one of them is smaller than MinSize,
+// or the boundary is less than MergeThreshold above the higher of the two segments' minima.
+// With the defaults, i.e. zero values, no segments are merged.
+//
+// Segments are numbered in the order of their first node.
+// The Score of the returned [Clustering] is not used.
+func (s *Som) Segment(config *SegmentConfig) *Clustering {
+	n := s.size.Nodes()
+	u := s.UMatrix(true)
+
+	height := make([]float64, n)
+	for i := range height {
+		x, y := s.size.Coords(i)
+		height[i] = u[y*2][x*2]
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		if height[a] < height[b] {
+			return -1
+		}
+		if height[a] > height[b] {
+			return 1
+		}
+		return 0
+	})
+
+	// Flooding
+	labels := make([]int, n)
+	for i := range labels {
+		labels[i] = -1
+	}
+	basins := 0
+	for _, i := range order {
+		x, y := s.size.Coords(i)
+		best, bestHeight, bestLink := -1, math.Inf(1), math.Inf(1)
+		s.forEachNeighbor(x, y, func(x2, y2, row, col int) {
+			j := s.size.Index(x2, y2)
+			if labels[j] < 0 {
+				return
+			}
+			if height[j] < bestHeight || (height[j] == bestHeight && u[row][col] < bestLink) {
+				best, bestHeight, bestLink = labels[j], height[j], u[row][col]
+			}
+		})
+		if best < 0 {
+			best = basins
+			basins++
+		}
+		labels[i] = best
+	}
+
+	// Merging
+	parent := make([]int, basins)
+	sizes := make([]int, basins)
+	minHeight := make([]float64, basins)
+	for i := range parent {
+		parent[i] = i
+		minHeight[i] = math.Inf(1)
+	}
+	for i, l := range labels {
+		sizes[l]++
+		minHeight[l] = min(minHeight[l], height[i])
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for {
+		a, b, saddle := -1, -1, math.Inf(1)
+		for i := 0; i < n; i++ {
+			x, y := s.size.Coords(i)
+			s.forEachNeighbor(x, y, func(x2, y2, row, col int) {
+				j := s.size.Index(x2, y2)
+				la, lb := find(labels[i]), find(labels[j])
+				h := max(height[i], height[j])
+				if la == lb || h >= saddle {
+					return
+				}
+				if sizes[la] >= config.MinSize && sizes[lb] >= config.MinSize &&
+					h-max(minHeight[la], minHeight[lb]) >= config.MergeThreshold {
+					return
+				}
+				a, b, saddle = la, lb, h
+			})
+		}
+		if a < 0 {
+			break
+		}
+		parent[b] = a
+		sizes[a] += sizes[b]
+		minHeight[a] = min(minHeight[a], minHeight[b])
+	}
+
+	nodes := make([]int, n)
+	for i, l := range labels {
+		nodes[i] = find(l)
+	}
+	nodes = renumberClusters(nodes, basins)
+
+	return &Clustering{Clusters: slices.Max(nodes) + 1, Nodes: nodes}
+}
@@ -0,0 +1,62 @@
+package som
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/mlange-42/som/layer"
+	"github.com/mlange-42/som/neighborhood"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSomSegment(t *testing.T) {
+	s := createGrowingSom(t, layer.Size{Width: 6, Height: 1}, nil, neighborhood.Wrap{})
+	copy(s.layers[0].Weights(), []float64{0, 0.1, 0.2, 5, 5.1, 5.2})
+
+	c := s.Segment(&SegmentConfig{})
+	assert.Equal(t, 2, c.Clusters)
+	assert.Equal(t, []int{0, 0, 0, 1, 1, 1}, c.Nodes)
+
+	c = s.Segment(&SegmentConfig{MinSize: 3})
+	assert.Equal(t, 2, c.Clusters)
+
+	c = s.Segment(&SegmentConfig{MinSize: 4})
+	assert.Equal(t, 1, c.Clusters)
+	assert.Equal(t, []int{0, 0, 0, 0, 0, 0}, c.Nodes)
+
+	c = s.Segment(&SegmentConfig{MergeThreshold: 2.3})
+	assert.Equal(t, 2, c.Clusters)
+
+	c = s.Segment(&SegmentConfig{MergeThreshold: 2.4})
+	assert.Equal(t, 1, c.Clusters)
+}
+
+func TestSomSegmentDefaults(t *testing.T) {
+	s := createGrowingSom(t, layer.Size{Width: 8, Height: 6}, nil, neighborhood.Wrap{})
+	rng := rand.New(rand.NewSource(1))
+	for i := range s.layers[0].Weights() {
+		s.layers[0].Weights()[i] = rng.Float64()
+	}
+
+	raw := s.Segment(&SegmentConfig{MergeThreshold: math.Inf(-1)})
+	assert.Greater(t, raw.Clusters, 1)
+
+	c := s.Segment(&SegmentConfig{})
+	assert.Equal(t, raw, c, "defaults should return the raw basins")
+}
+
+func TestSomSegmentMerge(t *testing.T) {
+	s := createGrowingSom(t, layer.Size{Width: 9, Height: 1}, nil, neighborhood.Wrap{})
+	copy(s.layers[0].Weights(), []float64{0, 0.1, 0.2, 1.2, 1.3, 1.4, 6.4, 6.5, 6.6})
+
+	c := s.Segment(&SegmentConfig{})
+	assert.Equal(t, []int{0, 0, 0, 1, 1, 1, 2, 2, 2}, c.Nodes)
+
+	// The low boundary between the first two segments is merged first.
+	c = s.Segment(&SegmentConfig{MergeThreshold: 1})
+	assert.Equal(t, []int{0, 0, 0, 0, 0, 0, 1, 1, 1}, c.Nodes)
+
+	assert.NoError(t, s.AddClusterLayer("segment", c))
+	assert.True(t, s.layers[1].IsCategorical())
+}
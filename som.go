@@ -23,12 +23,17 @@ type SomConfig struct {
 	Neighborhood neighborhood.Neighborhood // Neighborhood function of the SOM
 	MapMetric    neighborhood.Metric       // Metric used to calculate distances on the map
 	ViSomMetric  neighborhood.Metric       // Metric used to calculate distances on the map for ViSOM update
+	Topology     neighborhood.Topology     // Arrangement of nodes on the map. Optional, defaults to rectangular
+	Wrap         neighborhood.Wrap         // Boundary conditions of the map, i.e. whether it is periodic
 }
 
 // PrepareTables reads the CSV data and creates a table for each layer defined in the SomConfig.
 // If a categorical layer has no columns specified, it will attempt to read the class names for that layer
 // and create a table from the classes. The created tables are returned in the same order as
 // the layers in the SomConfig.
+//
+// If updateNormalizers is true, the covariance matrix of layers with a [distance.Mahalanobis]
+// metric is estimated from the normalized data.
 func (c *SomConfig) PrepareTables(reader table.Reader, ignoreLayers []string, updateNormalizers bool, keepOriginal bool) (normalized, raw []*table.Table, err error) {
 	normalized = make([]*table.Table, len(c.Layers))
 	raw = make([]*table.Table, len(c.Layers))
@@ -73,6 +78,11 @@ func (c *SomConfig) PrepareTables(reader table.Reader, ignoreLayers []string, up
 		}
 
 		normalizeTable(tab, layer, updateNormalizers)
+		if m, ok := layer.Metric.(*distance.Mahalanobis); ok && updateNormalizers {
+			if err := estimateCovariance(tab, m); err != nil {
+				return nil, nil, fmt.Errorf("can't use mahalanobis metric for layer %s: %s", layer.Name, err.Error())
+			}
+		}
 		normalized[i] = tab
 	}
 
@@ -100,6 +110,12 @@ func normalizeTable(tab *table.Table, layer *LayerDef, update bool) {
 	}
 }
 
+// estimateCovariance sets the covariance matrix of a Mahalanobis metric from the (normalized) table.
+func estimateCovariance(tab *table.Table, m *distance.Mahalanobis) error {
+	tables := []*table.Table{tab}
+	return m.SetCovariance(covariance(tables, columnMeans(tables)[0]))
+}
+
 func createCategoricalTable(reader table.Reader, layer *LayerDef) (*table.Table, error) {
 	classes, err := reader.ReadLabels(layer.Name)
 	if err != nil {
@@ -133,13 +149,15 @@ func keepTable(list []*table.Table, idx int, tab *table.Table, keep bool) error
 // A weight value of 0.0 is interpreted as standard weight of 1.0.
 // To get a weight of 0.0, give the weight field a negative value.
 type LayerDef struct {
-	Name        string            // Name of the layer
-	Columns     []string          // Columns to use from the data
-	Norm        []norm.Normalizer // Normalization functions for the columns
-	Metric      distance.Distance // Distance metric to use for this layer
-	Weight      float64           // Weight value for this layer (for multi-layer SOMs)
-	Categorical bool              // Whether the layer contains categorical data
-	Weights     []float64         // Pre-computed layer weights (if provided)
+	Name          string            // Name of the layer
+	Columns       []string          // Columns to use from the data
+	Norm          []norm.Normalizer // Normalization functions for the columns
+	Metric        distance.Distance // Distance metric to use for this layer
+	Weight        float64           // Weight value for this layer (for multi-layer SOMs)
+	Categorical   bool              // Whether the layer contains categorical data
+	Weights       []float64         // Pre-computed layer weights (if provided)
+	Rescale       bool              // Whether to rescale distances for missing data (see [distance.Rescaled])
+	ColumnWeights []float64         // Weights of the columns in distance calculations (optional)
 }
 
 // Som represents a Self-Organizing Map (SOM) model.
@@ -149,6 +167,8 @@ type Som struct {
 	neighborhood neighborhood.Neighborhood
 	metric       neighborhood.Metric
 	viSomMetric  neighborhood.Metric
+	topology     neighborhood.Topology
+	wrap         neighborhood.Wrap
 }
 
 // New creates a new Self-Organizing Map (SOM) instance based on the provided SomConfig.
@@ -173,13 +193,39 @@ func New(params *SomConfig) (*Som, error) {
 		} else if weight < 0 {
 			weight = 0
 		}
-		metric := l.Metric
-		if metric == nil {
+		var metric distance.Distance
+		if l.Metric == nil {
 			if l.Categorical {
 				metric = &distance.Hamming{}
 			} else {
 				metric = &distance.Euclidean{}
 			}
+		} else {
+			metric, err = distance.Clone(l.Metric)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(l.ColumnWeights) > 0 {
+			if len(l.ColumnWeights) != len(l.Columns) {
+				return nil, fmt.Errorf("layer %s has %d column weights, but %d columns", l.Name, len(l.ColumnWeights), len(l.Columns))
+			}
+			for _, w := range l.ColumnWeights {
+				if w < 0 {
+					return nil, fmt.Errorf("layer %s has negative column weights", l.Name)
+				}
+			}
+			metric.SetColumnWeights(slices.Clone(l.ColumnWeights))
+		}
+		if m, ok := metric.(*distance.Mahalanobis); ok && m.Dims() > 0 && m.Dims() != len(l.Columns) {
+			return nil, fmt.Errorf("layer %s has %d columns, but covariance matrix of mahalanobis metric has %d", l.Name, len(l.Columns), m.Dims())
+		}
+		if _, ok := metric.(*distance.Rescaled); l.Rescale && !ok {
+			rescalable, ok := metric.(distance.Rescalable)
+			if !ok {
+				return nil, fmt.Errorf("metric %s of layer %s does not support rescaling for missing data", metric.Name(), l.Name)
+			}
+			metric = &distance.Rescaled{Metric: rescalable}
 		}
 
 		if len(l.Weights) == 0 {
@@ -191,12 +237,21 @@ func New(params *SomConfig) (*Som, error) {
 			return nil, err
 		}
 	}
+	topology := params.Topology
+	if topology == nil {
+		topology = &neighborhood.Rectangular{}
+	}
+	if _, ok := topology.(*neighborhood.Hexagonal); ok && params.Wrap.Y && params.Size.Height%2 != 0 {
+		return nil, fmt.Errorf("hexagonal SOM wrapping in y direction requires an even number of rows, got %d", params.Size.Height)
+	}
 	return &Som{
 		size:         params.Size,
 		layers:       lay,
 		neighborhood: params.Neighborhood,
 		metric:       params.MapMetric,
 		viSomMetric:  params.ViSomMetric,
+		topology:     topology,
+		wrap:         params.Wrap,
 	}, nil
 }
 
@@ -245,6 +300,16 @@ func (s *Som) ViSomMetric() neighborhood.Metric {
 	return s.viSomMetric
 }
 
+// Topology returns the arrangement of nodes of the Self-Organizing Map (SOM), e.g. rectangular or hexagonal.
+func (s *Som) Topology() neighborhood.Topology {
+	return s.topology
+}
+
+// Wrap returns the boundary conditions of the Self-Organizing Map (SOM), i.e. whether it is periodic.
+func (s *Som) Wrap() neighborhood.Wrap {
+	return s.wrap
+}
+
 // Learn updates the weights of the Self-Organizing Map (SOM) based on the given input data.
 // It calculates the Best Matching Unit (BMU) for the input data, then updates the weights
 // of the nodes in the SOM based on the neighborhood function and learning rate.
@@ -306,21 +371,25 @@ func (s *Som) updateWeights(bmuIdx int, data [][]float64, alpha, radius, lambda
 		lim = s.size.Nodes()
 	}
 
+	xLim, yLim := s.topology.Extent(lim)
+
 	xBmu, yBmu := s.size.Coords(bmuIdx)
-	xMin, yMin := max(xBmu-lim, 0), max(yBmu-lim, 0)
-	xMax, yMax := min(xBmu+lim, s.size.Width-1), min(yBmu+lim, s.size.Height-1)
+	xMin, xMax := windowRange(xBmu, xLim, s.size.Width, s.wrap.X)
+	yMin, yMax := windowRange(yBmu, yLim, s.size.Height, s.wrap.Y)
 
 	// update BMU, to use its new position in neighborhood updates
 	s.updateNode(xBmu, yBmu, data, alpha)
 
-	for x := xMin; x <= xMax; x++ {
-		for y := yMin; y <= yMax; y++ {
+	for xw := xMin; xw <= xMax; xw++ {
+		x := wrapIndex(xw, s.size.Width)
+		for yw := yMin; yw <= yMax; yw++ {
+			y := wrapIndex(yw, s.size.Height)
 			if x == xBmu && y == yBmu {
 				// Skip BMU, already updated above
 				continue
 			}
 
-			dist := s.metric.Distance(xBmu, yBmu, x, y)
+			dist := s.mapDistance(s.metric, xBmu, yBmu, x, y)
 			r := s.neighborhood.Weight(dist, radius)
 			if r <= 0 {
 				// Outside neighborhood, don't update
@@ -356,8 +425,8 @@ func (s *Som) updateNode(x, y int, data [][]float64, rate float64) {
 func (s *Som) updateNodeViSom(bmuIdx, x, y int, data [][]float64, rate float64, lambda float64) {
 	xBmu, yBmu := s.size.Coords(bmuIdx)
 	nodeIdx := s.size.Index(x, y)
-	d := s.nodeDistance(bmuIdx, nodeIdx)                   // distance in data space
-	D := lambda * s.viSomMetric.Distance(xBmu, yBmu, x, y) // scaled distance in map space
+	d := s.nodeDistance(bmuIdx, nodeIdx)                         // distance in data space
+	D := lambda * s.mapDistance(s.viSomMetric, xBmu, yBmu, x, y) // scaled distance in map space
 
 	// scale = (d - D) / D = d/D - 1 (original formulation Yin 2002)
 	scale := 0.0
@@ -380,6 +449,180 @@ func (s *Som) updateNodeViSom(bmuIdx, x, y int, data [][]float64, rate float64,
 	}
 }
 
+// LearnBatch updates the weights of the Self-Organizing Map (SOM) using the batch algorithm.
+// Each node is set to the neighborhood-weighted mean of the data rows given by rows, with the index of each row's
+// Best Matching Unit (BMU) in bmus. If rows is nil, bmus refers to all data rows, in order.
+// Rows may occur multiple times. Rows are weighted by weights, indexed by data row. If weights is nil,
+// all rows have the same weight. Missing values are ignored. Node columns without any data
+// in the neighborhood keep their previous value.
+func (s *Som) LearnBatch(tables []*table.Table, rows []int, bmus []int, weights []float64, radius float64) {
+	nodes := s.size.Nodes()
+
+	// Aggregate data per BMU first, so that the neighborhood is evaluated per pair of nodes rather than per row.
+	sums := make([][]float64, len(s.layers))
+	counts := make([][]float64, len(s.layers))
+	for l, lay := range s.layers {
+		cols := lay.Columns()
+		sums[l] = make([]float64, nodes*cols)
+		counts[l] = make([]float64, nodes*cols)
+		if tables[l] == nil {
+			continue
+		}
+		for i, bmu := range bmus {
+			row := i
+			if rows != nil {
+				row = rows[i]
+			}
+			w := 1.0
+			if weights != nil {
+				w = weights[row]
+			}
+			data := tables[l].GetRow(row)
+			for i, d := range data {
+				if math.IsNaN(d) {
+					continue
+				}
+				sums[l][bmu*cols+i] += w * d
+				counts[l][bmu*cols+i] += w
+			}
+		}
+	}
+
+	lim := s.neighborhood.MaxRadius(radius)
+	if lim < 0 {
+		lim = nodes
+	}
+	xLim, yLim := s.topology.Extent(lim)
+
+	// Nodes in the neighborhood window, with their neighborhood weights.
+	window := make([]nodeWeight, 0, nodes)
+	for x := 0; x < s.size.Width; x++ {
+		xMin, xMax := windowRange(x, xLim, s.size.Width, s.wrap.X)
+		for y := 0; y < s.size.Height; y++ {
+			yMin, yMax := windowRange(y, yLim, s.size.Height, s.wrap.Y)
+
+			window = window[:0]
+			for xw := xMin; xw <= xMax; xw++ {
+				x2 := wrapIndex(xw, s.size.Width)
+				for yw := yMin; yw <= yMax; yw++ {
+					y2 := wrapIndex(yw, s.size.Height)
+					dist := s.mapDistance(s.metric, x, y, x2, y2)
+					if w := s.neighborhood.Weight(dist, radius); w > 0 {
+						window = append(window, nodeWeight{s.size.Index(x2, y2), w})
+					}
+				}
+			}
+
+			for l, lay := range s.layers {
+				s.updateNodeBatch(lay.GetNode(x, y), sums[l], counts[l], window)
+			}
+		}
+	}
+}
+
+// nodeWeight is the index of a node, with its neighborhood weight.
+type nodeWeight struct {
+	index  int
+	weight float64
+}
+
+// updateNodeBatch sets a node to the weighted mean of the aggregated data of the nodes in its neighborhood window.
+func (s *Som) updateNodeBatch(node []float64, sums, counts []float64, window []nodeWeight) {
+	cols := len(node)
+	for i := 0; i < cols; i++ {
+		num, den := 0.0, 0.0
+		for _, n := range window {
+			num += n.weight * sums[n.index*cols+i]
+			den += n.weight * counts[n.index*cols+i]
+		}
+		if den > 0 {
+			node[i] = num / den
+		}
+	}
+}
+
+// mapDistance calculates the distance between two nodes in map space,
+// using the positions of the nodes according to the SOM's topology.
+// For periodic maps, the distance to the nearest periodic image of the second node is used.
+func (s *Som) mapDistance(metric neighborhood.Metric, x1, y1, x2, y2 int) float64 {
+	px1, py1 := s.topology.Position(x1, y1)
+	px2, py2 := s.topology.Position(x2, y2)
+	if s.wrap.X {
+		period, _ := s.topology.Position(s.size.Width, 0)
+		px2 = px1 + periodicDelta(px2-px1, period)
+	}
+	if s.wrap.Y {
+		_, period := s.topology.Position(0, s.size.Height)
+		py2 = py1 + periodicDelta(py2-py1, period)
+	}
+	return metric.Distance(px1, py1, px2, py2)
+}
+
+// adjacent reports whether two nodes are neighbors on the map, according to the SOM's topology.
+func (s *Som) adjacent(metric neighborhood.Metric, idx1, idx2 int) bool {
+	x1, y1 := s.size.Coords(idx1)
+	x2, y2 := s.size.Coords(idx2)
+	// Use the periodic image of the second node that is closest to the first node.
+	if s.wrap.X {
+		x2 = x1 + int(periodicDelta(float64(x2-x1), float64(s.size.Width)))
+	}
+	if s.wrap.Y {
+		y2 = y1 + int(periodicDelta(float64(y2-y1), float64(s.size.Height)))
+	}
+	return s.topology.Adjacent(metric, x1, y1, x2, y2)
+}
+
+// forEachNeighbor calls fn for each direct neighbor of the node at (x, y),
+// with the neighbor's coordinates and the row and column of the link in the U-Matrix.
+// For periodic maps, neighbors across the edges are included.
+func (s *Som) forEachNeighbor(x, y int, fn func(x2, y2, row, col int)) {
+	w, h := s.size.Width, s.size.Height
+	uw, uh := s.uMatrixSize()
+	for _, n := range s.topology.Neighbors(x, y) {
+		x2, y2 := x+n[0], y+n[1]
+		if !s.wrap.X && (x2 < 0 || x2 >= w) || !s.wrap.Y && (y2 < 0 || y2 >= h) {
+			continue
+		}
+		fn(wrapIndex(x2, w), wrapIndex(y2, h), wrapIndex(y+y2, uh), wrapIndex(x+x2, uw))
+	}
+}
+
+// uMatrixSize returns the number of columns and rows of the U-Matrix.
+// Periodic maps have an additional column or row for the links across the edges.
+func (s *Som) uMatrixSize() (int, int) {
+	width, height := s.size.Width*2-1, s.size.Height*2-1
+	if s.wrap.X {
+		width++
+	}
+	if s.wrap.Y {
+		height++
+	}
+	return width, height
+}
+
+// windowRange returns the range of coordinates around center, covering lim cells to each side.
+// For periodic axes, the range is not clipped but limited to the size of the map.
+func windowRange(center, lim, size int, wrap bool) (int, int) {
+	if !wrap {
+		return max(center-lim, 0), min(center+lim, size-1)
+	}
+	if 2*lim+1 > size {
+		return center - size/2, center - size/2 + size - 1
+	}
+	return center - lim, center + lim
+}
+
+// wrapIndex maps an index into the range [0, size).
+func wrapIndex(idx, size int) int {
+	return ((idx % size) + size) % size
+}
+
+// periodicDelta returns the difference with the smallest absolute value
+// that is equivalent to delta, given the period.
+func periodicDelta(delta, period float64) float64 {
+	return delta - period*math.Round(delta/period)
+}
+
 func (s *Som) decayWeights(center [][]float64, rate float64) {
 	if rate == 0 {
 		return
@@ -400,16 +643,28 @@ func (s *Som) decayWeights(center [][]float64, rate float64) {
 	}
 }
 
+// distance calculates the weighted distance between a data row and a unit.
+// Layers with rescaling for missing data (see [distance.Rescaled]) that are completely missing
+// in the row are skipped, and the distance is scaled up by the weight fraction of the remaining layers.
 func (s *Som) distance(data [][]float64, unit int) float64 {
 	totalDist := 0.0
+	totalWeight, missingWeight := 0.0, 0.0
 	for l, layer := range s.layers {
 		if layer.Weight() == 0 || data[l] == nil {
 			continue
 		}
+		totalWeight += layer.Weight()
+		if _, ok := layer.Metric().(*distance.Rescaled); ok && distance.Observed(data[l]) == 0 {
+			missingWeight += layer.Weight()
+			continue
+		}
 		node := layer.GetNodeAt(unit)
 		dist := layer.Metric().Distance(node, data[l])
 		totalDist += layer.Weight() * dist
 	}
+	if missingWeight > 0 && missingWeight < totalWeight {
+		totalDist *= totalWeight / (totalWeight - missingWeight)
+	}
 	return totalDist
 }
 
@@ -447,11 +702,17 @@ func (s *Som) Layers() []*layer.Layer {
 // the dimensions of the original map, with the values representing the
 // distances between nodes and their neighbors.
 //
+// The link between nodes (x1, y1) and (x2, y2) is found at index [y1+y2][x1+x2].
+// For a hexagonal topology, this includes the diagonal links between rows,
+// which occupy the cells that are "empty space" for a rectangular topology.
+//
+// For periodic maps, the matrix has an additional column (wrapping in x direction)
+// or row (wrapping in y direction) for the links across the edges of the map.
+//
 // If fill is true, cells that don't correspond to a link, but to a node or an "empty space"
 // are filled with the average of the surrounding links.
 func (s *Som) UMatrix(fill bool) [][]float64 {
-	height := s.size.Height*2 - 1
-	width := s.size.Width*2 - 1
+	width, height := s.uMatrixSize()
 	u := make([][]float64, height)
 
 	for y := range u {
@@ -464,14 +725,13 @@ func (s *Som) UMatrix(fill bool) [][]float64 {
 	for x := 0; x < s.size.Width; x++ {
 		for y := 0; y < s.size.Height; y++ {
 			nodeHere := s.size.Index(x, y)
-			if x < s.size.Width-1 {
-				nodeRight := s.size.Index(x+1, y)
-				u[y*2][x*2+1] = s.nodeDistance(nodeHere, nodeRight)
-			}
-			if y < s.size.Height-1 {
-				nodeDown := s.size.Index(x, y+1)
-				u[y*2+1][x*2] = s.nodeDistance(nodeHere, nodeDown)
-			}
+			s.forEachNeighbor(x, y, func(x2, y2, row, col int) {
+				if !math.IsNaN(u[row][col]) {
+					// Link already processed from the other node
+					return
+				}
+				u[row][col] = s.nodeDistance(nodeHere, s.size.Index(x2, y2))
+			})
 		}
 	}
 
@@ -479,34 +739,25 @@ func (s *Som) UMatrix(fill bool) [][]float64 {
 		return u
 	}
 
-	for x := 0; x < s.size.Width*2-1; x++ {
-		for y := 0; y < s.size.Height*2-1; y++ {
-			if x%2 != y%2 {
-				continue
-			}
-
+	for x := 0; x < s.size.Width; x++ {
+		for y := 0; y < s.size.Height; y++ {
 			sum := 0.0
 			cnt := 0
-
-			if x > 0 {
-				sum += u[y][x-1]
-				cnt++
-			}
-			if x < width-1 {
-				sum += u[y][x+1]
+			s.forEachNeighbor(x, y, func(x2, y2, row, col int) {
+				sum += u[row][col]
 				cnt++
-			}
+			})
+			u[y*2][x*2] = sum / float64(cnt)
+		}
+	}
 
-			if y > 0 {
-				sum += u[y-1][x]
-				cnt++
-			}
-			if y < height-1 {
-				sum += u[y+1][x]
-				cnt++
+	for x := 1; x < width; x += 2 {
+		for y := 1; y < height; y += 2 {
+			if !math.IsNaN(u[y][x]) {
+				// Cell is a link
+				continue
 			}
-
-			u[y][x] = sum / float64(cnt)
+			u[y][x] = (u[y][x-1] + u[y][wrapIndex(x+1, width)] + u[y-1][x] + u[wrapIndex(y+1, height)][x]) / 4
 		}
 	}
 
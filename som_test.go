@@ -196,6 +196,50 @@ func TestNew(t *testing.T) {
 	})
 }
 
+func TestPrepareTablesMahalanobis(t *testing.T) {
+	metric := &distance.Mahalanobis{}
+	params := &SomConfig{
+		Size: layer.Size{Width: 2, Height: 2},
+		Layers: []*LayerDef{
+			{
+				Name:    "Layer1",
+				Columns: []string{"x", "y"},
+				Norm:    []norm.Normalizer{&norm.Identity{}, &norm.Identity{}},
+				Metric:  metric,
+			},
+		},
+	}
+
+	tab, err := table.NewWithData([]string{"x", "y"}, []float64{1, 2, 2, 4, 3, 5, 4, 9})
+	assert.NoError(t, err)
+	reader := mockReader{Table: tab}
+
+	_, _, err = params.PrepareTables(&reader, nil, false, false)
+	assert.NoError(t, err)
+	assert.Nil(t, metric.GetArgs())
+
+	_, _, err = params.PrepareTables(&reader, nil, true, false)
+	assert.NoError(t, err)
+	assert.InDeltaSlice(t, []float64{5.0 / 3, 11.0 / 3, 11.0 / 3, 26.0 / 3}, metric.GetArgs(), 1e-12)
+
+	som, err := New(params)
+	assert.NoError(t, err)
+	assert.NotSame(t, metric, som.layers[0].Metric())
+	assert.Equal(t, metric, som.layers[0].Metric())
+
+	params.Layers[0].Columns = []string{"x"}
+	params.Layers[0].Norm = []norm.Normalizer{&norm.Identity{}}
+	_, err = New(params)
+	assert.Error(t, err)
+
+	tab, err = table.NewWithData([]string{"x", "y"}, []float64{1, 2, 2, 4, 3, 6})
+	assert.NoError(t, err)
+	params.Layers[0].Columns = []string{"x", "y"}
+	params.Layers[0].Norm = []norm.Normalizer{&norm.Identity{}, &norm.Identity{}}
+	_, _, err = params.PrepareTables(&mockReader{Table: tab}, nil, true, false)
+	assert.Error(t, err)
+}
+
 func TestGetBMU(t *testing.T) {
 	params := SomConfig{
 		Size: layer.Size{Width: 2, Height: 2},
@@ -431,6 +475,87 @@ func TestNodeDistance(t *testing.T) {
 	assert.InDelta(t, math.Sqrt(8), som.nodeDistance(1, 2), 0.001)
 }
 
+func TestDistanceRescaleMissing(t *testing.T) {
+	nan := math.NaN()
+	config := SomConfig{
+		Size: layer.Size{Width: 1, Height: 1},
+		Layers: []*LayerDef{
+			{
+				Columns: []string{"x", "y"},
+				Norm:    []norm.Normalizer{&norm.Identity{}, &norm.Identity{}},
+				Metric:  &distance.Manhattan{},
+				Rescale: true,
+				Weights: []float64{0.0, 0.0},
+			},
+			{
+				Columns: []string{"a", "b"},
+				Norm:    []norm.Normalizer{&norm.Identity{}, &norm.Identity{}},
+				Metric:  &distance.Manhattan{},
+				Weight:  3,
+				Rescale: true,
+				Weights: []float64{0.0, 0.0},
+			},
+		},
+	}
+	som, err := New(&config)
+	assert.NoError(t, err)
+	assert.IsType(t, &distance.Rescaled{}, som.layers[0].Metric())
+
+	assert.Equal(t, 8.0, som.distance([][]float64{{1, 1}, {1, 1}}, 0))
+	assert.Equal(t, 8.0, som.distance([][]float64{{1, nan}, {1, 1}}, 0))
+	assert.Equal(t, 8.0, som.distance([][]float64{{nan, nan}, {1, 1}}, 0))
+	assert.Equal(t, 8.0, som.distance([][]float64{{1, 1}, {nan, 1}}, 0))
+	assert.Equal(t, 0.0, som.distance([][]float64{{nan, nan}, {nan, nan}}, 0))
+
+	config.Layers[0].Rescale = false
+	config.Layers[1].Rescale = false
+	som, err = New(&config)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 8.0, som.distance([][]float64{{1, 1}, {1, 1}}, 0))
+	assert.Equal(t, 6.0, som.distance([][]float64{{nan, nan}, {1, 1}}, 0))
+}
+
+func TestNewRescaleUnsupported(t *testing.T) {
+	for _, m := range []distance.Distance{
+		&distance.Hamming{},
+		&distance.Chebyshev{},
+		&distance.Cosine{},
+		&distance.Correlation{},
+	} {
+		config := SomConfig{
+			Size: layer.Size{Width: 2, Height: 2},
+			Layers: []*LayerDef{
+				{
+					Name:    "xy",
+					Columns: []string{"x", "y"},
+					Norm:    []norm.Normalizer{&norm.Identity{}, &norm.Identity{}},
+					Metric:  m,
+					Rescale: true,
+				},
+			},
+		}
+		_, err := New(&config)
+		assert.Error(t, err, m.Name())
+		assert.Contains(t, err.Error(), "does not support rescaling")
+	}
+
+	config := SomConfig{
+		Size: layer.Size{Width: 2, Height: 2},
+		Layers: []*LayerDef{
+			{
+				Name:    "xy",
+				Columns: []string{"x", "y"},
+				Norm:    []norm.Normalizer{&norm.Identity{}, &norm.Identity{}},
+				Metric:  &distance.Euclidean{},
+				Rescale: true,
+			},
+		},
+	}
+	_, err := New(&config)
+	assert.NoError(t, err)
+}
+
 func TestNodeMapDistance(t *testing.T) {
 	config := SomConfig{
 		Size:      layer.Size{Width: 2, Height: 2},
@@ -544,6 +669,26 @@ func BenchmarkUpdateWeights_10x10x5_Linear2(b *testing.B) {
 	}
 }
 
+func BenchmarkLearnBatch_30x30x5_Linear2(b *testing.B) {
+	b.StopTimer()
+	som := createBenchSom(30, 30, 5, &neighborhood.Linear{})
+	rng := rand.New(rand.NewSource(0))
+	tab := table.New([]string{"x0", "x1", "x2", "x3", "x4"}, 1000)
+	bmus := make([]int, tab.Rows())
+	for i := range bmus {
+		for j := 0; j < tab.Columns(); j++ {
+			tab.Set(i, j, rng.Float64())
+		}
+		bmus[i] = rng.Intn(som.Size().Nodes())
+	}
+	tables := []*table.Table{tab}
+
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		som.LearnBatch(tables, nil, bmus, nil, 2.0)
+	}
+}
+
 func BenchmarkUpdateWeightsViSOM_5x5x3_Gaussian2(b *testing.B) {
 	b.StopTimer()
 	som := createBenchSom(5, 5, 3, &neighborhood.Gaussian{})
@@ -671,3 +816,185 @@ func TestUMatrix(t *testing.T) {
 	// Check center (average of surrounding distances)
 	assert.InDelta(t, 1.5, uMatrix[1][1], 0.001)
 }
+
+func TestUMatrixColumnWeights(t *testing.T) {
+	params := &SomConfig{
+		Size: layer.Size{Width: 2, Height: 2},
+		Layers: []*LayerDef{
+			{
+				Name:          "Layer1",
+				Columns:       []string{"x", "y"},
+				Norm:          []norm.Normalizer{&norm.Identity{}, &norm.Identity{}},
+				Metric:        &distance.Euclidean{},
+				ColumnWeights: []float64{4, 0.25},
+			},
+		},
+		Neighborhood: &neighborhood.Gaussian{},
+	}
+
+	som, err := New(params)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{4, 0.25}, som.layers[0].Metric().ColumnWeights())
+
+	som.layers[0].Set(1, 0, 0, 1)
+	som.layers[0].Set(0, 1, 1, 2)
+	som.layers[0].Set(1, 1, 0, 1)
+	som.layers[0].Set(1, 1, 1, 2)
+
+	uMatrix := som.UMatrix(true)
+
+	assert.InDelta(t, 2.0, uMatrix[0][1], 0.001)
+	assert.InDelta(t, 1.0, uMatrix[1][0], 0.001)
+	assert.InDelta(t, 1.5, uMatrix[0][0], 0.001)
+
+	params.Layers[0].ColumnWeights = []float64{1}
+	_, err = New(params)
+	assert.Error(t, err)
+
+	params.Layers[0].ColumnWeights = []float64{1, -1}
+	_, err = New(params)
+	assert.Error(t, err)
+}
+
+func TestNewKeepsConfigMetric(t *testing.T) {
+	metric := &distance.Minkowski{}
+	assert.NoError(t, metric.SetArgs(3))
+	params := &SomConfig{
+		Size: layer.Size{Width: 2, Height: 2},
+		Layers: []*LayerDef{
+			{
+				Name:          "Layer1",
+				Columns:       []string{"x", "y"},
+				Norm:          []norm.Normalizer{&norm.Identity{}, &norm.Identity{}},
+				Metric:        metric,
+				ColumnWeights: []float64{4, 0.25},
+				Rescale:       true,
+			},
+		},
+		Neighborhood: &neighborhood.Gaussian{},
+	}
+
+	som, err := New(params)
+	assert.NoError(t, err)
+	assert.Same(t, metric, params.Layers[0].Metric)
+	assert.Nil(t, metric.ColumnWeights())
+	assert.Equal(t, []float64{3}, metric.GetArgs())
+	assert.Equal(t, []float64{4, 0.25}, som.layers[0].Metric().ColumnWeights())
+	assert.Equal(t, []float64{3}, som.layers[0].Metric().GetArgs())
+
+	params.Layers[0].ColumnWeights = nil
+	som, err = New(params)
+	assert.NoError(t, err)
+	assert.Nil(t, som.layers[0].Metric().ColumnWeights())
+}
+
+func TestUMatrixHexagonal(t *testing.T) {
+	params := &SomConfig{
+		Size: layer.Size{Width: 2, Height: 2},
+		Layers: []*LayerDef{
+			{
+				Name:    "Layer1",
+				Columns: []string{"x"},
+				Norm:    []norm.Normalizer{&norm.Identity{}},
+				Weight:  1.0,
+				Metric:  &distance.Euclidean{},
+			},
+		},
+		Neighborhood: &neighborhood.Gaussian{},
+		Topology:     &neighborhood.Hexagonal{},
+	}
+
+	som, err := New(params)
+	assert.NoError(t, err)
+
+	som.layers[0].Set(1, 0, 0, 1)
+	som.layers[0].Set(0, 1, 0, 2)
+	som.layers[0].Set(1, 1, 0, 4)
+
+	uMatrix := som.UMatrix(false)
+
+	// Horizontal links
+	assert.InDelta(t, 1.0, uMatrix[0][1], 0.001)
+	assert.InDelta(t, 2.0, uMatrix[2][1], 0.001)
+
+	// Links between rows, (0, 0)-(0, 1) and (1, 0)-(0, 1)
+	assert.InDelta(t, 2.0, uMatrix[1][0], 0.001)
+	assert.InDelta(t, 1.0, uMatrix[1][1], 0.001)
+	// (1, 0)-(1, 1)
+	assert.InDelta(t, 3.0, uMatrix[1][2], 0.001)
+
+	assert.True(t, math.IsNaN(uMatrix[0][0]))
+
+	uMatrix = som.UMatrix(true)
+	assert.InDelta(t, 1.5, uMatrix[0][0], 0.001)
+	assert.InDelta(t, 5.0/3.0, uMatrix[0][2], 0.001)
+}
+
+func TestUMatrixWrap(t *testing.T) {
+	params := &SomConfig{
+		Size: layer.Size{Width: 3, Height: 2},
+		Layers: []*LayerDef{
+			{
+				Name:    "Layer1",
+				Columns: []string{"x"},
+				Norm:    []norm.Normalizer{&norm.Identity{}},
+				Weight:  1.0,
+				Metric:  &distance.Euclidean{},
+			},
+		},
+		Neighborhood: &neighborhood.Gaussian{},
+		Wrap:         neighborhood.Wrap{X: true},
+	}
+
+	som, err := New(params)
+	assert.NoError(t, err)
+
+	som.layers[0].Set(1, 0, 0, 1)
+	som.layers[0].Set(2, 0, 0, 3)
+
+	uMatrix := som.UMatrix(false)
+
+	assert.Equal(t, 3, len(uMatrix))
+	assert.Equal(t, 6, len(uMatrix[0]))
+
+	// Link across the edge, (2, 0)-(0, 0)
+	assert.InDelta(t, 3.0, uMatrix[0][5], 0.001)
+
+	uMatrix = som.UMatrix(true)
+	// Node (0, 0) with links to (1, 0), (2, 0) and (0, 1)
+	assert.InDelta(t, 4.0/3.0, uMatrix[0][0], 0.001)
+}
+
+func TestMapDistanceWrap(t *testing.T) {
+	params := &SomConfig{
+		Size: layer.Size{Width: 5, Height: 4},
+		Layers: []*LayerDef{
+			{
+				Name:    "Layer1",
+				Columns: []string{"x"},
+				Norm:    []norm.Normalizer{&norm.Identity{}},
+				Weight:  1.0,
+				Metric:  &distance.Euclidean{},
+			},
+		},
+		Neighborhood: &neighborhood.Gaussian{},
+		Wrap:         neighborhood.Wrap{X: true, Y: true},
+	}
+
+	som, err := New(params)
+	assert.NoError(t, err)
+
+	metric := &neighborhood.ManhattanMetric{}
+	assert.InDelta(t, 1.0, som.mapDistance(metric, 0, 0, 4, 0), 0.001)
+	assert.InDelta(t, 2.0, som.mapDistance(metric, 0, 0, 4, 3), 0.001)
+	assert.InDelta(t, 4.0, som.mapDistance(metric, 0, 0, 2, 2), 0.001)
+
+	assert.True(t, som.adjacent(metric, som.size.Index(0, 0), som.size.Index(4, 0)))
+	assert.True(t, som.adjacent(metric, som.size.Index(0, 0), som.size.Index(0, 3)))
+	assert.False(t, som.adjacent(metric, som.size.Index(0, 0), som.size.Index(3, 0)))
+
+	params.Topology = &neighborhood.Hexagonal{}
+	params.Size.Height = 3
+	_, err = New(params)
+	assert.Error(t, err)
+}
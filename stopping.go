@@ -0,0 +1,89 @@
+package som
+
+import (
+	"math"
+	"time"
+)
+
+// StoppingConfig holds the criteria for stopping training before the configured number of epochs.
+//
+// When any of the criteria is met, the remaining decay schedule is compressed into FinalEpochs epochs,
+// so that the final learning rate and neighborhood radius are still reached.
+// All criteria are optional, and are ignored if zero.
+type StoppingConfig struct {
+	Tolerance   float64       // Relative change of the quantization error over Window epochs to stop below
+	Window      int           // Number of epochs to measure the relative change of the quantization error over
+	MaxTime     time.Duration // Maximum wall-clock duration of the training epochs
+	Patience    int           // Number of validation evaluations without improvement of the validation error to stop after. Ignored without validation data
+	FinalEpochs int           // Number of epochs for the compressed remaining decay schedule. Optional, default 1
+}
+
+// stopper evaluates the stopping criteria during training.
+type stopper struct {
+	config    *StoppingConfig
+	start     time.Time
+	errors    []float64
+	best      float64
+	sinceBest int
+}
+
+func newStopper(config *StoppingConfig) *stopper {
+	return &stopper{
+		config: config,
+		start:  time.Now(),
+		best:   math.Inf(1),
+	}
+}
+
+// check records the errors of a completed epoch and returns whether any stopping criterion is met.
+// The validation error is ignored if it is NaN.
+func (s *stopper) check(qError, validationError float64) bool {
+	c := s.config
+
+	if c.MaxTime > 0 && time.Since(s.start) >= c.MaxTime {
+		return true
+	}
+
+	if c.Tolerance > 0 && c.Window > 0 {
+		s.errors = append(s.errors, qError)
+		if len(s.errors) > c.Window {
+			old := s.errors[len(s.errors)-c.Window-1]
+			s.errors = s.errors[len(s.errors)-c.Window:]
+			if old == 0 || math.Abs(qError-old)/old < c.Tolerance {
+				return true
+			}
+		}
+	}
+
+	if c.Patience > 0 && !math.IsNaN(validationError) {
+		if validationError < s.best {
+			s.best = validationError
+			s.sinceBest = 0
+		} else {
+			s.sinceBest++
+			if s.sinceBest >= c.Patience {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// compressSchedule returns the schedule positions (i.e. epochs for decay functions) for running the
+// remaining decay schedule after the given epoch in at most final epochs.
+// The last position is always the last epoch of the schedule.
+func compressSchedule(epoch, epochs, final int) []int {
+	remaining := epochs - 1 - epoch
+	if final < 1 {
+		final = 1
+	}
+	if remaining <= final {
+		final = remaining
+	}
+	positions := make([]int, final)
+	for k := 0; k < final; k++ {
+		positions[k] = epoch + ((k+1)*remaining+final-1)/final
+	}
+	return positions
+}
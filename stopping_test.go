@@ -0,0 +1,125 @@
+package som
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/mlange-42/som/decay"
+	"github.com/mlange-42/som/layer"
+	"github.com/mlange-42/som/neighborhood"
+	"github.com/mlange-42/som/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressSchedule(t *testing.T) {
+	assert.Equal(t, []int{40, 70, 99}, compressSchedule(10, 100, 3))
+	assert.Equal(t, []int{99}, compressSchedule(10, 100, 0))
+	assert.Equal(t, []int{8, 9}, compressSchedule(7, 10, 5))
+	assert.Empty(t, compressSchedule(9, 10, 5))
+}
+
+func TestStopper(t *testing.T) {
+	t.Run("Tolerance", func(t *testing.T) {
+		s := newStopper(&StoppingConfig{Tolerance: 0.1, Window: 2})
+		assert.False(t, s.check(10, math.NaN()))
+		assert.False(t, s.check(8, math.NaN()))
+		assert.False(t, s.check(6, math.NaN()))
+		assert.False(t, s.check(5.5, math.NaN()))
+		assert.True(t, s.check(5.5, math.NaN()))
+	})
+
+	t.Run("Patience", func(t *testing.T) {
+		s := newStopper(&StoppingConfig{Patience: 2})
+		assert.False(t, s.check(1, 5))
+		assert.False(t, s.check(1, 4))
+		assert.False(t, s.check(1, 4.5))
+		assert.False(t, s.check(1, 3))
+		assert.False(t, s.check(1, 3))
+		assert.True(t, s.check(1, 3.5))
+
+		s = newStopper(&StoppingConfig{Patience: 1})
+		assert.False(t, s.check(1, math.NaN()))
+		assert.False(t, s.check(1, math.NaN()))
+	})
+
+	t.Run("MaxTime", func(t *testing.T) {
+		s := newStopper(&StoppingConfig{MaxTime: time.Nanosecond})
+		time.Sleep(time.Millisecond)
+		assert.True(t, s.check(1, math.NaN()))
+	})
+}
+
+func TestTrainerStopping(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	tab := table.New([]string{"x"}, 50)
+	for i := 0; i < tab.Rows(); i++ {
+		tab.Set(i, 0, rng.Float64())
+	}
+
+	s := createGrowingSom(t, layer.Size{Width: 3, Height: 1}, nil, neighborhood.Wrap{})
+	params := TrainingConfig{
+		Epochs:             1000,
+		LearningRate:       &decay.Linear{Start: 0.5, End: 0.01},
+		NeighborhoodRadius: &decay.Linear{Start: 2, End: 0.5},
+		Stopping: &StoppingConfig{
+			Patience:    3,
+			FinalEpochs: 4,
+		},
+	}
+	trainer, err := NewTrainer(s, []*table.Table{tab}, &params, rng)
+	assert.NoError(t, err)
+
+	err = trainer.SetValidation([]*table.Table{table.New([]string{"a", "b"}, 1)})
+	assert.Error(t, err)
+	err = trainer.SetValidation([]*table.Table{tab})
+	assert.NoError(t, err)
+
+	progress := make(chan TrainingProgress)
+	go trainer.Train(progress)
+
+	var last TrainingProgress
+	epochs := 0
+	for p := range progress {
+		last = p
+		epochs++
+	}
+
+	assert.Less(t, epochs, params.Epochs)
+	assert.Equal(t, params.Epochs-1, last.Epoch)
+	assert.Equal(t, params.NeighborhoodRadius.Decay(params.Epochs-1, params.Epochs), last.Radius)
+	assert.Equal(t, params.LearningRate.Decay(params.Epochs-1, params.Epochs), last.Alpha)
+}
+
+func TestTrainerStoppingNoValidation(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	tab := table.New([]string{"x"}, 50)
+	for i := 0; i < tab.Rows(); i++ {
+		tab.Set(i, 0, rng.Float64())
+	}
+
+	s := createGrowingSom(t, layer.Size{Width: 3, Height: 1}, nil, neighborhood.Wrap{})
+	params := TrainingConfig{
+		Epochs:             20,
+		LearningRate:       &decay.Linear{Start: 0.5, End: 0.01},
+		NeighborhoodRadius: &decay.Linear{Start: 2, End: 0.5},
+		Stopping: &StoppingConfig{
+			Patience: 3,
+		},
+	}
+	trainer, err := NewTrainer(s, []*table.Table{tab}, &params, rng)
+	assert.NoError(t, err)
+
+	progress := make(chan TrainingProgress)
+	go trainer.Train(progress)
+
+	epochs := 0
+	for p := range progress {
+		assert.False(t, p.Validation)
+		assert.True(t, math.IsNaN(p.ValidationError))
+		assert.True(t, math.IsNaN(p.ValidationTopoError))
+		epochs++
+	}
+	assert.Equal(t, params.Epochs, epochs)
+}
@@ -100,6 +100,20 @@ func (t *Table) Data() []float64 {
 	return t.data
 }
 
+// SelectRows creates a new table containing copies of the given rows, in the given order.
+func (t *Table) SelectRows(rows []int) *Table {
+	cols := len(t.columns)
+	data := make([]float64, 0, len(rows)*cols)
+	for _, row := range rows {
+		data = append(data, t.GetRow(row)...)
+	}
+	return &Table{
+		columns: t.columns,
+		rows:    len(rows),
+		data:    data,
+	}
+}
+
 func (t *Table) mean(col int) float64 {
 	sum := 0.0
 	count := 0
@@ -133,7 +147,7 @@ func (t *Table) MeanStdDev(col int) (float64, float64) {
 func (t *Table) Range(col int) (min, max float64) {
 	min = math.Inf(1)
 	max = math.Inf(-1)
-	for i := 1; i < t.Rows(); i++ {
+	for i := 0; i < t.Rows(); i++ {
 		v := t.Get(i, col)
 		if math.IsNaN(v) {
 			continue
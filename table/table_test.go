@@ -1,6 +1,7 @@
 package table
 
 import (
+	"math"
 	"testing"
 
 	"github.com/mlange-42/som/norm"
@@ -36,6 +37,38 @@ func TestTable(t *testing.T) {
 	assert.Equal(t, 100.0, tb.Get(2, 3))
 }
 
+func TestSelectRows(t *testing.T) {
+	tb, err := NewWithData([]string{"a", "b"}, []float64{0, 1, 2, 3, 4, 5})
+	assert.NoError(t, err)
+
+	sel := tb.SelectRows([]int{2, 0})
+	assert.Equal(t, 2, sel.Rows())
+	assert.Equal(t, []float64{4, 5, 0, 1}, sel.Data())
+
+	sel.Set(0, 0, 100)
+	assert.Equal(t, 4.0, tb.Get(2, 0))
+
+	sel = tb.SelectRows(nil)
+	assert.Equal(t, 0, sel.Rows())
+}
+
+func TestRange(t *testing.T) {
+	tb, err := NewWithData([]string{"a", "b"}, []float64{
+		-1, 5,
+		0, math.NaN(),
+		2, 3,
+	})
+	assert.NoError(t, err)
+
+	min, max := tb.Range(0)
+	assert.Equal(t, -1.0, min)
+	assert.Equal(t, 2.0, max)
+
+	min, max = tb.Range(1)
+	assert.Equal(t, 3.0, min)
+	assert.Equal(t, 5.0, max)
+}
+
 func TestNewTableFromData(t *testing.T) {
 	t.Run("Valid input", func(t *testing.T) {
 		columns := []string{"x", "y", "z"}
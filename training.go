@@ -1,11 +1,14 @@
 package som
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"math/rand"
+	"runtime"
 	"slices"
 	"strconv"
+	"time"
 
 	"github.com/mlange-42/som/decay"
 	"github.com/mlange-42/som/distance"
@@ -14,13 +17,54 @@ import (
 	"github.com/mlange-42/som/table"
 )
 
+// Algorithm is the algorithm used for training a Self-Organizing Map (SOM).
+type Algorithm uint8
+
+const (
+	Online Algorithm = iota // Sequential online training, updating the SOM for each data row
+	Batch                   // Batch training, setting each node to the neighborhood-weighted mean of the data
+)
+
+var algorithms = map[string]Algorithm{
+	"online": Online,
+	"batch":  Batch,
+}
+
+// GetAlgorithm returns the training algorithm with the given name.
+// Options are online and batch.
+func GetAlgorithm(name string) (Algorithm, bool) {
+	a, ok := algorithms[name]
+	return a, ok
+}
+
+// String returns the name of the algorithm.
+func (a Algorithm) String() string {
+	if a == Batch {
+		return "batch"
+	}
+	return "online"
+}
+
 // TrainingConfig holds the configuration parameters for training a Self-Organizing Map (SOM).
 type TrainingConfig struct {
-	Epochs             int         // Number of training epochs
-	LearningRate       decay.Decay // Learning rate decay function
-	NeighborhoodRadius decay.Decay // Neighborhood radius decay function
-	WeightDecay        decay.Decay // Weight decay coefficient decay function
-	ViSomLambda        float64     // ViSOM lambda resolution parameter
+	Algorithm          Algorithm        // Training algorithm, online (default) or batch
+	Init               Initialization   // Weight initialization strategy, random (default), pca, samples or data-range
+	Continue           bool             // Continue training from the SOM's current weights, skipping initialization
+	Epochs             int              // Number of training epochs
+	LearningRate       decay.Decay      // Learning rate decay function. Not used by batch training
+	NeighborhoodRadius decay.Decay      // Neighborhood radius decay function
+	WeightDecay        decay.Decay      // Weight decay coefficient decay function
+	ViSomLambda        float64          // ViSOM lambda resolution parameter
+	Growing            *GrowingConfig   // Parameters for growing the map during training. Optional
+	Hierarchy          *HierarchyConfig // Parameters for expanding the trained map into a hierarchy (GHSOM). Optional
+	Stopping           *StoppingConfig  // Criteria for stopping training early. Optional
+	Shuffle            bool             // Present data rows in random order, per epoch
+	Replacement        bool             // Sample data rows randomly with replacement
+	SamplesPerEpoch    int              // Number of data rows presented per epoch. Optional, defaults to the number of rows
+	WeightColumn       string           // Name of the data column with row weights. Optional, see [Trainer.SetWeights]
+	Balance            *BalanceConfig   // Class balancing by a categorical column. Optional, see [Trainer.SetClasses]
+	ValidationFraction float64          // Fraction of data rows to hold out for validation. Used by the CLI, see [Trainer.SetValidation]
+	ValidationInterval int              // Number of epochs between evaluations of the validation data. Optional, default 1
 }
 
 // Trainer is a struct that holds the necessary components for training a Self-Organizing Map (SOM).
@@ -32,6 +76,14 @@ type Trainer struct {
 	params *TrainingConfig
 	rng    *rand.Rand
 	center [][]float64
+
+	validation *Predictor
+	weights    []float64
+	classes    *classes
+
+	start       int
+	checkpoints *checkpointer
+	threads     int
 }
 
 // NewTrainer creates a new Trainer instance with the provided SOM, data tables, training configuration, and random number generator.
@@ -45,55 +97,274 @@ func NewTrainer(som *Som, tables []*table.Table, params *TrainingConfig, rng *ra
 	if som.ViSomMetric() == nil && params != nil && params.ViSomLambda != 0 {
 		return nil, fmt.Errorf("ViSOM update requires a ViSOM metric to be set")
 	}
+	if params != nil && params.Algorithm == Batch && params.ViSomLambda != 0 {
+		return nil, fmt.Errorf("ViSOM update is not supported by batch training")
+	}
+	if params != nil && params.Growing != nil {
+		if params.Growing.Epochs <= 0 {
+			return nil, fmt.Errorf("growing requires a positive number of epochs between insertions")
+		}
+		if params.Growing.MaxNodes < som.Size().Nodes() {
+			return nil, fmt.Errorf("maximum number of nodes for growing (%d) is smaller than the initial number of nodes (%d)",
+				params.Growing.MaxNodes, som.Size().Nodes())
+		}
+	}
 
 	return &Trainer{
-		som:    som,
-		tables: tables,
-		params: params,
-		rng:    rng,
+		som:     som,
+		tables:  tables,
+		params:  params,
+		rng:     rng,
+		threads: 1,
 	}, nil
 }
 
+// SetThreads sets the number of worker goroutines used for BMU search in batch training.
+// Values smaller than 1 use the number of available CPUs.
+// Online training is sequential, and results do not depend on the number of threads.
+func (t *Trainer) SetThreads(threads int) {
+	if threads < 1 {
+		threads = runtime.NumCPU()
+	}
+	t.threads = threads
+}
+
+// SetWeights sets weights for the data rows, e.g. sampling weights of survey data.
+// Each row's update of the SOM is scaled by its weight, relative to the mean weight.
+// With the batch algorithm, node weights are weighted means of the data.
+// Quantization errors in progress information are weighted means.
+//
+// An error is returned if the number of weights does not match the number of rows,
+// if any weight is negative or not finite, or if all weights are zero.
+func (t *Trainer) SetWeights(weights []float64) error {
+	if err := checkWeights(weights, t.tables[0].Rows()); err != nil {
+		return err
+	}
+	mean := 0.0
+	for _, w := range weights {
+		mean += w
+	}
+	mean /= float64(len(weights))
+
+	t.weights = make([]float64, len(weights))
+	for i, w := range weights {
+		t.weights[i] = w / mean
+	}
+	return nil
+}
+
+// weight returns the weight of the given data row, relative to the mean weight.
+// When balancing by inverse class frequency, the row's class weight is included.
+func (t *Trainer) weight(row int) float64 {
+	w := 1.0
+	if t.weights != nil {
+		w = t.weights[row]
+	}
+	if t.balanceMode() == InverseFrequency {
+		if cls := t.classes.indices[row]; cls >= 0 {
+			w *= t.classes.weights[cls]
+		}
+	}
+	return w
+}
+
+// rowWeights returns the weights of all data rows, or nil if all rows have the same weight.
+func (t *Trainer) rowWeights() []float64 {
+	if t.balanceMode() != InverseFrequency {
+		return t.weights
+	}
+	weights := make([]float64, t.tables[0].Rows())
+	for i := range weights {
+		weights[i] = t.weight(i)
+	}
+	return weights
+}
+
+// SetValidation sets validation data. The validation quantization and topographic errors are evaluated
+// every ValidationInterval epochs, as well as in the last epoch, and are reported in the progress information.
+// Validation data is also used for the validation plateau stopping criterion (see [StoppingConfig]).
+// Tables are assumed to be normalized.
+// An error is returned if the tables do not match the SOM.
+func (t *Trainer) SetValidation(tables []*table.Table) error {
+	pred, err := NewPredictor(t.som, tables)
+	if err != nil {
+		return err
+	}
+	t.validation = pred
+	return nil
+}
+
+// SetCheckpoints sets a callback for creating checkpoints during training.
+// The callback is called with the number of completed epochs, every epochs epochs,
+// and after an epoch when at least interval has passed since the last checkpoint.
+// Zero values disable the respective condition.
+//
+// The callback is called from the goroutine running [Trainer.Train], between epochs.
+// The SOM must not be modified by the callback.
+func (t *Trainer) SetCheckpoints(epochs int, interval time.Duration, callback func(epoch int)) {
+	t.checkpoints = &checkpointer{
+		epochs:   epochs,
+		interval: interval,
+		callback: callback,
+	}
+}
+
+// Resume sets the number of already completed epochs, for resuming training from a [Checkpoint].
+// Training then keeps the SOM's current weights, skips growing, and continues with the next epoch.
+// For results identical to an uninterrupted run, the random number generator must be restored
+// to the checkpoint's state, e.g. using [NewCountingSourceAt].
+func (t *Trainer) Resume(epoch int) {
+	t.start = epoch
+}
+
 // Train trains the Self-Organizing Map (SOM) using the provided training data and configuration.
 // It iterates through the specified number of epochs, updating the learning rate and neighborhood radius
 // at each epoch. For each epoch, it performs a single training iteration,
 // and sends the training progress information (epoch, learning rate, neighborhood radius, mean distance,
 // and quantization error) to the provided progress channel.
 // After all epochs are completed, the channel is closed.
+//
+// With the batch algorithm, the learning rate is not used, and weight decay is applied
+// after the update of each epoch.
+//
+// Before training, weights are initialized according to the configured [Initialization] strategy.
+// If Continue is set in the configuration, the SOM's current weights are kept instead.
+//
+// If growing is configured, the map is grown before the regular epochs (see [GrowingConfig]).
+// No progress information is sent during growth.
+//
+// If checkpoints are set (see [Trainer.SetCheckpoints]), they are created between epochs.
+// Training resumed via [Trainer.Resume] starts after the completed epochs.
+//
+// If stopping criteria are configured (see [StoppingConfig]), training may end before the configured
+// number of epochs, after running the remaining decay schedule in compressed form.
+// The Epoch of progress information is the position in the decay schedule.
+// Checkpoints are not created during the compressed schedule, and the state of stopping criteria
+// is not part of checkpoints.
 func (t *Trainer) Train(progress chan TrainingProgress) {
-	t.som.Randomize(t.rng)
+	t.TrainContext(context.Background(), progress)
+}
+
+// TrainContext trains the Self-Organizing Map (SOM) like [Trainer.Train], but stops early when the context is cancelled.
+// Cancellation is checked between epochs, so the SOM is always left in the state after a completed epoch.
+// The progress channel is closed in any case. Use the context's Err method to check whether training was cancelled.
+func (t *Trainer) TrainContext(ctx context.Context, progress chan TrainingProgress) {
+	resumed := t.start > 0
+	if !t.params.Continue && !resumed {
+		t.som.initialize(t.params.Init, t.tables, t.rng)
+	}
 
 	t.calcDataCenter()
 
-	var meanDist float64
-	var qError float64
-	var p TrainingProgress
-	for epoch := 0; epoch < t.params.Epochs; epoch++ {
-		alpha := t.params.LearningRate.Decay(epoch, t.params.Epochs)
-		radius := t.params.NeighborhoodRadius.Decay(epoch, t.params.Epochs)
-		decay := 0.0
-		if t.params.WeightDecay != nil {
-			decay = t.params.WeightDecay.Decay(epoch, t.params.Epochs)
+	if t.params.Growing != nil && !resumed {
+		t.grow(ctx)
+	}
+
+	if t.checkpoints != nil {
+		t.checkpoints.last = time.Now()
+	}
+
+	var stop *stopper
+	if t.params.Stopping != nil {
+		stop = newStopper(t.params.Stopping)
+	}
+
+	// Schedule positions remaining after a stopping criterion was met.
+	var final []int
+	epoch := t.start
+	for epoch < t.params.Epochs {
+		if ctx.Err() != nil {
+			break
 		}
 
-		if decay > 0 {
-			t.decayWeights(decay)
+		p := t.trainEpoch(epoch)
+		progress <- p
+
+		if final != nil {
+			final = final[1:]
+			if len(final) == 0 {
+				break
+			}
+			epoch = final[0]
+			continue
 		}
-		meanDist, qError = t.epoch(alpha, radius)
 
-		p.Epoch = epoch
-		p.Alpha = alpha
-		p.Radius = radius
-		p.WeightDecay = decay
-		p.MeanDist = meanDist
-		p.Error = qError
+		if t.checkpoints != nil && epoch+1 < t.params.Epochs {
+			t.checkpoints.check(epoch + 1)
+		}
 
-		progress <- p
+		if stop != nil && stop.check(p.Error, p.ValidationError) {
+			final = compressSchedule(epoch, t.params.Epochs, t.params.Stopping.FinalEpochs)
+			if len(final) == 0 {
+				break
+			}
+			epoch = final[0]
+			continue
+		}
+
+		epoch++
 	}
 
 	close(progress)
 }
 
+// trainEpoch runs a single training epoch, with decay functions evaluated at the given epoch.
+func (t *Trainer) trainEpoch(epoch int) TrainingProgress {
+	alpha := 0.0
+	if t.params.LearningRate != nil {
+		alpha = t.params.LearningRate.Decay(epoch, t.params.Epochs)
+	}
+	radius := t.params.NeighborhoodRadius.Decay(epoch, t.params.Epochs)
+	decay := 0.0
+	if t.params.WeightDecay != nil {
+		decay = t.params.WeightDecay.Decay(epoch, t.params.Epochs)
+	}
+
+	rows := t.sampleRows(epoch)
+
+	var meanDist, qError float64
+	if t.params.Algorithm == Batch {
+		meanDist, qError = t.batchEpoch(rows, radius)
+		if decay > 0 {
+			t.decayWeights(decay)
+		}
+	} else {
+		if decay > 0 {
+			t.decayWeights(decay)
+		}
+		meanDist, qError = t.epoch(rows, alpha, radius)
+	}
+
+	p := TrainingProgress{
+		Epoch:       epoch,
+		Alpha:       alpha,
+		Radius:      radius,
+		WeightDecay: decay,
+		MeanDist:    meanDist,
+		Error:       qError,
+	}
+	if t.balanceMode() != NoBalance {
+		p.ClassNames = t.classes.names
+		p.ClassShares = t.classShares(rows)
+	}
+	p.ValidationError, p.ValidationTopoError = math.NaN(), math.NaN()
+	if t.validation != nil {
+		p.Validation = true
+		interval := max(t.params.ValidationInterval, 1)
+		if epoch%interval == 0 || epoch == t.params.Epochs-1 {
+			p.ValidationError, p.ValidationTopoError = t.validationErrors()
+		}
+	}
+	return p
+}
+
+// validationErrors returns the quantization error (MSE) and the topographic error of the validation data.
+func (t *Trainer) validationErrors() (qError, topoError float64) {
+	eval := NewEvaluator(t.validation)
+	_, qError, _ = eval.Error()
+	return qError, eval.TopographicError(t.som.MapMetric())
+}
+
 func (t *Trainer) calcDataCenter() {
 	if t.params.WeightDecay == nil {
 		return
@@ -199,30 +470,16 @@ func (t *Trainer) propagateLabels(name string, probabilities []float64, counts [
 func (t *Trainer) updateLabelsFromNeighbors(x, y int,
 	self []float64, lay1 *layer.Layer, uMatrix [][]float64, sigma float64,
 	neigh neighborhood.Neighborhood) float64 {
-	sumWeights := 0.0
+	// BMU
+	sumWeights := t.updateLabels(self, lay1.GetNode(x, y), neigh.Weight(0, sigma))
 
-	w, h := t.som.Size().Width, t.som.Size().Height
-	dxMin, dxMax := max(x-1, 0)-x, min(x+1, w-1)-x
-	dyMin, dyMax := max(y-1, 0)-y, min(y+1, h-1)-y
-	for dx := dxMin; dx <= dxMax; dx++ {
-		for dy := dyMin; dy <= dyMax; dy++ {
-			if dx != 0 && dy != 0 {
-				continue // diagonal neighbors
-			}
-			var weight float64
-			if dx == 0 && dy == 0 {
-				// BMU
-				weight = neigh.Weight(0, sigma)
-			} else {
-				// Neighbor node
-				weight = neigh.Weight(uMatrix[2*y+dy][2*x+dx], sigma)
-			}
-
-			other := lay1.GetNode(x+dx, y+dy)
+	t.som.forEachNeighbor(x, y, func(x2, y2, row, col int) {
+		// Neighbor node
+		weight := neigh.Weight(uMatrix[row][col], sigma)
+		other := lay1.GetNode(x2, y2)
 
-			sumWeights += t.updateLabels(self, other, weight)
-		}
-	}
+		sumWeights += t.updateLabels(self, other, weight)
+	})
 	return sumWeights
 }
 
@@ -249,19 +506,14 @@ func (t *Trainer) createLabelLayers(name string, classes []string, probabilities
 }
 
 func (t *Trainer) calcPropagationSigma(uMatrix [][]float64) float64 {
-	w, h := t.som.Size().Width, t.som.Size().Height
-	values := make([]float64, (w-1)*h+w*(h-1))
-	idx := 0
-	for x := 0; x < w; x++ {
-		for y := 0; y < h; y++ {
-			if x < w-1 {
-				values[idx] = uMatrix[y*2][x*2+1]
-				idx++
-			}
-			if y < h-1 {
-				values[idx] = uMatrix[y*2+1][x*2]
-				idx++
+	values := []float64{}
+	for y := range uMatrix {
+		for x, v := range uMatrix[y] {
+			if x%2 == 0 && y%2 == 0 || math.IsNaN(v) {
+				// Node or empty space
+				continue
 			}
+			values = append(values, v)
 		}
 	}
 	slices.Sort(values)
@@ -324,19 +576,30 @@ func (t *Trainer) findLabels(classes []string, indices []int) ([]float64, []int,
 	return classCounter, totalCounter, nil
 }
 
-func (t *Trainer) epoch(alpha, radius float64) (meanDist, quantError float64) {
+// epoch runs an online training epoch, presenting the given rows, or all rows in order if rows is nil.
+func (t *Trainer) epoch(rows []int, alpha, radius float64) (meanDist, quantError float64) {
 	data := make([][]float64, len(t.tables))
-	rows := t.tables[0].Rows()
+	samples := t.tables[0].Rows()
+	if rows != nil {
+		samples = len(rows)
+	}
 
 	sumDist := 0.0
 	sumDistSq := 0.0
-	for i := 0; i < rows; i++ {
+	sumWeights := 0.0
+	for i := 0; i < samples; i++ {
+		row := i
+		if rows != nil {
+			row = rows[i]
+		}
 		for j := 0; j < len(t.tables); j++ {
-			data[j] = t.tables[j].GetRow(i)
+			data[j] = t.tables[j].GetRow(row)
 		}
-		dist := t.som.Learn(data, alpha, radius, t.params.ViSomLambda)
-		sumDist += dist
-		sumDistSq += dist * dist
+		w := t.weight(row)
+		dist := t.som.Learn(data, min(alpha*w, 1), radius, t.params.ViSomLambda)
+		sumDist += w * dist
+		sumDistSq += w * dist * dist
+		sumWeights += w
 
 		if t.params.ViSomLambda == 0 || i%10 != 0 { // SOM
 			continue
@@ -349,7 +612,101 @@ func (t *Trainer) epoch(alpha, radius float64) (meanDist, quantError float64) {
 		t.som.Learn(data, alpha, radius, t.params.ViSomLambda)
 	}
 
-	return sumDist / float64(rows), sumDistSq / float64(rows)
+	return sumDist / sumWeights, sumDistSq / sumWeights
+}
+
+// batchEpoch runs a batch training epoch, using the given rows, or all rows if rows is nil.
+// The BMU search is split over the Trainer's worker goroutines (see [Trainer.SetThreads]).
+func (t *Trainer) batchEpoch(rows []int, radius float64) (meanDist, quantError float64) {
+	samples := t.tables[0].Rows()
+	if rows != nil {
+		samples = len(rows)
+	}
+	bmus := make([]int, samples)
+	dists := make([]float64, samples)
+
+	forEachBlock(samples, t.threads, func(start, end int) {
+		data := make([][]float64, len(t.tables))
+		for i := start; i < end; i++ {
+			row := i
+			if rows != nil {
+				row = rows[i]
+			}
+			for j := 0; j < len(t.tables); j++ {
+				data[j] = t.tables[j].GetRow(row)
+			}
+			bmus[i], dists[i] = t.som.GetBMU(data)
+		}
+	})
+
+	sumDist := 0.0
+	sumDistSq := 0.0
+	sumWeights := 0.0
+	for i, dist := range dists {
+		row := i
+		if rows != nil {
+			row = rows[i]
+		}
+		w := t.weight(row)
+		sumDist += w * dist
+		sumDistSq += w * dist * dist
+		sumWeights += w
+	}
+
+	t.som.LearnBatch(t.tables, rows, bmus, t.rowWeights(), radius)
+
+	return sumDist / sumWeights, sumDistSq / sumWeights
+}
+
+// sampleRows returns the indices of the data rows to present in an epoch, in order.
+// Returns nil if all rows are presented in their original order.
+//
+// With replacement, rows are drawn uniformly at random. Otherwise, rows are presented in order,
+// or in random order per pass through the data if shuffling is enabled. Without shuffling,
+// the presented rows continue from those of the previous epoch if samples per epoch are set.
+//
+// With balanced classes (see [BalanceConfig]), rows are drawn with equal numbers per class,
+// in random order, regardless of shuffling and replacement.
+func (t *Trainer) sampleRows(epoch int) []int {
+	rows := t.tables[0].Rows()
+	samples := t.params.SamplesPerEpoch
+	if samples <= 0 {
+		samples = rows
+	}
+	if t.balanceMode() == Balanced {
+		return t.balancedRows(samples)
+	}
+	if rows == 0 || (!t.params.Shuffle && !t.params.Replacement && samples == rows) {
+		return nil
+	}
+
+	indices := make([]int, samples)
+	if t.params.Replacement {
+		for i := range indices {
+			indices[i] = t.rng.Intn(rows)
+		}
+		return indices
+	}
+
+	offset := 0
+	if !t.params.Shuffle {
+		offset = (epoch * samples) % rows
+	}
+	var order []int
+	for i := range indices {
+		if i%rows == 0 && (order == nil || t.params.Shuffle) {
+			if t.params.Shuffle {
+				order = t.rng.Perm(rows)
+			} else {
+				order = make([]int, rows)
+				for j := range order {
+					order[j] = (offset + j) % rows
+				}
+			}
+		}
+		indices[i] = order[i%rows]
+	}
+	return indices
 }
 
 func (t *Trainer) decayWeights(beta float64) {
@@ -358,17 +715,32 @@ func (t *Trainer) decayWeights(beta float64) {
 
 // TrainingProgress represents the progress of a training epoch.
 type TrainingProgress struct {
-	Epoch       int     // The current epoch number
-	Alpha       float64 // The current learning rate alpha
-	Radius      float64 // The current neighborhood radius
-	WeightDecay float64 // The weight decay factor
-	MeanDist    float64 // The mean distance of the training data to the SOM
-	Error       float64 // The quantization error (MSE)
+	Epoch       int       // The current epoch number
+	Alpha       float64   // The current learning rate alpha
+	Radius      float64   // The current neighborhood radius
+	WeightDecay float64   // The weight decay factor
+	MeanDist    float64   // The mean distance of the training data to the SOM
+	Error       float64   // The quantization error (MSE)
+	ClassNames  []string  // Names of the classes used for balancing. Nil without class balancing
+	ClassShares []float64 // Weighted share of each class among the presented rows. Nil without class balancing
+
+	Validation          bool    // Whether validation data is used
+	ValidationError     float64 // The quantization error (MSE) of the validation data. NaN if not evaluated in this epoch
+	ValidationTopoError float64 // The topographic error of the validation data. NaN if not evaluated in this epoch
 }
 
 // CsvHeader returns a CSV header row for the TrainingProgress struct fields, using the provided delimiter.
+// With validation data, columns are added for the validation errors.
+// With class balancing, a column is added for the share of each class.
 func (p *TrainingProgress) CsvHeader(delim rune) string {
-	return fmt.Sprintf("Epoch%cAlpha%cRadius%cDecay%cMeanDist%cError", delim, delim, delim, delim, delim)
+	header := fmt.Sprintf("Epoch%cAlpha%cRadius%cDecay%cMeanDist%cError", delim, delim, delim, delim, delim)
+	if p.Validation {
+		header += fmt.Sprintf("%cValError%cValTopoError", delim, delim)
+	}
+	for _, name := range p.ClassNames {
+		header += fmt.Sprintf("%cShare:%s", delim, name)
+	}
+	return header
 }
 
 // CsvRow returns a comma-separated string representation of the TrainingProgress struct fields.
@@ -380,5 +752,19 @@ func (p *TrainingProgress) CsvRow(delim rune) string {
 		strconv.FormatFloat(p.Radius, 'f', -1, 64), delim,
 		strconv.FormatFloat(p.WeightDecay, 'f', -1, 64), delim,
 		strconv.FormatFloat(p.MeanDist, 'f', -1, 64), delim,
-		strconv.FormatFloat(p.Error, 'f', -1, 64))
+		strconv.FormatFloat(p.Error, 'f', -1, 64)) + p.csvExtra(delim)
+}
+
+// csvExtra returns the optional validation and class share columns of a CSV row.
+func (p *TrainingProgress) csvExtra(delim rune) string {
+	row := ""
+	if p.Validation {
+		row += fmt.Sprintf("%c%s%c%s", delim,
+			strconv.FormatFloat(p.ValidationError, 'f', -1, 64), delim,
+			strconv.FormatFloat(p.ValidationTopoError, 'f', -1, 64))
+	}
+	for _, share := range p.ClassShares {
+		row += fmt.Sprintf("%c%s", delim, strconv.FormatFloat(share, 'f', -1, 64))
+	}
+	return row
 }
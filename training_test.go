@@ -1,8 +1,12 @@
 package som
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"math/rand"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/mlange-42/som/decay"
@@ -214,3 +218,245 @@ func TestTrainerTrain(t *testing.T) {
 		}
 	})
 }
+
+func TestTrainerTrainBatch(t *testing.T) {
+	params := TrainingConfig{
+		Algorithm:          Batch,
+		Epochs:             10,
+		NeighborhoodRadius: &decay.Linear{Start: 2, End: 0.5},
+		WeightDecay:        &decay.Linear{Start: 0.1, End: 0.0},
+	}
+	somParams := SomConfig{
+		Size: layer.Size{Width: 3, Height: 2},
+		Layers: []*LayerDef{
+			{
+				Columns: []string{"x", "y"},
+				Norm:    []norm.Normalizer{&norm.Identity{}, &norm.Identity{}},
+				Weight:  1.0,
+			},
+		},
+		Neighborhood: &neighborhood.Gaussian{},
+		MapMetric:    &neighborhood.EuclideanMetric{},
+	}
+
+	train := func(rows [][]float64, threads int) *Som {
+		som, err := New(&somParams)
+		assert.NoError(t, err)
+
+		tab := table.New([]string{"x", "y"}, len(rows))
+		for i, row := range rows {
+			tab.Set(i, 0, row[0])
+			tab.Set(i, 1, row[1])
+		}
+
+		trainer, err := NewTrainer(som, []*table.Table{tab}, &params, rand.New(rand.NewSource(1)))
+		assert.NoError(t, err)
+		trainer.SetThreads(threads)
+
+		progress := make(chan TrainingProgress)
+		go trainer.Train(progress)
+		for p := range progress {
+			assert.Equal(t, 0.0, p.Alpha)
+		}
+		return som
+	}
+
+	rows := [][]float64{{0, 0}, {1, 0}, {0, 1}, {1, 1}, {0.5, math.NaN()}}
+	s1 := train(rows, 1)
+	s3 := train(rows, 3)
+	assert.Equal(t, s1.layers[0].Weights(), s3.layers[0].Weights(), "Batch training should not depend on the number of threads")
+	slices.Reverse(rows)
+	s2 := train(rows, 1)
+
+	for i, v := range s1.layers[0].Weights() {
+		assert.False(t, math.IsNaN(v))
+		assert.GreaterOrEqual(t, v, 0.0)
+		assert.LessOrEqual(t, v, 1.0)
+		assert.InDelta(t, v, s2.layers[0].Weights()[i], 1e-9, "Batch training should not depend on row order")
+	}
+
+	p := params
+	p.ViSomLambda = 1.0
+	somParams.ViSomMetric = &neighborhood.EuclideanMetric{}
+	s, err := New(&somParams)
+	assert.NoError(t, err)
+	_, err = NewTrainer(s, []*table.Table{table.New([]string{"x", "y"}, 1)}, &p, rand.New(rand.NewSource(1)))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "batch")
+}
+
+func TestTrainerContinue(t *testing.T) {
+	params := TrainingConfig{
+		Algorithm:          Batch,
+		Epochs:             0,
+		NeighborhoodRadius: &decay.Linear{Start: 0.2, End: 0.1},
+		Continue:           true,
+	}
+	somParams := SomConfig{
+		Size: layer.Size{Width: 2, Height: 1},
+		Layers: []*LayerDef{
+			{
+				Columns: []string{"x"},
+				Norm:    []norm.Normalizer{&norm.Identity{}},
+				Weight:  1.0,
+				Weights: []float64{3, 7},
+			},
+		},
+		Neighborhood: &neighborhood.Gaussian{},
+		MapMetric:    &neighborhood.EuclideanMetric{},
+	}
+	tab, err := table.NewWithData([]string{"x"}, []float64{2, 3, 7, 8})
+	assert.NoError(t, err)
+
+	train := func(params *TrainingConfig) *Som {
+		som, err := New(&somParams)
+		assert.NoError(t, err)
+		trainer, err := NewTrainer(som, []*table.Table{tab}, params, rand.New(rand.NewSource(1)))
+		assert.NoError(t, err)
+
+		progress := make(chan TrainingProgress)
+		go trainer.Train(progress)
+		for range progress {
+		}
+		return som
+	}
+
+	s := train(&params)
+	assert.Equal(t, []float64{3, 7}, s.layers[0].Weights())
+
+	p := params
+	p.Continue = false
+	s = train(&p)
+	assert.NotEqual(t, []float64{3, 7}, s.layers[0].Weights())
+
+	p = params
+	p.Epochs = 5
+	s = train(&p)
+	assert.InDelta(t, 2.5, s.layers[0].Weights()[0], 0.5)
+	assert.InDelta(t, 7.5, s.layers[0].Weights()[1], 0.5)
+}
+
+func TestTrainerTrainContext(t *testing.T) {
+	s := createGrowingSom(t, layer.Size{Width: 3, Height: 1}, nil, neighborhood.Wrap{})
+	tab, err := table.NewWithData([]string{"x"}, []float64{0, 1, 2, 3})
+	assert.NoError(t, err)
+
+	params := TrainingConfig{
+		Epochs:             1000,
+		LearningRate:       &decay.Linear{Start: 0.5, End: 0.01},
+		NeighborhoodRadius: &decay.Linear{Start: 2, End: 0.7},
+	}
+	trainer, err := NewTrainer(s, []*table.Table{tab}, &params, rand.New(rand.NewSource(1)))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	progress := make(chan TrainingProgress)
+	go trainer.TrainContext(ctx, progress)
+
+	epochs := 0
+	for range progress {
+		epochs++
+		if epochs == 10 {
+			cancel()
+		}
+	}
+	// The epoch after cancellation may already have started.
+	assert.GreaterOrEqual(t, epochs, 10)
+	assert.LessOrEqual(t, epochs, 11)
+	assert.Error(t, ctx.Err())
+}
+
+func TestTrainerSampleRows(t *testing.T) {
+	s := createGrowingSom(t, layer.Size{Width: 3, Height: 1}, nil, neighborhood.Wrap{})
+	tab := table.New([]string{"x"}, 5)
+
+	sample := func(params TrainingConfig, epoch int) []int {
+		trainer, err := NewTrainer(s, []*table.Table{tab}, &params, rand.New(rand.NewSource(1)))
+		assert.NoError(t, err)
+		return trainer.sampleRows(epoch)
+	}
+
+	assert.Nil(t, sample(TrainingConfig{}, 0))
+	assert.Equal(t, []int{0, 1, 2}, sample(TrainingConfig{SamplesPerEpoch: 3}, 0))
+	assert.Equal(t, []int{3, 4, 0}, sample(TrainingConfig{SamplesPerEpoch: 3}, 1))
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 0, 1}, sample(TrainingConfig{SamplesPerEpoch: 7}, 0))
+
+	rows := sample(TrainingConfig{Shuffle: true}, 0)
+	assert.Equal(t, rows, sample(TrainingConfig{Shuffle: true}, 0), "shuffling should be reproducible")
+	assert.ElementsMatch(t, []int{0, 1, 2, 3, 4}, rows)
+
+	rows = sample(TrainingConfig{Shuffle: true, SamplesPerEpoch: 10}, 0)
+	assert.ElementsMatch(t, []int{0, 1, 2, 3, 4}, rows[:5])
+	assert.ElementsMatch(t, []int{0, 1, 2, 3, 4}, rows[5:])
+
+	rows = sample(TrainingConfig{Replacement: true, SamplesPerEpoch: 20}, 0)
+	assert.Len(t, rows, 20)
+	for _, r := range rows {
+		assert.GreaterOrEqual(t, r, 0)
+		assert.Less(t, r, 5)
+	}
+}
+
+func TestTrainerWeights(t *testing.T) {
+	s := createGrowingSom(t, layer.Size{Width: 1, Height: 1}, nil, neighborhood.Wrap{})
+	tab, err := table.NewWithData([]string{"x"}, []float64{0, 4})
+	assert.NoError(t, err)
+
+	params := TrainingConfig{
+		Algorithm:          Batch,
+		Epochs:             1,
+		NeighborhoodRadius: &decay.Linear{Start: 1, End: 1},
+	}
+	trainer, err := NewTrainer(s, []*table.Table{tab}, &params, rand.New(rand.NewSource(1)))
+	assert.NoError(t, err)
+
+	assert.Error(t, trainer.SetWeights([]float64{1}))
+	assert.Error(t, trainer.SetWeights([]float64{1, -1}))
+	assert.NoError(t, trainer.SetWeights([]float64{3, 1}))
+
+	progress := make(chan TrainingProgress)
+	go trainer.Train(progress)
+	for range progress {
+	}
+
+	// Weighted mean of the data.
+	assert.Equal(t, []float64{1}, s.layers[0].Weights())
+}
+
+func TestTrainerValidation(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	tab := table.New([]string{"x"}, 50)
+	for i := 0; i < tab.Rows(); i++ {
+		tab.Set(i, 0, rng.Float64())
+	}
+
+	s := createGrowingSom(t, layer.Size{Width: 3, Height: 1}, nil, neighborhood.Wrap{})
+	params := TrainingConfig{
+		Epochs:             10,
+		LearningRate:       &decay.Linear{Start: 0.5, End: 0.01},
+		NeighborhoodRadius: &decay.Linear{Start: 2, End: 0.5},
+		ValidationInterval: 4,
+	}
+	trainer, err := NewTrainer(s, []*table.Table{tab.SelectRows([]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})}, &params, rng)
+	assert.NoError(t, err)
+	assert.NoError(t, trainer.SetValidation([]*table.Table{tab.SelectRows([]int{10, 11, 12, 13, 14})}))
+
+	progress := make(chan TrainingProgress)
+	go trainer.Train(progress)
+
+	evaluated := []int{}
+	var last TrainingProgress
+	for p := range progress {
+		assert.True(t, p.Validation)
+		if !math.IsNaN(p.ValidationError) {
+			evaluated = append(evaluated, p.Epoch)
+			assert.GreaterOrEqual(t, p.ValidationTopoError, 0.0)
+			assert.LessOrEqual(t, p.ValidationTopoError, 1.0)
+		}
+		last = p
+	}
+	assert.Equal(t, []int{0, 4, 8, 9}, evaluated)
+	assert.Equal(t, "Epoch,Alpha,Radius,Decay,MeanDist,Error,ValError,ValTopoError", last.CsvHeader(','))
+	assert.Equal(t, 8, strings.Count(last.CsvRow(','), ",")+1)
+}
@@ -0,0 +1,251 @@
+package som
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+
+	"github.com/mlange-42/som/decay"
+	"github.com/mlange-42/som/layer"
+)
+
+// Search is the strategy for generating candidate configurations in hyperparameter tuning.
+type Search uint8
+
+const (
+	GridSearch   Search = iota // All combinations of the parameter values
+	RandomSearch               // Random combinations of parameter values and ranges
+)
+
+var searches = map[string]Search{
+	"grid":   GridSearch,
+	"random": RandomSearch,
+}
+
+// GetSearch returns the search strategy with the given name.
+// Options are grid and random.
+func GetSearch(name string) (Search, bool) {
+	s, ok := searches[name]
+	return s, ok
+}
+
+// String returns the name of the search strategy.
+func (s Search) String() string {
+	if s == RandomSearch {
+		return "random"
+	}
+	return "grid"
+}
+
+// FloatParam is a numeric tuning parameter, given either by a list of values,
+// or by a range for random search.
+type FloatParam struct {
+	Values []float64 // Values to use. If empty, values are drawn uniformly from [Min, Max]
+	Min    float64   // Lower bound of the range for random search
+	Max    float64   // Upper bound of the range for random search
+}
+
+// LayerWeightParam is a tuning parameter for the weight of a layer.
+type LayerWeightParam struct {
+	Layer  string     // Name of the layer
+	Weight FloatParam // Layer weights
+}
+
+// Tuning holds the search space and the evaluation settings for hyperparameter tuning.
+// Parameters without values are not tuned, and keep the values of the base configuration.
+type Tuning struct {
+	Search          Search             // Search strategy
+	Samples         int                // Number of candidates for random search
+	CrossValidation CrossValidation    // Cross-validation settings for scoring candidates
+	Sizes           []layer.Size       // Map sizes
+	Epochs          []int              // Numbers of training epochs
+	Alphas          []string           // Learning rate decay functions, see [decay.FromString]
+	Radii           []string           // Neighborhood radius decay functions, see [decay.FromString]
+	Lambdas         *FloatParam        // ViSOM resolution parameters. Optional
+	LayerWeights    []LayerWeightParam // Layer weights
+}
+
+// Candidate is a configuration to evaluate in hyperparameter tuning.
+type Candidate struct {
+	Config   *SomConfig      // SOM configuration
+	Training *TrainingConfig // Training configuration
+	Values   []string        // Formatted values of the tuned parameters, see [Tuning.Parameters]
+}
+
+// dimension is a single tuned parameter.
+type dimension struct {
+	name   string
+	values int // Number of discrete values, or 0 for a continuous range
+	min    float64
+	max    float64
+	// apply sets the parameter to the value with the given index, or to the given continuous value.
+	apply func(c *SomConfig, p *TrainingConfig, idx int, value float64) (string, error)
+}
+
+// Parameters returns the names of the tuned parameters.
+func (t *Tuning) Parameters() []string {
+	dims := t.dimensions()
+	names := make([]string, len(dims))
+	for i, d := range dims {
+		names[i] = d.name
+	}
+	return names
+}
+
+// Candidates generates the candidate configurations from the base configurations.
+// For grid search, candidates are all combinations of parameter values.
+// For random search, Samples candidates are drawn, with random parameter values or values from ranges.
+//
+// An error is returned if grid search is used with parameter ranges, or if any parameter value is invalid.
+func (t *Tuning) Candidates(config *SomConfig, params *TrainingConfig, rng *rand.Rand) ([]Candidate, error) {
+	dims := t.dimensions()
+
+	var choices [][]int
+	if t.Search == GridSearch {
+		count := 1
+		for _, d := range dims {
+			if d.values == 0 {
+				return nil, fmt.Errorf("grid search requires a list of values for parameter %s", d.name)
+			}
+			count *= d.values
+		}
+		for i := 0; i < count; i++ {
+			choice := make([]int, len(dims))
+			rem := i
+			for j := len(dims) - 1; j >= 0; j-- {
+				choice[j] = rem % dims[j].values
+				rem /= dims[j].values
+			}
+			choices = append(choices, choice)
+		}
+	} else {
+		if t.Samples <= 0 {
+			return nil, fmt.Errorf("random search requires a positive number of samples")
+		}
+		for i := 0; i < t.Samples; i++ {
+			choice := make([]int, len(dims))
+			for j, d := range dims {
+				if d.values > 0 {
+					choice[j] = rng.Intn(d.values)
+				} else {
+					choice[j] = -1
+				}
+			}
+			choices = append(choices, choice)
+		}
+	}
+
+	candidates := make([]Candidate, len(choices))
+	for i, choice := range choices {
+		c, p := copyConfig(config), *params
+		for _, l := range c.Layers {
+			l.Weights = nil
+		}
+		values := make([]string, len(dims))
+		for j, d := range dims {
+			value := 0.0
+			if choice[j] < 0 {
+				value = d.min + rng.Float64()*(d.max-d.min)
+			}
+			var err error
+			values[j], err = d.apply(c, &p, choice[j], value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		candidates[i] = Candidate{Config: c, Training: &p, Values: values}
+	}
+	return candidates, nil
+}
+
+func (t *Tuning) dimensions() []dimension {
+	dims := []dimension{}
+	if len(t.Sizes) > 0 {
+		dims = append(dims, dimension{
+			name:   "size",
+			values: len(t.Sizes),
+			apply: func(c *SomConfig, p *TrainingConfig, idx int, value float64) (string, error) {
+				c.Size = t.Sizes[idx]
+				return fmt.Sprintf("%dx%d", c.Size.Width, c.Size.Height), nil
+			},
+		})
+	}
+	if len(t.Epochs) > 0 {
+		dims = append(dims, dimension{
+			name:   "epochs",
+			values: len(t.Epochs),
+			apply: func(c *SomConfig, p *TrainingConfig, idx int, value float64) (string, error) {
+				p.Epochs = t.Epochs[idx]
+				return strconv.Itoa(p.Epochs), nil
+			},
+		})
+	}
+	if len(t.Alphas) > 0 {
+		dims = append(dims, dimension{
+			name:   "alpha",
+			values: len(t.Alphas),
+			apply: func(c *SomConfig, p *TrainingConfig, idx int, value float64) (string, error) {
+				var err error
+				p.LearningRate, err = decay.FromString(t.Alphas[idx])
+				return t.Alphas[idx], err
+			},
+		})
+	}
+	if len(t.Radii) > 0 {
+		dims = append(dims, dimension{
+			name:   "radius",
+			values: len(t.Radii),
+			apply: func(c *SomConfig, p *TrainingConfig, idx int, value float64) (string, error) {
+				var err error
+				p.NeighborhoodRadius, err = decay.FromString(t.Radii[idx])
+				return t.Radii[idx], err
+			},
+		})
+	}
+	if t.Lambdas != nil {
+		dims = append(dims, floatDimension("lambda", t.Lambdas, func(c *SomConfig, p *TrainingConfig, value float64) error {
+			p.ViSomLambda = value
+			return nil
+		}))
+	}
+	for _, lw := range t.LayerWeights {
+		dims = append(dims, floatDimension("weight:"+lw.Layer, &lw.Weight, func(c *SomConfig, p *TrainingConfig, value float64) error {
+			for _, l := range c.Layers {
+				if l.Name == lw.Layer {
+					l.Weight = value
+					return nil
+				}
+			}
+			return fmt.Errorf("layer %s for tuning weights not found", lw.Layer)
+		}))
+	}
+	return dims
+}
+
+// floatDimension creates a dimension for a numeric parameter.
+func floatDimension(name string, param *FloatParam, set func(c *SomConfig, p *TrainingConfig, value float64) error) dimension {
+	return dimension{
+		name:   name,
+		values: len(param.Values),
+		min:    param.Min,
+		max:    param.Max,
+		apply: func(c *SomConfig, p *TrainingConfig, idx int, value float64) (string, error) {
+			if idx >= 0 {
+				value = param.Values[idx]
+			}
+			return strconv.FormatFloat(value, 'f', -1, 64), set(c, p, value)
+		},
+	}
+}
+
+// copyConfig returns a copy of the SOM configuration, with copies of the layer definitions.
+// Normalizers and metrics are shared with the original.
+func copyConfig(config *SomConfig) *SomConfig {
+	c := *config
+	c.Layers = make([]*LayerDef, len(config.Layers))
+	for i, l := range config.Layers {
+		lay := *l
+		c.Layers[i] = &lay
+	}
+	return &c
+}
@@ -0,0 +1,64 @@
+package som
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/mlange-42/som/layer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTuningCandidates(t *testing.T) {
+	config, params, _ := createCrossValidationData(t)
+
+	t.Run("Grid", func(t *testing.T) {
+		tuning := Tuning{
+			Search:       GridSearch,
+			Sizes:        []layer.Size{{Width: 2, Height: 1}, {Width: 3, Height: 2}},
+			Alphas:       []string{"linear 0.5 0.01", "linear 0.2 0.01"},
+			LayerWeights: []LayerWeightParam{{Layer: "cls", Weight: FloatParam{Values: []float64{0.5, 1, 2}}}},
+		}
+		assert.Equal(t, []string{"size", "alpha", "weight:cls"}, tuning.Parameters())
+
+		candidates, err := tuning.Candidates(config, params, rand.New(rand.NewSource(1)))
+		assert.NoError(t, err)
+		assert.Len(t, candidates, 12)
+		assert.Equal(t, []string{"2x1", "linear 0.5 0.01", "0.5"}, candidates[0].Values)
+		assert.Equal(t, []string{"3x2", "linear 0.2 0.01", "2"}, candidates[11].Values)
+
+		last := candidates[11]
+		assert.Equal(t, layer.Size{Width: 3, Height: 2}, last.Config.Size)
+		assert.Equal(t, 2.0, last.Config.Layers[1].Weight)
+		assert.Equal(t, 0.2, last.Training.LearningRate.Decay(0, 10))
+
+		// Base configuration is not modified.
+		assert.Equal(t, layer.Size{Width: 4, Height: 1}, config.Size)
+		assert.Equal(t, 0.0, config.Layers[1].Weight)
+		assert.Equal(t, 0.5, params.LearningRate.Decay(0, 10))
+
+		tuning.Lambdas = &FloatParam{Min: 0, Max: 1}
+		_, err = tuning.Candidates(config, params, rand.New(rand.NewSource(1)))
+		assert.Error(t, err)
+	})
+
+	t.Run("Random", func(t *testing.T) {
+		tuning := Tuning{
+			Search:  RandomSearch,
+			Samples: 10,
+			Epochs:  []int{10, 20},
+			Lambdas: &FloatParam{Min: 0.1, Max: 0.2},
+		}
+		candidates, err := tuning.Candidates(config, params, rand.New(rand.NewSource(1)))
+		assert.NoError(t, err)
+		assert.Len(t, candidates, 10)
+		for _, c := range candidates {
+			assert.Contains(t, []int{10, 20}, c.Training.Epochs)
+			assert.GreaterOrEqual(t, c.Training.ViSomLambda, 0.1)
+			assert.Less(t, c.Training.ViSomLambda, 0.2)
+		}
+
+		tuning.LayerWeights = []LayerWeightParam{{Layer: "unknown", Weight: FloatParam{Values: []float64{1}}}}
+		_, err = tuning.Candidates(config, params, rand.New(rand.NewSource(1)))
+		assert.Error(t, err)
+	})
+}
@@ -2,6 +2,7 @@ package som
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/mlange-42/som/table"
 )
@@ -46,3 +47,22 @@ func checkTables(som *Som, tables []*table.Table) error {
 	}
 	return nil
 }
+
+// checkWeights checks that there is a valid, non-negative weight for each of the given number of rows,
+// and that not all weights are zero.
+func checkWeights(weights []float64, rows int) error {
+	if len(weights) != rows {
+		return fmt.Errorf("number of weights (%d) does not match number of rows (%d)", len(weights), rows)
+	}
+	sum := 0.0
+	for i, w := range weights {
+		if math.IsNaN(w) || math.IsInf(w, 0) || w < 0 {
+			return fmt.Errorf("invalid weight %f in row %d; weights must be finite and non-negative", w, i)
+		}
+		sum += w
+	}
+	if sum == 0 {
+		return fmt.Errorf("all weights are zero")
+	}
+	return nil
+}
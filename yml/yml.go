@@ -3,6 +3,8 @@ package yml
 import (
 	"bytes"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/mlange-42/som"
 	"github.com/mlange-42/som/decay"
@@ -20,28 +22,84 @@ type ymlLayer struct {
 	Metric      string
 	Weight      float64   `yaml:",omitempty"`
 	Categorical bool      `yaml:",omitempty"`
+	Rescale     bool      `yaml:"rescale-missing,omitempty"`
+	ColWeights  []float64 `yaml:"column-weights,flow,omitempty"`
 	Data        []float64 `yaml:",flow,omitempty"`
 }
 
 type ymlSom struct {
 	Size         [2]int `yaml:",flow"`
+	Topology     string `yaml:",omitempty"`
+	Wrap         string `yaml:",omitempty"`
 	Neighborhood string
 	Metric       string
 	ViSomMetric  string `yaml:"visom-metric,omitempty"`
 	Layers       []*ymlLayer
+	Children     []*ymlChild `yaml:",omitempty"`
+}
+
+type ymlChild struct {
+	Node int
+	Som  ymlSom
 }
 
 type ymlTraining struct {
+	Algorithm   string `yaml:",omitempty"`
+	Init        string `yaml:",omitempty"`
 	Epochs      int
-	Alpha       string  `yaml:",omitempty"`
-	Radius      string  `yaml:",omitempty"`
-	WeightDecay string  `yaml:"weight-decay,omitempty"`
-	Lambda      float64 `yaml:",omitempty"`
+	Alpha       string        `yaml:",omitempty"`
+	Radius      string        `yaml:",omitempty"`
+	WeightDecay string        `yaml:"weight-decay,omitempty"`
+	Lambda      float64       `yaml:",omitempty"`
+	Shuffle     bool          `yaml:",omitempty"`
+	Replacement bool          `yaml:",omitempty"`
+	Samples     int           `yaml:"samples-per-epoch,omitempty"`
+	WeightCol   string        `yaml:"weight-column,omitempty"`
+	Growing     *ymlGrowing   `yaml:",omitempty"`
+	Hierarchy   *ymlHierarchy `yaml:",omitempty"`
+	Stopping    *ymlStopping  `yaml:",omitempty"`
+	Balance     *ymlBalance   `yaml:",omitempty"`
+	ValFraction float64       `yaml:"validation-fraction,omitempty"`
+	ValInterval int           `yaml:"validation-interval,omitempty"`
+}
+
+type ymlBalance struct {
+	Column string
+	Mode   string `yaml:",omitempty"`
+}
+
+type ymlStopping struct {
+	Tolerance   float64 `yaml:",omitempty"`
+	Window      int     `yaml:",omitempty"`
+	MaxTime     string  `yaml:"max-time,omitempty"`
+	Patience    int     `yaml:",omitempty"`
+	FinalEpochs int     `yaml:"final-epochs,omitempty"`
+}
+
+type ymlHierarchy struct {
+	Size     [2]int `yaml:",flow"`
+	Tau      float64
+	MaxDepth int `yaml:"max-depth"`
+	MinRows  int `yaml:"min-rows,omitempty"`
+}
+
+type ymlGrowing struct {
+	MaxNodes       int     `yaml:"max-nodes"`
+	ErrorThreshold float64 `yaml:"error-threshold,omitempty"`
+	Epochs         int
+}
+
+type ymlCheckpoint struct {
+	Epoch  int
+	Epochs int
+	Seed   int64
+	Draws  uint64
 }
 
 type ymlConfig struct {
-	Som      ymlSom
-	Training *ymlTraining `yaml:",omitempty"`
+	Som        ymlSom
+	Training   *ymlTraining   `yaml:",omitempty"`
+	Checkpoint *ymlCheckpoint `yaml:",omitempty"`
 }
 
 func ToSomConfig(ymlData []byte) (*som.SomConfig, *som.TrainingConfig, error) {
@@ -55,77 +113,202 @@ func ToSomConfig(ymlData []byte) (*som.SomConfig, *som.TrainingConfig, error) {
 		return nil, nil, err
 	}
 
-	neigh, ok := neighborhood.GetNeighborhood(yml.Som.Neighborhood)
+	conf, err := toSomConfig(&yml.Som)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	training, err := toTrainingConfig(yml.Training, conf.ViSomMetric != nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return conf, training, nil
+}
+
+func toSomConfig(yml *ymlSom) (*som.SomConfig, error) {
+	neigh, ok := neighborhood.GetNeighborhood(yml.Neighborhood)
 	if !ok {
-		return nil, nil, fmt.Errorf("unknown neighborhood: %s", yml.Som.Neighborhood)
+		return nil, fmt.Errorf("unknown neighborhood: %s", yml.Neighborhood)
 	}
-	metric, ok := neighborhood.GetMetric(yml.Som.Metric)
+	metric, ok := neighborhood.GetMetric(yml.Metric)
 	if !ok {
-		return nil, nil, fmt.Errorf("unknown neighborhood metric: %s", yml.Som.Metric)
+		return nil, fmt.Errorf("unknown neighborhood metric: %s", yml.Metric)
 	}
 	var viSomMetric neighborhood.Metric
-	if yml.Som.ViSomMetric != "" {
-		viSomMetric, ok = neighborhood.GetMetric(yml.Som.ViSomMetric)
+	if yml.ViSomMetric != "" {
+		viSomMetric, ok = neighborhood.GetMetric(yml.ViSomMetric)
 		if !ok {
-			return nil, nil, fmt.Errorf("unknown ViSOM neighborhood metric: %s", yml.Som.ViSomMetric)
+			return nil, fmt.Errorf("unknown ViSOM neighborhood metric: %s", yml.ViSomMetric)
 		}
 	}
 
-	conf := som.SomConfig{
-		Size:         layer.Size{Width: yml.Som.Size[0], Height: yml.Som.Size[1]},
+	var topology neighborhood.Topology
+	if yml.Topology != "" {
+		topology, ok = neighborhood.GetTopology(yml.Topology)
+		if !ok {
+			return nil, fmt.Errorf("unknown topology: %s", yml.Topology)
+		}
+	}
+
+	var wrap neighborhood.Wrap
+	if yml.Wrap != "" {
+		wrap, ok = neighborhood.GetWrap(yml.Wrap)
+		if !ok {
+			return nil, fmt.Errorf("unknown wrap: %s", yml.Wrap)
+		}
+	}
+
+	conf := &som.SomConfig{
+		Size:         layer.Size{Width: yml.Size[0], Height: yml.Size[1]},
 		Layers:       []*som.LayerDef{},
 		Neighborhood: neigh,
 		MapMetric:    metric,
 		ViSomMetric:  viSomMetric,
+		Topology:     topology,
+		Wrap:         wrap,
 	}
-	for _, l := range yml.Som.Layers {
-		lay, err := createLayer(&yml.Som, l)
+	for _, l := range yml.Layers {
+		lay, err := createLayer(yml, l)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 		conf.Layers = append(conf.Layers, lay)
 	}
 
-	var training *som.TrainingConfig
+	return conf, nil
+}
 
-	if yml.Training != nil {
-		if yml.Training.Lambda != 0 && viSomMetric == nil {
-			return nil, nil, fmt.Errorf("ViSOM lambda provided, but no ViSOM metric")
+func toTrainingConfig(yml *ymlTraining, hasViSomMetric bool) (*som.TrainingConfig, error) {
+	if yml == nil {
+		return nil, nil
+	}
+	if yml.Lambda != 0 && !hasViSomMetric {
+		return nil, fmt.Errorf("ViSOM lambda provided, but no ViSOM metric")
+	}
+
+	var ok bool
+	var err error
+	algorithm := som.Online
+	if yml.Algorithm != "" {
+		algorithm, ok = som.GetAlgorithm(yml.Algorithm)
+		if !ok {
+			return nil, fmt.Errorf("unknown training algorithm: %s", yml.Algorithm)
+		}
+	}
+
+	initialization := som.InitRandom
+	if yml.Init != "" {
+		initialization, ok = som.GetInitialization(yml.Init)
+		if !ok {
+			return nil, fmt.Errorf("unknown initialization: %s", yml.Init)
 		}
+	}
 
-		alpha, err := decay.FromString(yml.Training.Alpha)
+	var alpha decay.Decay
+	if algorithm == som.Online || yml.Alpha != "" {
+		alpha, err = decay.FromString(yml.Alpha)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
-		radius, err := decay.FromString(yml.Training.Radius)
+	}
+	radius, err := decay.FromString(yml.Radius)
+	if err != nil {
+		return nil, err
+	}
+
+	var wtDecay decay.Decay
+	if yml.WeightDecay != "" {
+		wtDecay, err = decay.FromString(yml.WeightDecay)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
+		}
+	}
+
+	var growing *som.GrowingConfig
+	if yml.Growing != nil {
+		growing = &som.GrowingConfig{
+			MaxNodes:       yml.Growing.MaxNodes,
+			ErrorThreshold: yml.Growing.ErrorThreshold,
+			Epochs:         yml.Growing.Epochs,
+		}
+	}
+
+	var hierarchy *som.HierarchyConfig
+	if yml.Hierarchy != nil {
+		hierarchy = &som.HierarchyConfig{
+			Size:     layer.Size{Width: yml.Hierarchy.Size[0], Height: yml.Hierarchy.Size[1]},
+			Tau:      yml.Hierarchy.Tau,
+			MaxDepth: yml.Hierarchy.MaxDepth,
+			MinRows:  yml.Hierarchy.MinRows,
 		}
+	}
 
-		var wtDecay decay.Decay
-		if yml.Training.WeightDecay != "" {
-			wtDecay, err = decay.FromString(yml.Training.WeightDecay)
+	var stopping *som.StoppingConfig
+	if yml.Stopping != nil {
+		var maxTime time.Duration
+		if yml.Stopping.MaxTime != "" {
+			maxTime, err = time.ParseDuration(yml.Stopping.MaxTime)
 			if err != nil {
-				return nil, nil, err
+				return nil, err
 			}
 		}
+		stopping = &som.StoppingConfig{
+			Tolerance:   yml.Stopping.Tolerance,
+			Window:      yml.Stopping.Window,
+			MaxTime:     maxTime,
+			Patience:    yml.Stopping.Patience,
+			FinalEpochs: yml.Stopping.FinalEpochs,
+		}
+	}
+
+	if yml.ValFraction < 0 || yml.ValFraction >= 1 {
+		return nil, fmt.Errorf("validation fraction must be in range [0, 1), got %f", yml.ValFraction)
+	}
 
-		training = &som.TrainingConfig{
-			Epochs:             yml.Training.Epochs,
-			LearningRate:       alpha,
-			NeighborhoodRadius: radius,
-			WeightDecay:        wtDecay,
-			ViSomLambda:        yml.Training.Lambda,
+	var balance *som.BalanceConfig
+	if yml.Balance != nil {
+		mode := som.Balanced
+		if yml.Balance.Mode != "" {
+			mode, ok = som.GetBalance(yml.Balance.Mode)
+			if !ok {
+				return nil, fmt.Errorf("unknown class balancing mode: %s", yml.Balance.Mode)
+			}
+		}
+		if yml.Balance.Column == "" && mode != som.NoBalance {
+			return nil, fmt.Errorf("class balancing requires a column")
+		}
+		balance = &som.BalanceConfig{
+			Column: yml.Balance.Column,
+			Mode:   mode,
 		}
 	}
 
-	return &conf, training, nil
+	return &som.TrainingConfig{
+		Algorithm:          algorithm,
+		Init:               initialization,
+		Epochs:             yml.Epochs,
+		LearningRate:       alpha,
+		NeighborhoodRadius: radius,
+		WeightDecay:        wtDecay,
+		ViSomLambda:        yml.Lambda,
+		Growing:            growing,
+		Hierarchy:          hierarchy,
+		Stopping:           stopping,
+		Shuffle:            yml.Shuffle,
+		Replacement:        yml.Replacement,
+		SamplesPerEpoch:    yml.Samples,
+		WeightColumn:       yml.WeightCol,
+		Balance:            balance,
+		ValidationFraction: yml.ValFraction,
+		ValidationInterval: yml.ValInterval,
+	}, nil
 }
 
 func createLayer(s *ymlSom, l *ymlLayer) (*som.LayerDef, error) {
-	metric, ok := distance.GetMetric(l.Metric)
-	if !ok {
-		return nil, fmt.Errorf("unknown metric: %s", l.Metric)
+	metric, err := distance.FromString(l.Metric)
+	if err != nil {
+		return nil, err
 	}
 	if len(l.Data) > 0 && len(l.Data) != len(l.Columns)*s.Size[0]*s.Size[1] {
 		return nil, fmt.Errorf("invalid data size for layer %s", l.Name)
@@ -137,7 +320,6 @@ func createLayer(s *ymlSom, l *ymlLayer) (*som.LayerDef, error) {
 
 	norms := make([]norm.Normalizer, len(l.Columns))
 	for i := range norms {
-		var err error
 		if i >= len(l.Norm) {
 			if len(l.Norm) == 0 {
 				norms[i] = &norm.Identity{}
@@ -156,23 +338,136 @@ func createLayer(s *ymlSom, l *ymlLayer) (*som.LayerDef, error) {
 	}
 
 	return &som.LayerDef{
-		Name:        l.Name,
-		Columns:     l.Columns,
-		Norm:        norms,
-		Metric:      metric,
-		Weight:      l.Weight,
-		Weights:     l.Data,
-		Categorical: l.Categorical,
+		Name:          l.Name,
+		Columns:       l.Columns,
+		Norm:          norms,
+		Metric:        metric,
+		Weight:        l.Weight,
+		Weights:       l.Data,
+		Categorical:   l.Categorical,
+		Rescale:       l.Rescale,
+		ColumnWeights: l.ColWeights,
 	}, nil
 }
 
 func ToYAML(som *som.Som) ([]byte, error) {
+	return encode(&ymlConfig{Som: *somToYml(som)})
+}
+
+// ToCheckpoint reads a SOM configuration with weights, and the training state, from checkpoint YAML data.
+// Returns an error if the YAML data contains no checkpoint.
+func ToCheckpoint(ymlData []byte) (*som.SomConfig, *som.Checkpoint, error) {
+	reader := bytes.NewReader(ymlData)
+	decoder := yaml.NewDecoder(reader)
+	decoder.KnownFields(true)
+
+	var yml ymlConfig
+	err := decoder.Decode(&yml)
+	if err != nil {
+		return nil, nil, err
+	}
+	if yml.Checkpoint == nil {
+		return nil, nil, fmt.Errorf("no checkpoint found in YAML data")
+	}
+
+	conf, err := toSomConfig(&yml.Som)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return conf, &som.Checkpoint{
+		Epoch:  yml.Checkpoint.Epoch,
+		Epochs: yml.Checkpoint.Epochs,
+		Seed:   yml.Checkpoint.Seed,
+		Draws:  yml.Checkpoint.Draws,
+	}, nil
+}
+
+// CheckpointToYAML converts a SOM and the state of its training run to checkpoint YAML data.
+func CheckpointToYAML(s *som.Som, checkpoint *som.Checkpoint) ([]byte, error) {
+	return encode(&ymlConfig{
+		Som: *somToYml(s),
+		Checkpoint: &ymlCheckpoint{
+			Epoch:  checkpoint.Epoch,
+			Epochs: checkpoint.Epochs,
+			Seed:   checkpoint.Seed,
+			Draws:  checkpoint.Draws,
+		},
+	})
+}
+
+// ToHierarchy creates a hierarchy of SOMs from YAML data, with child maps nested under the root map.
+// For YAML data without child maps, the hierarchy consists of the root map only.
+func ToHierarchy(ymlData []byte) (*som.Hierarchy, error) {
+	reader := bytes.NewReader(ymlData)
+	decoder := yaml.NewDecoder(reader)
+	decoder.KnownFields(true)
+
+	var yml ymlConfig
+	err := decoder.Decode(&yml)
+	if err != nil {
+		return nil, err
+	}
+
+	return toHierarchy(&yml.Som)
+}
+
+func toHierarchy(yml *ymlSom) (*som.Hierarchy, error) {
+	conf, err := toSomConfig(yml)
+	if err != nil {
+		return nil, err
+	}
+	s, err := som.New(conf)
+	if err != nil {
+		return nil, err
+	}
+	h := som.NewHierarchy(s)
+	for _, c := range yml.Children {
+		child, err := toHierarchy(&c.Som)
+		if err != nil {
+			return nil, err
+		}
+		if err := h.SetChild(c.Node, child); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// HierarchyToYAML converts a hierarchy of SOMs to YAML, with child maps nested under their parent nodes.
+func HierarchyToYAML(h *som.Hierarchy) ([]byte, error) {
+	return encode(&ymlConfig{Som: *hierarchyToYml(h)})
+}
+
+func hierarchyToYml(h *som.Hierarchy) *ymlSom {
+	yml := somToYml(h.Som())
+	for _, node := range h.ChildNodes() {
+		child, _ := h.Child(node)
+		yml.Children = append(yml.Children, &ymlChild{
+			Node: node,
+			Som:  *hierarchyToYml(child),
+		})
+	}
+	return yml
+}
+
+func somToYml(som *som.Som) *ymlSom {
 	viSomMetric := ""
 	if som.ViSomMetric() != nil {
 		viSomMetric = som.ViSomMetric().Name()
 	}
+	topology := ""
+	if _, ok := som.Topology().(*neighborhood.Rectangular); !ok {
+		topology = som.Topology().Name()
+	}
+	wrap := ""
+	if som.Wrap().Any() {
+		wrap = som.Wrap().Name()
+	}
 	yml := ymlSom{
 		Size:         [2]int{som.Size().Width, som.Size().Height},
+		Topology:     topology,
+		Wrap:         wrap,
 		Layers:       []*ymlLayer{},
 		Neighborhood: som.Neighborhood().Name(),
 		Metric:       som.MapMetric().Name(),
@@ -198,30 +493,158 @@ func ToYAML(som *som.Som) ([]byte, error) {
 			weight = 0
 		}
 
+		_, rescale := l.Metric().(*distance.Rescaled)
+
 		yml.Layers = append(yml.Layers, &ymlLayer{
 			Name:        l.Name(),
 			Columns:     l.ColumnNames(),
 			Norm:        norms,
-			Metric:      l.Metric().Name(),
+			Metric:      distance.ToString(l.Metric()),
 			Weight:      weight,
 			Categorical: l.IsCategorical(),
+			Rescale:     rescale,
+			ColWeights:  l.Metric().ColumnWeights(),
 			Data:        l.Weights(),
 		})
 	}
 
-	ymlConf := ymlConfig{
-		Som:      yml,
-		Training: nil,
-	}
+	return &yml
+}
 
+func encode(yml *ymlConfig) ([]byte, error) {
 	writer := bytes.Buffer{}
 	encoder := yaml.NewEncoder(&writer)
 	encoder.SetIndent(2)
 
-	err := encoder.Encode(ymlConf)
+	err := encoder.Encode(yml)
 	if err != nil {
 		return nil, err
 	}
 
 	return writer.Bytes(), nil
 }
+
+type ymlTuning struct {
+	Search     string `yaml:",omitempty"`
+	Samples    int    `yaml:",omitempty"`
+	Folds      int
+	Score      string `yaml:",omitempty"`
+	Target     string `yaml:",omitempty"`
+	Parameters ymlTuningParams
+}
+
+type ymlTuningParams struct {
+	Size         [][2]int                  `yaml:",flow,omitempty"`
+	Epochs       []int                     `yaml:",flow,omitempty"`
+	Alpha        []string                  `yaml:",omitempty"`
+	Radius       []string                  `yaml:",omitempty"`
+	Lambda       *ymlFloatParam            `yaml:",omitempty"`
+	LayerWeights map[string]*ymlFloatParam `yaml:"layer-weights,omitempty"`
+}
+
+// ymlFloatParam is a list of values, or a range with min and max.
+type ymlFloatParam struct {
+	Values []float64
+	Min    *float64
+	Max    *float64
+}
+
+func (p *ymlFloatParam) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		return node.Decode(&p.Values)
+	}
+	var rng struct {
+		Min *float64
+		Max *float64
+	}
+	if err := node.Decode(&rng); err != nil {
+		return err
+	}
+	if rng.Min == nil || rng.Max == nil {
+		return fmt.Errorf("parameter range requires min and max, in line %d", node.Line)
+	}
+	p.Min, p.Max = rng.Min, rng.Max
+	return nil
+}
+
+func (p *ymlFloatParam) toFloatParam() som.FloatParam {
+	if p.Min != nil {
+		return som.FloatParam{Min: *p.Min, Max: *p.Max}
+	}
+	return som.FloatParam{Values: p.Values}
+}
+
+// ToTuning reads the search space and settings for hyperparameter tuning from YAML data.
+func ToTuning(ymlData []byte) (*som.Tuning, error) {
+	reader := bytes.NewReader(ymlData)
+	decoder := yaml.NewDecoder(reader)
+	decoder.KnownFields(true)
+
+	var yml ymlTuning
+	err := decoder.Decode(&yml)
+	if err != nil {
+		return nil, err
+	}
+
+	var ok bool
+	search := som.GridSearch
+	if yml.Search != "" {
+		search, ok = som.GetSearch(yml.Search)
+		if !ok {
+			return nil, fmt.Errorf("unknown search strategy: %s", yml.Search)
+		}
+	}
+	score := som.ScoreQuantization
+	if yml.Score != "" {
+		score, ok = som.GetScore(yml.Score)
+		if !ok {
+			return nil, fmt.Errorf("unknown score: %s", yml.Score)
+		}
+	}
+	if score == som.ScoreAccuracy && yml.Target == "" {
+		return nil, fmt.Errorf("score accuracy requires a target layer")
+	}
+
+	params := &yml.Parameters
+	sizes := make([]layer.Size, len(params.Size))
+	for i, s := range params.Size {
+		sizes[i] = layer.Size{Width: s[0], Height: s[1]}
+	}
+	for _, a := range append(append([]string{}, params.Alpha...), params.Radius...) {
+		if _, err := decay.FromString(a); err != nil {
+			return nil, err
+		}
+	}
+
+	var lambdas *som.FloatParam
+	if params.Lambda != nil {
+		l := params.Lambda.toFloatParam()
+		lambdas = &l
+	}
+
+	layers := make([]string, 0, len(params.LayerWeights))
+	for name := range params.LayerWeights {
+		layers = append(layers, name)
+	}
+	slices.Sort(layers)
+	weights := make([]som.LayerWeightParam, len(layers))
+	for i, name := range layers {
+		weights[i] = som.LayerWeightParam{Layer: name, Weight: params.LayerWeights[name].toFloatParam()}
+	}
+
+	return &som.Tuning{
+		Search:  search,
+		Samples: yml.Samples,
+		CrossValidation: som.CrossValidation{
+			Folds:  yml.Folds,
+			Score:  score,
+			Target: yml.Target,
+		},
+		Sizes:        sizes,
+		Epochs:       params.Epochs,
+		Alphas:       params.Alpha,
+		Radii:        params.Radius,
+		Lambdas:      lambdas,
+		LayerWeights: weights,
+	}, nil
+}
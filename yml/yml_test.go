@@ -2,6 +2,7 @@ package yml
 
 import (
 	"testing"
+	"time"
 
 	"github.com/mlange-42/som"
 	"github.com/mlange-42/som/distance"
@@ -202,3 +203,472 @@ som:
 
 	assert.Equal(t, expected, string(result))
 }
+
+func TestToYAMLTopology(t *testing.T) {
+	ymlData := []byte(`
+som:
+  size: [2, 1]
+  topology: hexagonal
+  neighborhood: gaussian
+  metric: euclidean
+  layers:
+  - name: layer1
+    columns: [a]
+    metric: euclidean
+`)
+
+	config, _, err := ToSomConfig(ymlData)
+	assert.NoError(t, err)
+	assert.IsType(t, &neighborhood.Hexagonal{}, config.Topology)
+
+	s, err := som.New(config)
+	assert.NoError(t, err)
+
+	result, err := ToYAML(s)
+	assert.NoError(t, err)
+
+	expected := `som:
+  size: [2, 1]
+  topology: hexagonal
+  neighborhood: gaussian
+  metric: euclidean
+  layers:
+    - name: layer1
+      columns: [a]
+      metric: euclidean
+      data: [0, 0]
+`
+	assert.Equal(t, expected, string(result))
+
+	_, _, err = ToSomConfig([]byte(`
+som:
+  size: [2, 1]
+  topology: unknown
+  neighborhood: gaussian
+  metric: euclidean
+`))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown topology: unknown")
+}
+
+func TestToYAMLMetric(t *testing.T) {
+	ymlData := []byte(`
+som:
+  size: [2, 1]
+  neighborhood: gaussian
+  metric: euclidean
+  layers:
+  - name: layer1
+    columns: [a, b]
+    metric: minkowski 3
+    column-weights: [2, 0.5]
+  - name: layer2
+    columns: [c, d]
+    metric: canberra
+    rescale-missing: true
+`)
+
+	config, _, err := ToSomConfig(ymlData)
+	assert.NoError(t, err)
+	assert.IsType(t, &distance.Minkowski{}, config.Layers[0].Metric)
+	assert.Equal(t, []float64{3}, config.Layers[0].Metric.GetArgs())
+	assert.Equal(t, &distance.Canberra{}, config.Layers[1].Metric)
+	assert.False(t, config.Layers[0].Rescale)
+	assert.True(t, config.Layers[1].Rescale)
+	assert.Equal(t, []float64{2, 0.5}, config.Layers[0].ColumnWeights)
+
+	s, err := som.New(config)
+	assert.NoError(t, err)
+
+	result, err := ToYAML(s)
+	assert.NoError(t, err)
+
+	expected := `som:
+  size: [2, 1]
+  neighborhood: gaussian
+  metric: euclidean
+  layers:
+    - name: layer1
+      columns: [a, b]
+      metric: minkowski 3
+      column-weights: [2, 0.5]
+      data: [0, 0, 0, 0]
+    - name: layer2
+      columns: [c, d]
+      metric: canberra
+      rescale-missing: true
+      data: [0, 0, 0, 0]
+`
+	assert.Equal(t, expected, string(result))
+
+	_, _, err = ToSomConfig([]byte(`
+som:
+  size: [2, 1]
+  neighborhood: gaussian
+  metric: euclidean
+  layers:
+  - name: layer1
+    columns: [a, b]
+    metric: minkowski
+`))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid arguments for metric minkowski")
+}
+
+func TestToYAMLMahalanobis(t *testing.T) {
+	ymlData := []byte(`
+som:
+  size: [2, 1]
+  neighborhood: gaussian
+  metric: euclidean
+  layers:
+  - name: layer1
+    columns: [a, b]
+    metric: mahalanobis
+`)
+
+	config, _, err := ToSomConfig(ymlData)
+	assert.NoError(t, err)
+	m, ok := config.Layers[0].Metric.(*distance.Mahalanobis)
+	assert.True(t, ok)
+	assert.NoError(t, m.SetCovariance([][]float64{{2, 0.5}, {0.5, 1}}))
+
+	s, err := som.New(config)
+	assert.NoError(t, err)
+
+	result, err := ToYAML(s)
+	assert.NoError(t, err)
+	assert.Contains(t, string(result), "metric: mahalanobis 2 0.5 0.5 1\n")
+
+	config, _, err = ToSomConfig(result)
+	assert.NoError(t, err)
+	assert.Equal(t, m, config.Layers[0].Metric)
+
+	_, _, err = ToSomConfig([]byte(`
+som:
+  size: [2, 1]
+  neighborhood: gaussian
+  metric: euclidean
+  layers:
+  - name: layer1
+    columns: [a, b]
+    metric: mahalanobis 1 1 1 1
+`))
+	assert.Error(t, err)
+}
+
+func TestToYAMLWrap(t *testing.T) {
+	ymlData := []byte(`
+som:
+  size: [2, 1]
+  wrap: cylinder-x
+  neighborhood: gaussian
+  metric: euclidean
+  layers:
+  - name: layer1
+    columns: [a]
+    metric: euclidean
+`)
+
+	config, _, err := ToSomConfig(ymlData)
+	assert.NoError(t, err)
+	assert.Equal(t, neighborhood.Wrap{X: true}, config.Wrap)
+
+	s, err := som.New(config)
+	assert.NoError(t, err)
+
+	result, err := ToYAML(s)
+	assert.NoError(t, err)
+
+	expected := `som:
+  size: [2, 1]
+  wrap: cylinder-x
+  neighborhood: gaussian
+  metric: euclidean
+  layers:
+    - name: layer1
+      columns: [a]
+      metric: euclidean
+      data: [0, 0]
+`
+	assert.Equal(t, expected, string(result))
+
+	_, _, err = ToSomConfig([]byte(`
+som:
+  size: [2, 1]
+  wrap: unknown
+  neighborhood: gaussian
+  metric: euclidean
+`))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown wrap: unknown")
+}
+
+func TestToSomConfigTraining(t *testing.T) {
+	ymlData := []byte(`
+som:
+  size: [2, 1]
+  neighborhood: gaussian
+  metric: euclidean
+  layers:
+  - name: layer1
+    columns: [a]
+    metric: euclidean
+training:
+  algorithm: batch
+  init: pca
+  epochs: 10
+  radius: linear 2 0.5
+  shuffle: true
+  replacement: true
+  samples-per-epoch: 500
+  weight-column: wt
+  validation-fraction: 0.2
+  validation-interval: 10
+  growing:
+    max-nodes: 100
+    error-threshold: 0.01
+    epochs: 5
+  stopping:
+    tolerance: 0.001
+    window: 10
+    max-time: 1h30m
+    patience: 5
+    final-epochs: 20
+  balance:
+    column: species
+    mode: inverse
+`)
+
+	_, training, err := ToSomConfig(ymlData)
+	assert.NoError(t, err)
+	assert.Equal(t, som.Batch, training.Algorithm)
+	assert.Equal(t, som.InitPCA, training.Init)
+	assert.True(t, training.Shuffle)
+	assert.True(t, training.Replacement)
+	assert.Equal(t, 500, training.SamplesPerEpoch)
+	assert.Equal(t, "wt", training.WeightColumn)
+	assert.Equal(t, 0.2, training.ValidationFraction)
+	assert.Equal(t, 10, training.ValidationInterval)
+	assert.Nil(t, training.LearningRate)
+	assert.Equal(t, &som.GrowingConfig{MaxNodes: 100, ErrorThreshold: 0.01, Epochs: 5}, training.Growing)
+	assert.Equal(t, &som.StoppingConfig{
+		Tolerance:   0.001,
+		Window:      10,
+		MaxTime:     90 * time.Minute,
+		Patience:    5,
+		FinalEpochs: 20,
+	}, training.Stopping)
+	assert.Equal(t, &som.BalanceConfig{Column: "species", Mode: som.InverseFrequency}, training.Balance)
+
+	_, _, err = ToSomConfig([]byte(`
+som:
+  size: [2, 1]
+  neighborhood: gaussian
+  metric: euclidean
+training:
+  algorithm: unknown
+  epochs: 10
+  alpha: linear 0.1 0.01
+  radius: linear 2 0.5
+`))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown training algorithm: unknown")
+
+	_, _, err = ToSomConfig([]byte(`
+som:
+  size: [2, 1]
+  neighborhood: gaussian
+  metric: euclidean
+training:
+  init: unknown
+  epochs: 10
+  alpha: linear 0.1 0.01
+  radius: linear 2 0.5
+`))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown initialization: unknown")
+
+	_, _, err = ToSomConfig([]byte(`
+som:
+  size: [2, 1]
+  neighborhood: gaussian
+  metric: euclidean
+training:
+  epochs: 10
+  alpha: linear 0.1 0.01
+  radius: linear 2 0.5
+  balance:
+    column: species
+    mode: unknown
+`))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown class balancing mode: unknown")
+}
+
+func TestToSomConfigHierarchy(t *testing.T) {
+	ymlData := []byte(`
+som:
+  size: [2, 1]
+  neighborhood: gaussian
+  metric: euclidean
+  layers:
+  - name: layer1
+    columns: [a]
+    metric: euclidean
+training:
+  epochs: 10
+  alpha: linear 0.1 0.01
+  radius: linear 2 0.5
+  hierarchy:
+    size: [3, 2]
+    tau: 0.1
+    max-depth: 2
+`)
+
+	_, training, err := ToSomConfig(ymlData)
+	assert.NoError(t, err)
+	assert.Equal(t, &som.HierarchyConfig{
+		Size:     layer.Size{Width: 3, Height: 2},
+		Tau:      0.1,
+		MaxDepth: 2,
+	}, training.Hierarchy)
+}
+
+func TestHierarchyToYAML(t *testing.T) {
+	ymlData := []byte(`som:
+  size: [2, 1]
+  neighborhood: gaussian
+  metric: euclidean
+  layers:
+    - name: layer1
+      columns: [a]
+      metric: euclidean
+      data: [0, 1]
+  children:
+    - node: 1
+      som:
+        size: [2, 1]
+        neighborhood: gaussian
+        metric: euclidean
+        layers:
+          - name: layer1
+            columns: [a]
+            metric: euclidean
+            data: [0.5, 1.5]
+`)
+
+	h, err := ToHierarchy(ymlData)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, h.Depth())
+	assert.Equal(t, []int{1}, h.ChildNodes())
+
+	child, ok := h.Child(1)
+	assert.True(t, ok)
+	assert.Equal(t, []float64{0.5, 1.5}, child.Som().Layers()[0].Weights())
+
+	yml, err := HierarchyToYAML(h)
+	assert.NoError(t, err)
+	assert.Equal(t, string(ymlData), string(yml))
+
+	_, err = ToHierarchy([]byte(`som:
+  size: [2, 1]
+  neighborhood: gaussian
+  metric: euclidean
+  layers:
+    - name: layer1
+      columns: [a]
+      metric: euclidean
+  children:
+    - node: 1
+      som:
+        size: [2, 1]
+        neighborhood: gaussian
+        metric: euclidean
+        layers:
+          - name: layer2
+            columns: [a]
+            metric: euclidean
+`))
+	assert.Error(t, err)
+}
+
+func TestCheckpointToYAML(t *testing.T) {
+	ymlData := []byte(`som:
+  size: [2, 1]
+  neighborhood: gaussian
+  metric: euclidean
+  layers:
+    - name: layer1
+      columns: [a]
+      metric: euclidean
+      data: [0.1, 1.5]
+checkpoint:
+  epoch: 100
+  epochs: 1000
+  seed: 42
+  draws: 12345
+`)
+
+	conf, checkpoint, err := ToCheckpoint(ymlData)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{0.1, 1.5}, conf.Layers[0].Weights)
+	assert.Equal(t, &som.Checkpoint{Epoch: 100, Epochs: 1000, Seed: 42, Draws: 12345}, checkpoint)
+
+	s, err := som.New(conf)
+	assert.NoError(t, err)
+
+	yml, err := CheckpointToYAML(s, checkpoint)
+	assert.NoError(t, err)
+	assert.Equal(t, string(ymlData), string(yml))
+
+	_, _, err = ToCheckpoint([]byte(`som:
+  size: [2, 1]
+  neighborhood: gaussian
+  metric: euclidean
+  layers:
+    - name: layer1
+      columns: [a]
+      metric: euclidean
+`))
+	assert.Error(t, err)
+}
+
+func TestToTuning(t *testing.T) {
+	tuning, err := ToTuning([]byte(`
+search: random
+samples: 20
+folds: 5
+score: accuracy
+target: species
+parameters:
+  size: [[8, 6], [12, 8]]
+  epochs: [500, 1000]
+  alpha: [polynomial 0.25 0.01 2, linear 0.5 0.01]
+  lambda: {min: 0, max: 0.5}
+  layer-weights:
+    species: [0.25, 0.5]
+    scalars: {min: 0.5, max: 1}
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, som.RandomSearch, tuning.Search)
+	assert.Equal(t, 20, tuning.Samples)
+	assert.Equal(t, som.CrossValidation{Folds: 5, Score: som.ScoreAccuracy, Target: "species"}, tuning.CrossValidation)
+	assert.Equal(t, []layer.Size{{Width: 8, Height: 6}, {Width: 12, Height: 8}}, tuning.Sizes)
+	assert.Equal(t, []int{500, 1000}, tuning.Epochs)
+	assert.Equal(t, []string{"polynomial 0.25 0.01 2", "linear 0.5 0.01"}, tuning.Alphas)
+	assert.Equal(t, &som.FloatParam{Min: 0, Max: 0.5}, tuning.Lambdas)
+	assert.Equal(t, []som.LayerWeightParam{
+		{Layer: "scalars", Weight: som.FloatParam{Min: 0.5, Max: 1}},
+		{Layer: "species", Weight: som.FloatParam{Values: []float64{0.25, 0.5}}},
+	}, tuning.LayerWeights)
+
+	_, err = ToTuning([]byte("folds: 5\nscore: accuracy\n"))
+	assert.Error(t, err)
+	_, err = ToTuning([]byte("folds: 5\nsearch: unknown\n"))
+	assert.Error(t, err)
+	_, err = ToTuning([]byte("folds: 5\nparameters:\n  alpha: [unknown 1 2]\n"))
+	assert.Error(t, err)
+	_, err = ToTuning([]byte("folds: 5\nparameters:\n  lambda: {min: 0}\n"))
+	assert.Error(t, err)
+}